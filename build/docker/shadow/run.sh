@@ -5,7 +5,9 @@ env | grep 'KT_'
 
 # fetch authorized_keys from volume mounted via config map
 mkdir -p /root/.ssh
-cp /root/authorized/authorized_keys /root/.ssh/authorized_keys
+if [ -f /root/authorized/authorized_keys ]; then
+  cp /root/authorized/authorized_keys /root/.ssh/authorized_keys
+fi
 
 if [ -n "${privateKey}" ]; then
   # for ephemeral container
@@ -14,7 +16,13 @@ if [ -n "${privateKey}" ]; then
   echo "Private key created created"
 fi
 
-if [ "${KT_DNS_PROTOCOL}" = "" ]; then
+if [ -n "${authorizedKey}" ]; then
+  # for ephemeral container, which cannot mount config map volume
+  echo "${authorizedKey}" | base64 -d > /root/.ssh/authorized_keys
+  echo "Authorized key created"
+fi
+
+if [ "${KT_DNS_PROTOCOL}" = "" ] && [ "${KT_UDP_PORTS}" = "" ]; then
   echo "Skip shadow process"
 elif [ "${1}" = "--debug" ]; then
   echo "Run shadow in debug mode"
@@ -24,4 +32,7 @@ else
   /usr/sbin/shadow &
 fi
 
-/usr/sbin/sshd -D
\ No newline at end of file
+# record pid of main process, so that ephemeral container could be stopped without affecting other processes of pod
+echo $$ > /var/run/kt-shadow.pid
+# ephemeral container shares network namespace with other containers of pod, sshd port could be specified to avoid conflict
+exec /usr/sbin/sshd -D -p "${sshPort:-22}"
\ No newline at end of file
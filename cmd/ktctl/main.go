@@ -44,10 +44,14 @@ func main() {
 	}
 
 	rootCmd.AddCommand(command.NewConnectCommand())
+	rootCmd.AddCommand(command.NewStatusCommand())
+	rootCmd.AddCommand(command.NewDisconnectCommand())
 	rootCmd.AddCommand(command.NewExchangeCommand())
 	rootCmd.AddCommand(command.NewMeshCommand())
 	rootCmd.AddCommand(command.NewPreviewCommand())
 	rootCmd.AddCommand(command.NewForwardCommand())
+	rootCmd.AddCommand(command.NewSyncCommand())
+	rootCmd.AddCommand(command.NewRunCommand())
 	rootCmd.AddCommand(command.NewRecoverCommand())
 	rootCmd.AddCommand(command.NewReplayCommand())
 	rootCmd.AddCommand(command.NewCleanCommand())
@@ -19,6 +19,7 @@ const pathKtLock = "/var/kt.lock"
 const actionSetup = "setup"
 const actionAdd = "add"
 const actionRemove = "remove"
+const actionSplit = "split"
 
 func main() {
 	fileLock := flock.New(pathKtLock)
@@ -37,6 +38,8 @@ func main() {
 			add(os.Args[2:])
 		case actionRemove:
 			remove(os.Args[2:])
+		case actionSplit:
+			split(os.Args[2:])
 		default:
 			log.Error().Msgf("Invalid action '%s'", os.Args[1])
 			usage()
@@ -49,7 +52,8 @@ func usage() {
 router %s <service-name> <service-port> <custom-version>
 router %s <custom-version>
 router %s <custom-version>
-`, actionSetup, actionAdd, actionRemove)
+router %s <service-name> <service-port> <shadow-service-name> <exposed-ports>
+`, actionSetup, actionAdd, actionRemove, actionSplit)
 }
 
 func setup(args []string) {
@@ -77,6 +81,30 @@ func setup(args []string) {
 	log.Info().Msgf("Route setup completed.")
 }
 
+func split(args []string) {
+	if len(args) < 4 {
+		usage()
+		return
+	}
+	ktConf := router.KtConf{
+		Service: args[0],
+		Ports:   getPorts(args[1]),
+		Shadow:  args[2],
+		Exposed: strings.Split(args[3], ","),
+	}
+	err := router.WriteKtConf(&ktConf)
+	if err != nil {
+		log.Error().Err(err).Msgf("Write kt config failed")
+		return
+	}
+	err = router.WriteAndReloadSplitConf(&ktConf)
+	if err != nil {
+		log.Error().Err(err).Msgf("Write and load split config failed")
+		return
+	}
+	log.Info().Msgf("Split setup completed.")
+}
+
 func add(args []string) {
 	header, version := splitVersionMark(args[0])
 	err := updateRoute(header, version, actionAdd)
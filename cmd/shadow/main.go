@@ -3,9 +3,11 @@ package main
 import (
 	"github.com/gitlayzer/kt-connect/pkg/common"
 	"github.com/gitlayzer/kt-connect/pkg/shadow/dnsserver"
+	"github.com/gitlayzer/kt-connect/pkg/shadow/udprelay"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +18,8 @@ const (
 	ArgDnsProtocol = "--protocol"
 	// ArgLogLevel application argument for shadow pod log level
 	ArgLogLevel = "--log-level"
+	// ArgUdpPorts application argument for udp ports to relay
+	ArgUdpPorts = "--udp-ports"
 )
 
 func init() {
@@ -29,6 +33,16 @@ func main() {
 		log.Error().Err(err).Msgf("Failed to parse log level")
 	}
 	zerolog.SetGlobalLevel(level)
+	udpPorts := getParameter(common.EnvVarUdpPorts, ArgUdpPorts, "")
+	if udpPorts != "" {
+		if err = startUdpRelay(udpPorts); err != nil {
+			log.Fatal().Err(err).Msgf("Failed to start udp relay")
+		}
+		if getParameter(common.EnvVarDnsProtocol, ArgDnsProtocol, "") == "" {
+			// shadow pod of exchange, mesh or preview does not serve dns
+			select {}
+		}
+	}
 	dnsPort := common.StandardDnsPort
 	dnsProtocol := getParameter(common.EnvVarDnsProtocol, ArgDnsProtocol, "udp")
 	localDomain := getParameter(common.EnvVarLocalDomains, ArgLocalDomains, "")
@@ -39,6 +53,18 @@ func main() {
 	dnsserver.Start(dnsPort, dnsProtocol, localDomain)
 }
 
+func startUdpRelay(udpPorts string) error {
+	ports := make([]int, 0)
+	for _, p := range strings.Split(udpPorts, ",") {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return err
+		}
+		ports = append(ports, port)
+	}
+	return udprelay.Start(ports)
+}
+
 func getParameter(envVar string, argVar string, defaultValue string) string {
 	if os.Getenv(envVar) != "" {
 		return os.Getenv(envVar)
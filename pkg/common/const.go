@@ -10,6 +10,8 @@ const (
 	StandardSshPort = 22
 	// StandardDnsPort standard dns port
 	StandardDnsPort = 53
+	// UdpRelayPort port in shadow pod for tunneling udp datagrams to local via ssh
+	UdpRelayPort = 12053
 
 	// EnvVarLocalDomains environment variable for local domain config
 	EnvVarLocalDomains = "KT_LOCAL_DOMAIN"
@@ -17,4 +19,6 @@ const (
 	EnvVarDnsProtocol = "KT_DNS_PROTOCOL"
 	// EnvVarLogLevel environment variable for shadow pod log level
 	EnvVarLogLevel = "KT_LOG_LEVEL"
+	// EnvVarUdpPorts environment variable for udp ports to relay in shadow pod
+	EnvVarUdpPorts = "KT_UDP_PORTS"
 )
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// MaxDatagramSize max size of udp datagram payload
+const MaxDatagramSize = 65535
+
+// WriteRelayPort write udp port which the relayed datagrams are received from, as header of relay stream
+func WriteRelayPort(w io.Writer, port int) error {
+	header := make([]byte, 2)
+	binary.BigEndian.PutUint16(header, uint16(port))
+	_, err := w.Write(header)
+	return err
+}
+
+// ReadRelayPort read udp port from header of relay stream
+func ReadRelayPort(r io.Reader) (int, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return -1, err
+	}
+	return int(binary.BigEndian.Uint16(header)), nil
+}
+
+// WriteDatagram write a length prefixed datagram to relay stream
+func WriteDatagram(w io.Writer, data []byte) error {
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadDatagram read a length prefixed datagram from relay stream
+func ReadDatagram(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
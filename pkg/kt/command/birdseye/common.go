@@ -89,6 +89,18 @@ func GetServiceStatus(ktSvcs []coreV1.Service, pods []coreV1.Pod, svcs []coreV1.
 				}
 			}
 		}
+		for _, s := range ktSvcs {
+			config := util.String2Map(s.Annotations[util.KtConfig])
+			if config["gateway"] == svc.Name {
+				allServices = append(allServices, []string{svc.Name, "meshed (gateway) by " +
+					getMeshedUserNames(ktSvcs, pods, svc.Name + util.MeshPodInfix)})
+				continue svcLoop
+			} else if config["istio"] == svc.Name {
+				allServices = append(allServices, []string{svc.Name, "meshed (istio) by " +
+					getMeshedUserNames(ktSvcs, pods, svc.Name + util.MeshPodInfix)})
+				continue svcLoop
+			}
+		}
 		if !opt.Get().Birdseye.HideNaturalService {
 			allServices = append(allServices, []string{svc.Name, "normal"})
 		}
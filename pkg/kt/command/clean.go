@@ -55,8 +55,12 @@ func isEmpty(r *clean.ResourceToClean) bool {
 	return len(r.PodsToDelete) == 0 &&
 		len(r.ConfigMapsToDelete) == 0 &&
 		len(r.DeploymentsToDelete) == 0 &&
-		len(r.DeploymentsToScale) == 0 &&
+		len(r.WorkloadsToScale) == 0 &&
+		len(r.AutoscalersToRestore) == 0 &&
 		len(r.ServicesToDelete) == 0 &&
 		len(r.ServicesToUnlock) == 0 &&
-		len(r.ServicesToRecover) == 0
+		len(r.ServicesToRecover) == 0 &&
+		len(r.IstioRulesToRecover) == 0 &&
+		len(r.HTTPRoutesToRecover) == 0 &&
+		len(r.PodsToRecover) == 0
 }
@@ -22,9 +22,13 @@ type ResourceToClean struct {
 	ServicesToDelete    []string
 	ConfigMapsToDelete  []string
 	DeploymentsToDelete []string
-	DeploymentsToScale  map[string]int32
+	WorkloadsToScale    map[string]int32
+	AutoscalersToRestore []string
 	ServicesToRecover   []string
 	ServicesToUnlock   []string
+	IstioRulesToRecover map[string][]string
+	HTTPRoutesToRecover map[string][]string
+	PodsToRecover       []string
 }
 
 
@@ -39,9 +43,13 @@ func CheckClusterResources() (*ResourceToClean, error) {
 		ServicesToDelete:    make([]string, 0),
 		ConfigMapsToDelete:  make([]string, 0),
 		DeploymentsToDelete: make([]string, 0),
-		DeploymentsToScale:  make(map[string]int32),
+		WorkloadsToScale:    make(map[string]int32),
+		AutoscalersToRestore: make([]string, 0),
 		ServicesToRecover:   make([]string, 0),
 		ServicesToUnlock:    make([]string, 0),
+		IstioRulesToRecover: make(map[string][]string),
+		HTTPRoutesToRecover: make(map[string][]string),
+		PodsToRecover:       make([]string, 0),
 	}
 	for _, pod := range pods {
 		analysisExpiredPods(pod, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
@@ -57,6 +65,9 @@ func CheckClusterResources() (*ResourceToClean, error) {
 	}
 	svcList, err := cluster.Ins().GetAllServiceInNamespace(opt.Get().Global.Namespace)
 	analysisLockAndOrphanServices(svcList.Items, &resourceToClean)
+	if podList, err2 := cluster.Ins().GetPodsByLabel(map[string]string{}, opt.Get().Global.Namespace); err2 == nil {
+		analysisEphemeralExchangedPods(podList.Items, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
+	}
 	return &resourceToClean, nil
 }
 
@@ -88,13 +99,20 @@ func TidyClusterResources(r *ResourceToClean) {
 			log.Info().Msgf(" * %s", name)
 		}
 	}
-	log.Info().Msgf("Recovering %d scaled deployments", len(r.DeploymentsToScale))
-	for name, replica := range r.DeploymentsToScale {
-		err := cluster.Ins().ScaleTo(name, opt.Get().Global.Namespace, &replica)
+	log.Info().Msgf("Recovering %d scaled workloads", len(r.WorkloadsToScale))
+	for key, replica := range r.WorkloadsToScale {
+		kind, name, _ := strings.Cut(key, "/")
+		err := cluster.Ins().ScaleWorkload(kind, name, opt.Get().Global.Namespace, replica)
 		if err != nil {
-			log.Warn().Err(err).Msgf("Failed to scale deployment %s to %d", name, replica)
+			log.Warn().Err(err).Msgf("Failed to scale %s %s to %d", kind, name, replica)
 		} else {
-			log.Info().Msgf(" * %s", name)
+			log.Info().Msgf(" * %s", key)
+		}
+	}
+	log.Info().Msgf("Restoring %d horizontal pod autoscalers", len(r.AutoscalersToRestore))
+	for _, encoded := range r.AutoscalersToRestore {
+		if err := general.RestoreAutoscaler(encoded); err != nil {
+			log.Warn().Err(err).Msgf("Failed to restore horizontal pod autoscaler")
 		}
 	}
 	log.Info().Msgf("Deleting %d unavailing services", len(r.ServicesToDelete))
@@ -123,6 +141,26 @@ func TidyClusterResources(r *ResourceToClean) {
 			}
 		}
 	}
+	log.Info().Msgf("Recovering istio rules of %d meshed services", len(r.IstioRulesToRecover))
+	for name, versions := range r.IstioRulesToRecover {
+		for _, version := range versions {
+			general.RemoveIstioRules(name, opt.Get().Global.Namespace, version)
+		}
+		log.Info().Msgf(" * %s", name)
+	}
+	log.Info().Msgf("Recovering http routes of %d meshed services", len(r.HTTPRoutesToRecover))
+	for name, versions := range r.HTTPRoutesToRecover {
+		for _, version := range versions {
+			general.RemoveHTTPRouteRules(name, opt.Get().Global.Namespace, name+util.MeshPodInfix+version)
+		}
+		log.Info().Msgf(" * %s", name)
+	}
+	log.Info().Msgf("Recovering %d ephemeral exchanged pods", len(r.PodsToRecover))
+	for _, name := range r.PodsToRecover {
+		if general.RecoverEphemeralExchangedPod(name, opt.Get().Global.Namespace) {
+			log.Info().Msgf(" * %s", name)
+		}
+	}
 	log.Info().Msg("Done")
 }
 
@@ -139,9 +177,15 @@ func PrintClusterResourcesToClean(r *ResourceToClean) {
 	for _, name := range r.DeploymentsToDelete {
 		log.Info().Msgf(" * %s", name)
 	}
-	log.Info().Msgf("Find %d exchanged deployments to recover:", len(r.DeploymentsToScale))
-	for name, replica := range r.DeploymentsToScale {
-		log.Info().Msgf(" * %s -> %d", name, replica)
+	log.Info().Msgf("Find %d exchanged workloads to recover:", len(r.WorkloadsToScale))
+	for key, replica := range r.WorkloadsToScale {
+		log.Info().Msgf(" * %s -> %d", key, replica)
+	}
+	log.Info().Msgf("Find %d horizontal pod autoscalers to restore:", len(r.AutoscalersToRestore))
+	for _, encoded := range r.AutoscalersToRestore {
+		if hpa, err := general.DecodeAutoscaler(encoded); err == nil {
+			log.Info().Msgf(" * %s", hpa.Name)
+		}
 	}
 	log.Info().Msgf("Find %d unavailing service to delete:", len(r.ServicesToDelete))
 	for _, name := range r.ServicesToDelete {
@@ -155,6 +199,18 @@ func PrintClusterResourcesToClean(r *ResourceToClean) {
 	for _, name := range r.ServicesToUnlock {
 		log.Info().Msgf(" * %s", name)
 	}
+	log.Info().Msgf("Find istio rules of %d meshed services to recover:", len(r.IstioRulesToRecover))
+	for name, versions := range r.IstioRulesToRecover {
+		log.Info().Msgf(" * %s -> %s", name, strings.Join(versions, ","))
+	}
+	log.Info().Msgf("Find http routes of %d meshed services to recover:", len(r.HTTPRoutesToRecover))
+	for name, versions := range r.HTTPRoutesToRecover {
+		log.Info().Msgf(" * %s -> %s", name, strings.Join(versions, ","))
+	}
+	log.Info().Msgf("Find %d ephemeral exchanged pods to recover:", len(r.PodsToRecover))
+	for _, name := range r.PodsToRecover {
+		log.Info().Msgf(" * %s", name)
+	}
 }
 
 func TidyLocalResources() {
@@ -164,7 +220,7 @@ func TidyLocalResources() {
 	util.CleanRsaKeys()
 	log.Debug().Msg("Cleaning up background logs")
 	util.CleanBackgroundLogs()
-	if util.GetDaemonRunning(util.ComponentConnect) < 0 {
+	if util.GetDaemonRunning(util.ComponentConnect) < 0 && util.GetDaemonRunning(util.ComponentRun) < 0 {
 		if util.IsRunAsAdmin() {
 			log.Debug().Msg("Cleaning up hosts file")
 			dns.DropHosts()
@@ -183,7 +239,9 @@ func TidyLocalResources() {
 func cleanPidFiles() {
 	files, _ := ioutil.ReadDir(util.KtPidDir)
 	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".pid") {
+		// session and control socket files of connect processes are named in the same way as pid files
+		if strings.HasSuffix(f.Name(), ".pid") || strings.HasSuffix(f.Name(), ".session") ||
+			strings.HasSuffix(f.Name(), ".sock") {
 			component, pid := parseComponentAndPid(f.Name())
 			if util.IsProcessExist(pid) {
 				log.Debug().Msgf("Find kt %s instance with pid %d", component, pid)
@@ -276,14 +334,35 @@ func analysisLockAndOrphanServices(svcs []coreV1.Service, resourceToClean *Resou
 	}
 }
 
+func analysisEphemeralExchangedPods(pods []coreV1.Pod, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
+	for _, pod := range pods {
+		if util.String2Map(pod.Annotations[util.KtConfig])["ephemeral"] == "" {
+			continue
+		}
+		lastHeartBeat := util.ParseTimestamp(pod.Annotations[util.KtLastHeartBeat])
+		if lastHeartBeat < 0 || isExpired(lastHeartBeat, cleanThresholdInMinus) {
+			log.Debug().Msgf(" * pod %s exchanged by ephemeral container expired, lastHeartBeat: %d ", pod.Name, lastHeartBeat)
+			resourceToClean.PodsToRecover = append(resourceToClean.PodsToRecover, pod.Name)
+		}
+	}
+}
+
 func analysisConfigAnnotation(role string, config map[string]string, resourceToClean *ResourceToClean) {
 	log.Debug().Msgf("   role %s, config: %v", role, config)
 	// scale exchange
 	if role == util.RoleExchangeShadow {
 		replica, _ := strconv.ParseInt(config["replicas"], 10, 32)
 		app := config["app"]
+		kind := config["kind"]
+		if kind == "" {
+			// exchanged by earlier version
+			kind = cluster.KindDeployment
+		}
 		if replica > 0 && app != "" {
-			resourceToClean.DeploymentsToScale[app] = int32(replica)
+			resourceToClean.WorkloadsToScale[kind+"/"+app] = int32(replica)
+		}
+		if config["hpa"] != "" {
+			resourceToClean.AutoscalersToRestore = append(resourceToClean.AutoscalersToRestore, config["hpa"])
 		}
 	}
 	// auto mesh and selector exchange
@@ -292,6 +371,18 @@ func analysisConfigAnnotation(role string, config map[string]string, resourceToC
 			resourceToClean.ServicesToRecover = append(resourceToClean.ServicesToRecover, service)
 		}
 	}
+	// istio mesh
+	if role == util.RoleMeshShadow && config["istio"] != "" && config["version"] != "" {
+		for _, service := range strings.Split(config["istio"], ";") {
+			resourceToClean.IstioRulesToRecover[service] = append(resourceToClean.IstioRulesToRecover[service], config["version"])
+		}
+	}
+	// gateway mesh
+	if role == util.RoleMeshShadow && config["gateway"] != "" && config["version"] != "" {
+		for _, service := range strings.Split(config["gateway"], ";") {
+			resourceToClean.HTTPRoutesToRecover[service] = append(resourceToClean.HTTPRoutesToRecover[service], config["version"])
+		}
+	}
 }
 
 func isShadowPodExist(selector map[string]string, svcName, namespace, suffix string) bool {
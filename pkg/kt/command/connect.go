@@ -28,6 +28,9 @@ func NewConnectCommand() *cobra.Command {
 			return general.Prepare()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.Get().Connect.Daemon {
+				return connect.RunInBackground()
+			}
 			return Connect()
 		},
 		Example: "ktctl connect [command options]",
@@ -44,23 +47,15 @@ func Connect() error {
 	if err != nil {
 		return err
 	}
-
-	if !opt.Get().Connect.SkipCleanup {
-		go silenceCleanup()
+	connect.SetStopSignal(ch)
+	if err = connect.ServeControlApi(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to start control api, status of current process will not be available")
 	}
 
-	log.Info().Msgf("Using %s mode", opt.Get().Connect.Mode)
-	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks {
-		err = connect.ByTun2Socks()
-	} else if opt.Get().Connect.Mode == util.ConnectModeShuttle {
-		err = connect.BySshuttle()
-	} else {
-		err = fmt.Errorf("invalid connect mode: '%s', supportted mode are %s, %s", opt.Get().Connect.Mode,
-			util.ConnectModeTun2Socks, util.ConnectModeShuttle)
-	}
-	if err != nil {
+	if err = connectCluster(); err != nil {
 		return err
 	}
+	connect.SetReady()
 	log.Info().Msg("---------------------------------------------------------------")
 	log.Info().Msgf(" All looks good, now you can access to resources in the kubernetes cluster")
 	log.Info().Msg("---------------------------------------------------------------")
@@ -71,16 +66,78 @@ func Connect() error {
 	return nil
 }
 
+func connectCluster() error {
+	if err := checkConnectSessions(); err != nil {
+		return err
+	}
+	if !opt.Get().Connect.SkipCleanup {
+		go silenceCleanup()
+	}
+
+	log.Info().Msgf("Using %s mode", opt.Get().Connect.Mode)
+	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks {
+		return connect.ByTun2Socks()
+	} else if opt.Get().Connect.Mode == util.ConnectModeShuttle {
+		return connect.BySshuttle()
+	}
+	return fmt.Errorf("invalid connect mode: '%s', supportted mode are %s, %s", opt.Get().Connect.Mode,
+		util.ConnectModeTun2Socks, util.ConnectModeShuttle)
+}
+
 func preCheck() error {
 	if err := checkPermissionAndOptions(); err != nil {
 		return err
 	}
-	if pid := util.GetDaemonRunning(util.ComponentConnect); pid > 0 {
-		return fmt.Errorf("another connect process already running at %d, exiting", pid)
+	if opt.Get().Connect.DomainSuffix != "" {
+		// connect to another cluster alongside running connect process
+		return nil
+	}
+	if pid := util.GetDaemonRunning(util.ComponentConnect); pid > 0 && !isDomainSuffixSession(pid) {
+		return fmt.Errorf("another connect process already running at %d, use --domainSuffix to connect to another cluster at the same time", pid)
 	}
 	return nil
 }
 
+// checkConnectSessions make sure current context and domain suffix are not served by other connect process
+func checkConnectSessions() error {
+	sessions := util.GetConnectSessions()
+	if opt.Get().Connect.DomainSuffix != "" && !util.IsMacos() && !hasLocalDnsSession(sessions) {
+		// domain suffix is forwarded by local dns of the connect process which takes over system dns
+		return fmt.Errorf("domain suffix requires a connect process without --domainSuffix running in %s mode",
+			util.DnsModeLocalDns)
+	}
+	for _, session := range sessions {
+		if session.Context == opt.Store.KubeContext {
+			return fmt.Errorf("context '%s' is already connected by process %d", session.Context, session.Pid)
+		} else if session.DomainSuffix != opt.Get().Connect.DomainSuffix {
+			continue
+		} else if session.DomainSuffix == "" {
+			return fmt.Errorf("another connect process already running at %d, use --domainSuffix to connect to another cluster at the same time", session.Pid)
+		} else {
+			return fmt.Errorf("domain suffix '%s' is already used by process %d", session.DomainSuffix, session.Pid)
+		}
+	}
+	return nil
+}
+
+func hasLocalDnsSession(sessions []util.ConnectSession) bool {
+	for _, session := range sessions {
+		if session.DomainSuffix == "" && strings.HasPrefix(session.DnsMode, util.DnsModeLocalDns) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDomainSuffixSession(pid int) bool {
+	for _, session := range util.GetConnectSessions() {
+		if session.Pid == pid {
+			return session.DomainSuffix != ""
+		}
+	}
+	return false
+}
+
 func silenceCleanup() {
 	if r, err := clean.CheckClusterResources(); err == nil {
 		for _, name := range r.PodsToDelete {
@@ -99,6 +156,18 @@ func silenceCleanup() {
 }
 
 func checkPermissionAndOptions() error {
+	if opt.Get().Connect.ShadowReplicas < 1 {
+		return fmt.Errorf("shadow replicas should be at least 1")
+	}
+	if opt.Get().Connect.ShadowReplicas > 1 && opt.Get().Connect.Mode != util.ConnectModeTun2Socks {
+		return fmt.Errorf("multiple shadow replicas is only available for connect mode '%s'", util.ConnectModeTun2Socks)
+	}
+	if opt.Get().Connect.Rootless {
+		if opt.Get().Connect.Mode != util.ConnectModeTun2Socks || opt.Get().Connect.DomainSuffix != "" {
+			return fmt.Errorf("rootless is only available for connect mode '%s' without domain suffix", util.ConnectModeTun2Socks)
+		}
+		return nil
+	}
 	if !util.IsRunAsAdmin() {
 		if util.IsWindows() {
 			return fmt.Errorf("permission declined, please re-run connect command as Administrator")
@@ -108,5 +177,12 @@ func checkPermissionAndOptions() error {
 	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks && opt.Get().Connect.DnsMode == util.DnsModePodDns {
 		return fmt.Errorf("dns mode '%s' is not available for connect mode '%s'", util.DnsModePodDns, util.ConnectModeTun2Socks)
 	}
+	if opt.Get().Connect.DomainSuffix != "" && opt.Get().Connect.Mode != util.ConnectModeTun2Socks {
+		return fmt.Errorf("domain suffix is only available for connect mode '%s'", util.ConnectModeTun2Socks)
+	}
+	if opt.Get().Connect.Namespaces != "" && (!strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) ||
+		opt.Get().Connect.DomainSuffix != "") {
+		return fmt.Errorf("namespaces is only available for dns mode '%s' without domain suffix", util.DnsModeLocalDns)
+	}
 	return nil
 }
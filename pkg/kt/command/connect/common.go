@@ -3,10 +3,11 @@ package connect
 import (
 	"fmt"
 	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/dns"
-	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
@@ -14,7 +15,17 @@ import (
 	"time"
 )
 
-func setupDns(shadowPodName, shadowPodIp string) error {
+func setupDns(shadowPodIp string) error {
+	if opt.Get().Connect.DomainSuffix != "" {
+		dnsAddress, err := setupDomainSuffixDns()
+		if err != nil {
+			return err
+		}
+		return registerSession(dnsAddress)
+	}
+	if err := registerSession(""); err != nil {
+		return err
+	}
 	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeHosts) {
 		log.Info().Msgf("Setting up dns in hosts mode")
 		dump2HostsNamespaces := ""
@@ -30,14 +41,12 @@ func setupDns(shadowPodName, shadowPodIp string) error {
 		return dns.SetNameServer(shadowPodIp)
 	} else if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) {
 		log.Info().Msgf("Setting up dns in local mode")
-		svcToIp, headlessPods := getServiceHosts(opt.Get().Global.Namespace, true)
-		if err := dns.DumpHosts(svcToIp, ""); err != nil {
+		if err := dumpShortNames(getShortNameNamespaces()); err != nil {
 			return err
 		}
-		watchServicesAndPods(opt.Get().Global.Namespace, svcToIp, headlessPods, true)
 
 		forwardedPodPort := util.GetRandomTcpPort()
-		if _, err := transmission.SetupPortForwardToLocal(shadowPodName, common.StandardDnsPort, forwardedPodPort); err != nil {
+		if err := forwardDnsPortToLocal(forwardedPodPort); err != nil {
 			return err
 		}
 
@@ -61,6 +70,60 @@ func setupDns(shadowPodName, shadowPodIp string) error {
 	return nil
 }
 
+// setupDomainSuffixDns resolve domains with the suffix via local dns server of current process, system dns is left to
+// the connect process without domain suffix
+func setupDomainSuffixDns() (string, error) {
+	suffix := opt.Get().Connect.DomainSuffix
+	log.Info().Msgf("Setting up dns for domain suffix %s", suffix)
+	forwardedPodPort := util.GetRandomTcpPort()
+	if err := forwardDnsPortToLocal(forwardedPodPort); err != nil {
+		return "", err
+	}
+	dnsPort := util.GetRandomTcpPort()
+	if err := dns.SetupLocalDns(forwardedPodPort, dnsPort, []string{util.DnsOrderCluster}); err != nil {
+		log.Error().Err(err).Msgf("Failed to setup local dns server")
+		return "", err
+	}
+	dnsAddress := fmt.Sprintf("%s:%d", common.Localhost, dnsPort)
+	if err := dns.SetDomainNameServer(suffix, dnsAddress); err != nil {
+		return "", err
+	}
+	if !util.IsMacos() {
+		log.Info().Msgf("Domain suffix %s is resolvable while a connect process without --domainSuffix is running in %s mode",
+			suffix, util.DnsModeLocalDns)
+	}
+	return dnsAddress, nil
+}
+
+// session of current connect process, nil before registered
+var session *util.ConnectSession
+
+// registerSession record current connect process, so that connect processes of other clusters could cooperate with it
+func registerSession(dnsAddress string) error {
+	session = &util.ConnectSession{
+		Context:      opt.Store.KubeContext,
+		DomainSuffix: opt.Get().Connect.DomainSuffix,
+		DnsAddress:   dnsAddress,
+		DnsMode:      GetStatus().DnsMode,
+		Routes:       GetStatus().Routes,
+	}
+	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks && !opt.Get().Connect.DisableTunDevice {
+		session.TunName = tun.Ins().GetName()
+	}
+	return util.WriteConnectSession(session)
+}
+
+// updateSessionRoutes keep routes recorded in session up to date, so that other connect processes could avoid them
+func updateSessionRoutes(routes []string) {
+	if session == nil {
+		return
+	}
+	session.Routes = routes
+	if err := util.WriteConnectSession(session); err != nil {
+		log.Debug().Err(err).Msgf("Failed to update connect session")
+	}
+}
+
 func getDnsOrder(dnsMode string) []string {
 	if ! strings.Contains(dnsMode, ":") {
 		return []string{ util.DnsOrderCluster, util.DnsOrderUpstream }
@@ -68,26 +131,25 @@ func getDnsOrder(dnsMode string) []string {
 	return strings.Split(strings.SplitN(dnsMode, ":", 2)[1], ",")
 }
 
-func watchServicesAndPods(namespace string, svcToIp map[string]string, headlessPods []string, shortDomainOnly bool) {
+// watchServicesAndPods invoke refresh when services or headless pods in namespace changed, refresh should return
+// the latest headless pod names
+func watchServicesAndPods(namespace string, headlessPods []string, refresh func() []string) {
 	setupTime := time.Now().Unix()
 	go cluster.Ins().WatchService("", namespace,
 		func(svc *coreV1.Service) {
 			// ignore add service event during watch setup
 			if time.Now().Unix() - setupTime > 3 {
-				svcToIp, headlessPods = getServiceHosts(namespace, shortDomainOnly)
-				_ = dns.DumpHosts(svcToIp, namespace)
+				headlessPods = refresh()
 			}
 		},
 		func(svc *coreV1.Service) {
-			svcToIp, headlessPods = getServiceHosts(namespace, shortDomainOnly)
-			_ = dns.DumpHosts(svcToIp, namespace)
+			headlessPods = refresh()
 		}, nil)
 	go cluster.Ins().WatchPod("", namespace, nil, func(pod *coreV1.Pod) {
 		if util.Contains(headlessPods, pod.Name) {
 			// it may take some time for new pod get assign an ip
 			time.Sleep(5 * time.Second)
-			svcToIp, headlessPods = getServiceHosts(namespace, shortDomainOnly)
-			_ = dns.DumpHosts(svcToIp, namespace)
+			headlessPods = refresh()
 		}
 	}, nil)
 }
@@ -104,7 +166,12 @@ func dumpToHost(targetNamespaces string) error {
 	for _, namespace := range namespacesToDump {
 		log.Debug().Msgf("Search service in %s namespace ...", namespace)
 		svcToIp, headlessPods := getServiceHosts(namespace, false)
-		watchServicesAndPods(namespace, svcToIp, headlessPods, false)
+		ns := namespace
+		watchServicesAndPods(namespace, headlessPods, func() []string {
+			svcToIp, headlessPods := getServiceHosts(ns, false)
+			_ = dns.DumpHosts(svcToIp, ns)
+			return headlessPods
+		})
 		for svc, ip := range svcToIp {
 			hosts[svc] = ip
 		}
@@ -112,6 +179,75 @@ func dumpToHost(targetNamespaces string) error {
 	return dns.DumpHosts(hosts, "")
 }
 
+func getShortNameNamespaces() []string {
+	if opt.Get().Connect.Namespaces == "" {
+		return []string{opt.Get().Global.Namespace}
+	}
+	namespaces := make([]string, 0)
+	for _, ns := range strings.Split(opt.Get().Connect.Namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" && !util.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// dumpShortNames dump short service names of namespaces to hosts file, and keep them updated
+func dumpShortNames(namespaces []string) error {
+	svcToIp, headlessPods, collisions := getShortNameHosts(namespaces)
+	for name, nsList := range collisions {
+		log.Warn().Msgf("Service name '%s' exists in namespaces %s, short name is resolved to namespace '%s'",
+			name, strings.Join(nsList, ","), nsList[0])
+	}
+	if err := dns.DumpHosts(svcToIp, ""); err != nil {
+		return err
+	}
+	for _, namespace := range namespaces {
+		ns := namespace
+		watchServicesAndPods(namespace, headlessPods[namespace], func() []string {
+			// short names of all namespaces are dumped as a whole, since collision could change with any of them
+			svcToIp, headlessPods, _ := getShortNameHosts(namespaces)
+			_ = dns.DumpHosts(svcToIp, "")
+			return headlessPods[ns]
+		})
+	}
+	return nil
+}
+
+// getShortNameHosts get short name hosts of services in namespaces, returns hosts, headless pod names of each namespace
+// and namespaces of collided service names
+func getShortNameHosts(namespaces []string) (map[string]string, map[string][]string, map[string][]string) {
+	hostsOfNamespaces := make(map[string]map[string]string)
+	headlessPods := make(map[string][]string)
+	for _, namespace := range namespaces {
+		log.Debug().Msgf("Search service in %s namespace ...", namespace)
+		hostsOfNamespaces[namespace], headlessPods[namespace] = getServiceHosts(namespace, true)
+	}
+	hosts, collisions := mergeShortNameHosts(namespaces, hostsOfNamespaces)
+	return hosts, headlessPods, collisions
+}
+
+// mergeShortNameHosts merge hosts of namespaces in order, former namespace takes precedence when names collide
+func mergeShortNameHosts(namespaces []string, hostsOfNamespaces map[string]map[string]string) (map[string]string, map[string][]string) {
+	hosts := make(map[string]string)
+	owners := make(map[string][]string)
+	for _, namespace := range namespaces {
+		for name, ip := range hostsOfNamespaces[namespace] {
+			if _, exists := hosts[name]; !exists {
+				hosts[name] = ip
+			}
+			owners[name] = append(owners[name], namespace)
+		}
+	}
+	collisions := make(map[string][]string)
+	for name, nsList := range owners {
+		if len(nsList) > 1 {
+			collisions[name] = nsList
+		}
+	}
+	return hosts, collisions
+}
+
 func getServiceHosts(namespace string, shortDomainOnly bool) (map[string]string, []string) {
 	hosts := make(map[string]string)
 	podNames := make([]string, 0)
@@ -155,23 +291,39 @@ func getOrCreateShadow() (string, string, string, error) {
 		shadowPodName = fmt.Sprintf("kt-connect-shadow-daemon")
 	}
 
-	endPointIP, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, getLabels(),
-		make(map[string]string), getEnvs(), "", map[int]string{})
-	if err != nil {
-		return "", "", "", err
+	replicas := opt.Get().Connect.ShadowReplicas
+	for i := 0; i < replicas; i++ {
+		name := shadowPodName
+		labels := getLabels()
+		if replicas > 1 {
+			name = fmt.Sprintf("%s-%d", shadowPodName, i+1)
+			labels[util.KtShadowGroup] = shadowPodName
+		}
+		endPointIP, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(name, labels,
+			make(map[string]string), getEnvs(), "", map[int]string{})
+		if err != nil {
+			return "", "", "", err
+		}
+		setShadow(i, name, podName, endPointIP, privateKeyPath)
+		general.WatchShadow(name, podName, onShadowRecreated(i))
 	}
 
-	return endPointIP, podName, privateKeyPath, nil
+	primary := getShadow()
+	return primary.ip, primary.name, primary.privateKey, nil
 }
 
 func getEnvs() map[string]string {
 	envs := make(map[string]string)
 	localDomains := dns.GetLocalDomains()
+	if opt.Get().Connect.DomainSuffix != "" {
+		// shadow pod strips the suffix before looking up
+		localDomains = util.Append(localDomains, opt.Get().Connect.DomainSuffix)
+	}
 	if localDomains != "" {
 		log.Debug().Msgf("Found local domains: %s", localDomains)
 		envs[common.EnvVarLocalDomains] = localDomains
 	}
-	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) {
+	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) || opt.Get().Connect.DomainSuffix != "" {
 		envs[common.EnvVarDnsProtocol] = "tcp"
 	} else {
 		envs[common.EnvVarDnsProtocol] = "udp"
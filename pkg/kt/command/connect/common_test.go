@@ -0,0 +1,22 @@
+package connect
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMergeShortNameHosts(t *testing.T) {
+	hosts, collisions := mergeShortNameHosts([]string{"dev", "test", "default"}, map[string]map[string]string{
+		"default": {"tomcat": "10.0.0.1", "nginx": "10.0.0.2"},
+		"dev":     {"tomcat": "10.0.1.1"},
+		"test":    {"nginx": "10.0.2.2", "tomcat": "10.0.2.1", "redis": "10.0.2.3"},
+	})
+	require.Equal(t, map[string]string{"tomcat": "10.0.1.1", "nginx": "10.0.2.2", "redis": "10.0.2.3"}, hosts)
+	require.Equal(t, map[string][]string{"tomcat": {"dev", "test", "default"}, "nginx": {"test", "default"}}, collisions)
+
+	hosts, collisions = mergeShortNameHosts([]string{"default"}, map[string]map[string]string{
+		"default": {"tomcat": "10.0.0.1"},
+	})
+	require.Equal(t, map[string]string{"tomcat": "10.0.0.1"}, hosts)
+	require.Empty(t, collisions)
+}
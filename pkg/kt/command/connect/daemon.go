@@ -0,0 +1,94 @@
+package connect
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// daemonStartTimeout max seconds to wait for background connect process ready
+const daemonStartTimeout = 120
+
+// daemonStopTimeout max seconds to wait for background connect process cleaning up after disconnected
+const daemonStopTimeout = 30
+
+// RunInBackground start a detached connect process with same options, and wait for it ready
+func RunInBackground() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := make([]string, 0)
+	for _, arg := range os.Args[1:] {
+		if arg != "--daemon" && !strings.HasPrefix(arg, "--daemon=") {
+			args = append(args, arg)
+		}
+	}
+	// avoid background process fork itself again when daemon option is configured as default
+	args = append(args, "--daemon=false")
+
+	logFile, err := ioutil.TempFile(os.TempDir(), "kt-connect-")
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	_ = util.FixFileOwner(logFile.Name())
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	util.DetachProcess(cmd)
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	log.Info().Msgf("Connect process started in background at %d, logging to %s", pid, logFile.Name())
+	for i := 0; i < daemonStartTimeout; i++ {
+		select {
+		case <-exited:
+			return fmt.Errorf("background connect process exited, check %s for detail", logFile.Name())
+		case <-time.After(1 * time.Second):
+		}
+		if status, err2 := control.GetStatus(pid); err2 == nil && status.Ready {
+			log.Info().Msg("---------------------------------------------------------------")
+			log.Info().Msgf(" Connected, use 'ktctl status' to check and 'ktctl disconnect' to stop it")
+			log.Info().Msg("---------------------------------------------------------------")
+			return nil
+		}
+	}
+	// do not leave shadow pod and routes of a connect process considered failed
+	stopBackgroundProcess(cmd, exited)
+	return fmt.Errorf("background connect process not ready in %d seconds, check %s for detail",
+		daemonStartTimeout, logFile.Name())
+}
+
+// stopBackgroundProcess disconnect background process to let it clean up, and kill it if not exited in time
+func stopBackgroundProcess(cmd *exec.Cmd, exited chan error) {
+	pid := cmd.Process.Pid
+	if err := control.Disconnect(pid); err != nil {
+		log.Debug().Err(err).Msgf("Failed to disconnect background connect process %d", pid)
+	} else {
+		select {
+		case <-exited:
+			log.Info().Msgf("Background connect process %d stopped", pid)
+			return
+		case <-time.After(daemonStopTimeout * time.Second):
+		}
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to kill background connect process %d", pid)
+	} else {
+		log.Warn().Msgf("Background connect process %d killed, please run 'ktctl clean' to remove its resources", pid)
+	}
+}
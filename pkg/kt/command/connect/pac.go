@@ -0,0 +1,53 @@
+package connect
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"net"
+	"strings"
+	"sync"
+)
+
+var pacCidr []string
+var pacCidrOnce sync.Once
+
+// getPacContent generate pac file which only route cluster domains and ips to the http proxy
+func getPacContent(proxyAddress string) string {
+	pacCidrOnce.Do(func() {
+		pacCidr, _ = cluster.Ins().ClusterCidr(opt.Get().Global.Namespace)
+		log.Debug().Msgf("Cluster CIDR in pac file: %v", pacCidr)
+	})
+	domains := []string{"svc." + opt.Get().Connect.ClusterDomain}
+	for _, ns := range append([]string{opt.Get().Global.Namespace}, getShortNameNamespaces()...) {
+		if !util.Contains(domains, ns) {
+			domains = append(domains, ns+".svc", ns)
+		}
+	}
+	return generatePac(proxyAddress, domains, pacCidr)
+}
+
+// generatePac hosts end with cluster or namespace domain suffixes or inside cidr go through proxy, plain host names
+// are left direct since they could be intranet hosts, ip is only checked when host is an ip address, to avoid
+// resolving domain names by browser
+func generatePac(proxyAddress string, domains []string, cidr []string) string {
+	var sb strings.Builder
+	sb.WriteString("function FindProxyForURL(url, host) {\n")
+	sb.WriteString(fmt.Sprintf("  var proxy = \"PROXY %s\";\n", proxyAddress))
+	for _, domain := range domains {
+		sb.WriteString(fmt.Sprintf("  if (dnsDomainIs(host, \".%s\")) {\n    return proxy;\n  }\n", domain))
+	}
+	sb.WriteString("  if (/^\\d+\\.\\d+\\.\\d+\\.\\d+$/.test(host)) {\n")
+	for _, c := range cidr {
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil || ipNet.IP.To4() == nil {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    if (isInNet(host, \"%s\", \"%s\")) {\n      return proxy;\n    }\n",
+			ipNet.IP.String(), net.IP(ipNet.Mask).String()))
+	}
+	sb.WriteString("  }\n  return \"DIRECT\";\n}\n")
+	return sb.String()
+}
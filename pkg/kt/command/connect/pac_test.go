@@ -0,0 +1,33 @@
+package connect
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGeneratePac(t *testing.T) {
+	pac := generatePac("127.0.0.1:2224", []string{"svc.cluster.local", "default.svc", "default"},
+		[]string{"10.96.0.0/12", "172.16.0.0/16", "fd00::/64"})
+	require.Equal(t, `function FindProxyForURL(url, host) {
+  var proxy = "PROXY 127.0.0.1:2224";
+  if (dnsDomainIs(host, ".svc.cluster.local")) {
+    return proxy;
+  }
+  if (dnsDomainIs(host, ".default.svc")) {
+    return proxy;
+  }
+  if (dnsDomainIs(host, ".default")) {
+    return proxy;
+  }
+  if (/^\d+\.\d+\.\d+\.\d+$/.test(host)) {
+    if (isInNet(host, "10.96.0.0", "255.240.0.0")) {
+      return proxy;
+    }
+    if (isInNet(host, "172.16.0.0", "255.255.0.0")) {
+      return proxy;
+    }
+  }
+  return "DIRECT";
+}
+`, pac)
+}
@@ -0,0 +1,87 @@
+package connect
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// proxyAuthUser username of auto generated proxy credential
+const proxyAuthUser = "kt"
+
+// proxyAuth credential required by local proxies, nil for no authentication
+var proxyAuth *proxy.Auth
+
+// setupProxyAuth resolve credential of local proxies from option, a random one is generated for each session in auto mode
+func setupProxyAuth() error {
+	auth, err := parseProxyAuth(opt.Get().Connect.ProxyAuth)
+	if err != nil {
+		return err
+	}
+	if auth == nil {
+		if !isLoopbackAddress(opt.Get().Connect.ProxyAddr) {
+			log.Warn().Msgf("Proxy is accessible to anyone who can reach %s, consider using '--proxyAuth %s'",
+				opt.Get().Connect.ProxyAddr, util.ProxyAuthAuto)
+		}
+		return nil
+	}
+	proxyAuth = auth
+	if opt.Get().Connect.ProxyAuth == util.ProxyAuthAuto {
+		log.Info().Msgf("Proxy authentication enabled, username: %s, password: %s", auth.User, auth.Password)
+	} else {
+		log.Info().Msgf("Proxy authentication enabled, username: %s", auth.User)
+	}
+	return nil
+}
+
+func parseProxyAuth(value string) (*proxy.Auth, error) {
+	if value == "" {
+		return nil, nil
+	} else if value == util.ProxyAuthAuto {
+		password := make([]byte, 12)
+		if _, err := rand.Read(password); err != nil {
+			return nil, err
+		}
+		return &proxy.Auth{User: proxyAuthUser, Password: hex.EncodeToString(password)}, nil
+	}
+	parts := strings.SplitN(value, ":", 2)
+	// rfc 1929 limits length of username and password to 255
+	if len(parts) != 2 || parts[0] == "" || len(parts[0]) > 255 || len(parts[1]) > 255 {
+		return nil, fmt.Errorf("invalid proxy auth '%s', should be '%s' or in '<username>:<password>' format",
+			value, util.ProxyAuthAuto)
+	}
+	return &proxy.Auth{User: parts[0], Password: parts[1]}, nil
+}
+
+// proxyUrl get url of local proxy, with credential if authentication required
+func proxyUrl(scheme, address string) string {
+	u := &url.URL{Scheme: scheme, Host: address}
+	if proxyAuth != nil {
+		u.User = url.UserPassword(proxyAuth.User, proxyAuth.Password)
+	}
+	return u.String()
+}
+
+// maskedProxyUrl get url of local proxy to show in messages, password is replaced with 'xxxxx'
+func maskedProxyUrl(scheme, address string) string {
+	u, err := url.Parse(proxyUrl(scheme, address))
+	if err != nil {
+		return fmt.Sprintf("%s://%s", scheme, address)
+	}
+	return u.Redacted()
+}
+
+func isLoopbackAddress(address string) bool {
+	if address == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,227 @@
+package connect
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/proxy"
+	"io"
+	coreV1 "k8s.io/api/core/v1"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serviceListener local listener of a cluster service port
+type serviceListener struct {
+	listener  net.Listener
+	localPort int
+	// target domain of service port, resolved by shadow pod
+	target string
+}
+
+// rootlessForwarder expose cluster services via local listeners, and forward traffic through socks proxy,
+// so that neither tun device nor system dns configuration is required
+type rootlessForwarder struct {
+	dialer     proxy.Dialer
+	namespaces []string
+	// listeners keyed by <service>.<namespace>:<port>
+	listeners map[string]*serviceListener
+	lock      sync.Mutex
+}
+
+// setupRootlessForward listen services of namespaces on localhost, keep listeners updated with service changes
+func setupRootlessForward(socks5Address string, namespaces []string) error {
+	dialer, err := proxy.SOCKS5("tcp", socks5Address, proxyAuth, proxy.Direct)
+	if err != nil {
+		return err
+	}
+	f := &rootlessForwarder{
+		dialer:     dialer,
+		namespaces: namespaces,
+		listeners:  make(map[string]*serviceListener),
+	}
+	if err = f.refresh(); err != nil {
+		return err
+	}
+	setupTime := time.Now().Unix()
+	for _, namespace := range namespaces {
+		go cluster.Ins().WatchService("", namespace,
+			func(svc *coreV1.Service) {
+				// ignore add service event during watch setup
+				if time.Now().Unix()-setupTime > 3 {
+					_ = f.refresh()
+				}
+			},
+			func(svc *coreV1.Service) {
+				_ = f.refresh()
+			},
+			func(svc *coreV1.Service) {
+				_ = f.refresh()
+			})
+	}
+	log.Info().Msgf("Service environment variables are written to %s", util.KtServicesEnvFile)
+	return nil
+}
+
+// refresh open listeners for new service ports, and close listeners of removed ones
+func (f *rootlessForwarder) refresh() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ports := make(map[string]string)
+	servicesOfNamespaces := make(map[string][]coreV1.Service)
+	for _, namespace := range f.namespaces {
+		services, err := cluster.Ins().GetAllServiceInNamespace(namespace)
+		if err != nil {
+			return err
+		}
+		servicesOfNamespaces[namespace] = services.Items
+		for _, svc := range services.Items {
+			for _, p := range svc.Spec.Ports {
+				if p.Protocol == coreV1.ProtocolUDP {
+					// only tcp traffic could go through socks proxy
+					continue
+				}
+				ports[fmt.Sprintf("%s.%s:%d", svc.Name, namespace, p.Port)] = fmt.Sprintf("%s.%s.svc.%s:%d",
+					svc.Name, namespace, opt.Get().Connect.ClusterDomain, p.Port)
+			}
+		}
+	}
+	for key, l := range f.listeners {
+		if _, exists := ports[key]; !exists {
+			_ = l.listener.Close()
+			delete(f.listeners, key)
+			log.Info().Msgf("Service %s removed, stop listening on port %d", key, l.localPort)
+		}
+	}
+	for _, key := range sortedKeys(ports) {
+		if _, exists := f.listeners[key]; exists {
+			continue
+		}
+		l, err := f.listen(key, ports[key])
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to listen for service %s", key)
+			continue
+		}
+		f.listeners[key] = l
+		log.Info().Msgf("Service %s is accessible via %s:%d", key, common.Localhost, l.localPort)
+	}
+	return writeServicesEnvFile(getServiceEnvs(f.namespaces, servicesOfNamespaces, f.localPortOf))
+}
+
+func (f *rootlessForwarder) localPortOf(namespace, name string, port int32) int {
+	if l, exists := f.listeners[fmt.Sprintf("%s.%s:%d", name, namespace, port)]; exists {
+		return l.localPort
+	}
+	return -1
+}
+
+// listen use same port as service if possible, otherwise a random port
+func (f *rootlessForwarder) listen(key, target string) (*serviceListener, error) {
+	_, servicePort, _ := net.SplitHostPort(key)
+	listener, err := net.Listen("tcp", net.JoinHostPort(common.Localhost, servicePort))
+	if err != nil {
+		log.Debug().Err(err).Msgf("Port %s not available for service %s", servicePort, key)
+		if listener, err = net.Listen("tcp", net.JoinHostPort(common.Localhost, "0")); err != nil {
+			return nil, err
+		}
+	}
+	l := &serviceListener{
+		listener:  listener,
+		localPort: listener.Addr().(*net.TCPAddr).Port,
+		target:    target,
+	}
+	go f.serve(l)
+	return l, nil
+}
+
+func (f *rootlessForwarder) serve(l *serviceListener) {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			log.Debug().Err(err).Msgf("Listener of %s closed", l.target)
+			return
+		}
+		go func() {
+			remote, err2 := f.dialer.Dial("tcp", l.target)
+			if err2 != nil {
+				log.Warn().Err(err2).Msgf("Failed to connect to %s", l.target)
+				_ = conn.Close()
+				return
+			}
+			relayConn(conn, remote)
+		}()
+	}
+}
+
+// relayConn transfer data between two connections, until either side closed
+func relayConn(local, remote net.Conn) {
+	done := make(chan int, 2)
+	go func() {
+		_, _ = io.Copy(remote, local)
+		done <- 1
+	}()
+	go func() {
+		_, _ = io.Copy(local, remote)
+		done <- 1
+	}()
+	<-done
+	_ = local.Close()
+	_ = remote.Close()
+}
+
+// getServiceEnvs generate kubernetes style service environment variables pointing to local listeners, as an
+// alternative of hosts file which requires root permission, former namespace takes precedence when names collide
+func getServiceEnvs(namespaces []string, servicesOfNamespaces map[string][]coreV1.Service,
+	localPortOf func(namespace, name string, port int32) int) map[string]string {
+	envs := make(map[string]string)
+	for _, namespace := range namespaces {
+		for _, svc := range servicesOfNamespaces[namespace] {
+			prefix := strings.ToUpper(strings.ReplaceAll(svc.Name, "-", "_"))
+			if _, exists := envs[prefix+"_SERVICE_HOST"]; exists {
+				continue
+			}
+			for _, p := range svc.Spec.Ports {
+				localPort := localPortOf(namespace, svc.Name, p.Port)
+				if localPort < 0 {
+					continue
+				}
+				if _, exists := envs[prefix+"_SERVICE_HOST"]; !exists {
+					envs[prefix+"_SERVICE_HOST"] = common.Localhost
+					envs[prefix+"_SERVICE_PORT"] = fmt.Sprintf("%d", localPort)
+				}
+				if p.Name != "" {
+					envs[prefix+"_SERVICE_PORT_"+strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_"))] =
+						fmt.Sprintf("%d", localPort)
+				}
+			}
+		}
+	}
+	return envs
+}
+
+func writeServicesEnvFile(envs map[string]string) error {
+	var sb strings.Builder
+	for _, name := range sortedKeys(envs) {
+		sb.WriteString(fmt.Sprintf("%s=%s%s", name, envs[name], util.Eol))
+	}
+	if err := os.WriteFile(util.KtServicesEnvFile, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	return util.FixFileOwner(util.KtServicesEnvFile)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package connect
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"testing"
+)
+
+func TestGetServiceEnvs(t *testing.T) {
+	newService := func(name string, ports ...coreV1.ServicePort) coreV1.Service {
+		return coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: coreV1.ServiceSpec{Ports: ports}}
+	}
+	localPorts := map[string]int{
+		"tomcat.dev:8080":     8080,
+		"tomcat.test:8080":    18080,
+		"my-redis.test:6379":  6379,
+		"my-redis.test:26379": 26379,
+	}
+	envs := getServiceEnvs([]string{"dev", "test"}, map[string][]coreV1.Service{
+		"dev": {newService("tomcat", coreV1.ServicePort{Port: 8080})},
+		"test": {
+			newService("tomcat", coreV1.ServicePort{Port: 8080}),
+			newService("my-redis", coreV1.ServicePort{Name: "redis", Port: 6379},
+				coreV1.ServicePort{Name: "sentinel-port", Port: 26379}),
+			newService("dns", coreV1.ServicePort{Port: 53, Protocol: coreV1.ProtocolUDP}),
+		},
+	}, func(namespace, name string, port int32) int {
+		if p, exists := localPorts[fmt.Sprintf("%s.%s:%d", name, namespace, port)]; exists {
+			return p
+		}
+		return -1
+	})
+	require.Equal(t, map[string]string{
+		"TOMCAT_SERVICE_HOST":                 "127.0.0.1",
+		"TOMCAT_SERVICE_PORT":                 "8080",
+		"MY_REDIS_SERVICE_HOST":               "127.0.0.1",
+		"MY_REDIS_SERVICE_PORT":               "6379",
+		"MY_REDIS_SERVICE_PORT_REDIS":         "6379",
+		"MY_REDIS_SERVICE_PORT_SENTINEL_PORT": "26379",
+	}, envs)
+}
+
+func TestRelayConn(t *testing.T) {
+	local, client := net.Pipe()
+	remote, server := net.Pipe()
+	go relayConn(local, remote)
+	go func() {
+		buf := make([]byte, 4)
+		n, _ := server.Read(buf)
+		_, _ = server.Write(append([]byte("echo:"), buf[:n]...))
+		_ = server.Close()
+	}()
+	_, err := client.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := client.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "echo:ping", string(buf[:n]))
+}
@@ -0,0 +1,57 @@
+package connect
+
+import (
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+)
+
+// watchTunRoute add or remove routes of tun device while ip ranges of cluster changing
+func watchTunRoute(cidr, routes []string) {
+	current := routes
+	go cluster.Ins().WatchClusterCidr(opt.Get().Global.Namespace, cidr, func(latestCidr, excludeCidr []string) {
+		latest := tun.ExcludeRanges(latestCidr, excludeCidr)
+		toAdd, toRemove := diffRoutes(current, latest)
+		log.Info().Msgf("Cluster ip ranges changed, updating routes")
+		if r, routed, other := util.FindOverlappedRoute(toAdd, util.GetConnectSessions()); other != nil {
+			log.Warn().Msgf("Ip range %s overlaps with %s routed by connect process %d of context '%s'",
+				r, routed, other.Pid, other.Context)
+		}
+		// add new routes first, in case a range is replaced by a larger one
+		if len(toAdd) > 0 {
+			if err := tun.Ins().AddRoute(toAdd); err != nil {
+				log.Warn().Msgf("Some route rule is not setup properly")
+			}
+		}
+		if len(toRemove) > 0 {
+			if err := tun.Ins().RemoveRoute(toRemove); err != nil {
+				log.Warn().Msgf("Some route rule is not removed properly")
+			}
+		}
+		current = latest
+		updateStatus(func(s *control.ConnectStatus) {
+			s.Routes = latest
+		})
+		updateSessionRoutes(latest)
+	})
+}
+
+// diffRoutes get ranges only exist in latest routes and ranges only exist in current routes
+func diffRoutes(current, latest []string) ([]string, []string) {
+	toAdd := make([]string, 0)
+	toRemove := make([]string, 0)
+	for _, r := range latest {
+		if !util.Contains(current, r) {
+			toAdd = append(toAdd, r)
+		}
+	}
+	for _, r := range current {
+		if !util.Contains(latest, r) {
+			toRemove = append(toRemove, r)
+		}
+	}
+	return toAdd, toRemove
+}
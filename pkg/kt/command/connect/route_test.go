@@ -0,0 +1,17 @@
+package connect
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffRoutes(t *testing.T) {
+	toAdd, toRemove := diffRoutes([]string{"10.96.0.0/16", "172.168.0.0/24"},
+		[]string{"10.96.0.0/16", "172.168.0.0/16", "172.169.3.0/24"})
+	require.Equal(t, []string{"172.168.0.0/16", "172.169.3.0/24"}, toAdd)
+	require.Equal(t, []string{"172.168.0.0/24"}, toRemove)
+
+	toAdd, toRemove = diffRoutes([]string{"10.96.0.0/16"}, []string{"10.96.0.0/16"})
+	require.Empty(t, toAdd)
+	require.Empty(t, toRemove)
+}
@@ -0,0 +1,216 @@
+package connect
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/dns"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strings"
+	"sync"
+)
+
+// shadowPod the shadow pod current process connected to, it changes after shadow pod recreated
+type shadowPod struct {
+	// resource name of shadow pod or deployment, which keeps unchanged after recreated
+	resource   string
+	name       string
+	ip         string
+	privateKey string
+}
+
+// shadows all shadow replicas, dns is served by any healthy one of them
+var shadows []shadowPod
+var shadowLock sync.RWMutex
+
+// unhealthyReplicas index of shadow replicas whose tunnel is broken
+var unhealthyReplicas = map[int]bool{}
+
+// dnsReplica index of shadow replica currently serving dns
+var dnsReplica = 0
+
+// getShadow get the primary shadow pod
+func getShadow() shadowPod {
+	return getShadowReplica(0)
+}
+
+func getShadowReplica(index int) shadowPod {
+	shadowLock.RLock()
+	defer shadowLock.RUnlock()
+	if index >= len(shadows) {
+		return shadowPod{}
+	}
+	return shadows[index]
+}
+
+func setShadow(index int, resource, podName, podIP, privateKey string) {
+	shadowLock.Lock()
+	for len(shadows) <= index {
+		shadows = append(shadows, shadowPod{})
+	}
+	shadows[index] = shadowPod{resource: resource, name: podName, ip: podIP, privateKey: privateKey}
+	names := make([]string, 0, len(shadows))
+	ips := make([]string, 0, len(shadows))
+	for _, sp := range shadows {
+		names = append(names, sp.name)
+		ips = append(ips, sp.ip)
+	}
+	shadowLock.Unlock()
+	updateStatus(func(s *control.ConnectStatus) {
+		s.Shadow = strings.Join(names, ",")
+		s.ShadowIp = strings.Join(ips, ",")
+	})
+}
+
+// getDnsShadow get the shadow pod currently serving dns
+func getDnsShadow() shadowPod {
+	shadowLock.RLock()
+	index := dnsReplica
+	shadowLock.RUnlock()
+	return getShadowReplica(index)
+}
+
+// setReplicaHealthy record whether tunnel to shadow replica works, dns is moved away from broken replica
+func setReplicaHealthy(index int, healthy bool) {
+	shadowLock.Lock()
+	unhealthyReplicas[index] = !healthy
+	isDnsReplica := index == dnsReplica
+	shadowLock.Unlock()
+	if !healthy && isDnsReplica && opt.Get().Connect.DnsMode == util.DnsModePodDns {
+		switchPodDns()
+	}
+}
+
+// nextDnsReplica move dns to next healthy replica, keep current one if no other replica is healthy
+func nextDnsReplica() int {
+	shadowLock.Lock()
+	defer shadowLock.Unlock()
+	for i := 1; i < len(shadows); i++ {
+		index := (dnsReplica + i) % len(shadows)
+		if !unhealthyReplicas[index] {
+			dnsReplica = index
+			break
+		}
+	}
+	return dnsReplica
+}
+
+// switchPodDns let system resolve domains via another healthy shadow replica in pod dns mode
+func switchPodDns() {
+	previous := getDnsShadow()
+	current := getShadowReplica(nextDnsReplica())
+	if current.ip == previous.ip {
+		return
+	}
+	if err := dns.UpdateNameServer(current.ip); err != nil {
+		log.Warn().Err(err).Msgf("Failed to switch dns server to shadow pod %s", current.name)
+		return
+	}
+	log.Info().Msgf("Dns server switched from shadow pod %s to %s", previous.name, current.name)
+}
+
+// forwardDnsPortToLocal keep port forward to dns port of shadow replicas alive, it moves to another healthy replica
+// when reconnecting, so that dns won't depend on any single replica
+func forwardDnsPortToLocal(localPort int) error {
+	s := transmission.NewPortForwardSupervisor(func() string {
+		return getDnsShadow().name
+	}, common.StandardDnsPort, localPort)
+	s.BeforeReconnect = func() error {
+		return ensureShadowAlive(nextDnsReplica())
+	}
+	trackConnection(s)
+	if err := s.Start(); err != nil {
+		log.Error().Err(err).Msgf("Failed to setup port forward local:%d -> pod %s:%d",
+			localPort, getDnsShadow().name, common.StandardDnsPort)
+		return err
+	}
+	return nil
+}
+
+// forwardShadowPortToLocal keep port forward to specified shadow replica alive, shadow pod is recreated if it's gone
+func forwardShadowPortToLocal(index, remotePort, localPort int) error {
+	s := transmission.NewPortForwardSupervisor(func() string {
+		return getShadowReplica(index).name
+	}, remotePort, localPort)
+	s.BeforeReconnect = func() error {
+		return ensureShadowAlive(index)
+	}
+	trackConnection(s)
+	if err := s.Start(); err != nil {
+		log.Error().Err(err).Msgf("Failed to setup port forward local:%d -> pod %s:%d",
+			localPort, getShadowReplica(index).name, remotePort)
+		return err
+	}
+	return nil
+}
+
+// ensureShadowAlive check whether shadow pod is deleted or evicted before reconnecting, in case the event is missed
+// by shadow watcher
+func ensureShadowAlive(index int) error {
+	current := getShadowReplica(index)
+	pod, err := general.EnsureShadowAlive(current.resource, current.name)
+	if err != nil {
+		return err
+	}
+	onShadowRecreated(index)(pod)
+	return nil
+}
+
+// onShadowRecreated connections will be redirected to the new shadow pod when reconnecting
+func onShadowRecreated(index int) func(pod *coreV1.Pod) {
+	return func(pod *coreV1.Pod) {
+		current := getShadowReplica(index)
+		if pod.Name == current.name && pod.Status.PodIP == current.ip {
+			return
+		}
+		setShadow(index, current.resource, pod.Name, pod.Status.PodIP, current.privateKey)
+		if opt.Get().Connect.DnsMode == util.DnsModePodDns && getDnsShadow().name == pod.Name {
+			if err := dns.UpdateNameServer(pod.Status.PodIP); err != nil {
+				log.Warn().Err(err).Msgf("Failed to update dns server to recreated shadow pod %s", pod.Name)
+			} else {
+				log.Info().Msgf("Dns server updated to recreated shadow pod %s", pod.Name)
+			}
+		}
+	}
+}
+
+// trackConnection record state of connection into status, and exit current process when it's totally failed
+func trackConnection(s *transmission.Supervisor) {
+	s.OnStateChange = func(state string, err error) {
+		updateStatus(func(st *control.ConnectStatus) {
+			if st.Connections == nil {
+				st.Connections = make(map[string]string)
+			}
+			if st.Connections[s.Name] == control.HealthReconnecting && state == control.HealthHealthy {
+				st.ReconnectCount++
+			}
+			st.Connections[s.Name] = state
+			if err != nil {
+				st.LastError = fmt.Sprintf("%s: %s", s.Name, err.Error())
+			}
+			st.Health = worstHealth(st.Connections)
+		})
+	}
+	s.OnGiveUp = func() {
+		log.Error().Msgf("Connection to cluster is lost, exiting")
+		stopConnect()
+	}
+}
+
+// worstHealth summarize health of all connections
+func worstHealth(connections map[string]string) string {
+	ordered := []string{control.HealthFailed, control.HealthReconnecting, control.HealthConnecting, control.HealthDegraded}
+	for _, health := range ordered {
+		for _, state := range connections {
+			if state == health {
+				return health
+			}
+		}
+	}
+	return control.HealthHealthy
+}
@@ -0,0 +1,20 @@
+package connect
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWorstHealth(t *testing.T) {
+	require.Equal(t, control.HealthHealthy, worstHealth(map[string]string{}))
+	require.Equal(t, control.HealthDegraded, worstHealth(map[string]string{
+		"Port forward local:2222": control.HealthHealthy,
+		"Socks proxy":             control.HealthDegraded,
+	}))
+	require.Equal(t, control.HealthReconnecting, worstHealth(map[string]string{
+		"Port forward local:2222": control.HealthReconnecting,
+		"Port forward local:5353": control.HealthHealthy,
+		"Socks proxy":             control.HealthDegraded,
+	}))
+}
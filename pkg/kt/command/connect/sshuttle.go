@@ -4,25 +4,31 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/common"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/sshuttle"
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	"os"
+	"sync"
 	"time"
 )
 
 func BySshuttle() error {
 	checkSshuttleInstalled()
 
-	podIP, podName, privateKeyPath, err := getOrCreateShadow()
+	podIP, _, privateKeyPath, err := getOrCreateShadow()
 	if err != nil {
 		return err
 	}
 
 	cidr, excludeCidr := cluster.Ins().ClusterCidr(opt.Get().Global.Namespace)
+	updateStatus(func(s *control.ConnectStatus) {
+		s.Routes = cidr
+	})
 
 	localSshPort := util.GetRandomTcpPort()
-	if _, err = transmission.SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
+	if err = forwardShadowPortToLocal(0, common.StandardSshPort, localSshPort); err != nil {
 		return err
 	}
 
@@ -36,26 +42,68 @@ func BySshuttle() error {
 	if err = startSshuttle(req); err != nil {
 		return err
 	}
+	watchSshuttleRoute(req)
 
-	return setupDns(podName, podIP)
+	return setupDns(podIP)
 }
 
+// sshuttleRestart notify running sshuttle to restart with the latest ip ranges
+var sshuttleRestart = make(chan struct{}, 1)
+var sshuttleLock sync.Mutex
+
 func startSshuttle(req *sshuttle.SSHVPNRequest) error {
-	res := make(chan error)
-	if err := util.BackgroundRun(sshuttle.Ins().Connect(req), "vpn(sshuttle)", res); err != nil {
-		return err
-	}
+	s := transmission.NewSupervisor("Sshuttle", func(ready func()) error {
+		for {
+			select {
+			case <-sshuttleRestart:
+				// ip ranges already up to date
+			default:
+			}
+			// shadow pod may be recreated during reconnecting
+			current := getShadow()
+			sshuttleLock.Lock()
+			req.RemoteSSHPKPath = current.privateKey
+			req.RemoteDNSServerAddress = current.ip
+			cmd := sshuttle.Ins().Connect(req)
+			sshuttleLock.Unlock()
+			res := make(chan error)
+			if err := util.BackgroundRun(cmd, "vpn(sshuttle)", res); err != nil {
+				return err
+			}
+			timer := time.AfterFunc(1*time.Second, ready)
+			select {
+			case err := <-res:
+				timer.Stop()
+				return err
+			case <-sshuttleRestart:
+				timer.Stop()
+				log.Info().Msgf("Restarting sshuttle with updated ip ranges")
+				// sshuttle restores firewall rules when interrupted
+				_ = cmd.Process.Signal(os.Interrupt)
+				<-res
+			}
+		}
+	})
+	trackConnection(s)
+	return s.Start()
+}
 
-	go func() {
+// watchSshuttleRoute restart sshuttle with the latest subnets while ip ranges of cluster changing
+func watchSshuttleRoute(req *sshuttle.SSHVPNRequest) {
+	go cluster.Ins().WatchClusterCidr(opt.Get().Global.Namespace, req.IncludeCIDR, func(cidr, excludeCidr []string) {
+		log.Info().Msgf("Cluster ip ranges changed to %v", cidr)
+		sshuttleLock.Lock()
+		req.IncludeCIDR = cidr
+		req.ExcludeCIDR = excludeCidr
+		sshuttleLock.Unlock()
+		updateStatus(func(s *control.ConnectStatus) {
+			s.Routes = cidr
+		})
 		select {
-		case <-res:
-			time.Sleep(10 * time.Second)
-			log.Debug().Msgf("Restarting sshuttle ...")
-			_ = startSshuttle(req)
+		case sshuttleRestart <- struct{}{}:
+		default:
 		}
-	}()
-
-	return nil
+	})
 }
 
 func checkSshuttleInstalled() {
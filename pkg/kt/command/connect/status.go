@@ -0,0 +1,81 @@
+package connect
+
+import (
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"os"
+	"sync"
+	"time"
+)
+
+var status = &control.ConnectStatus{Health: control.HealthConnecting}
+var statusLock sync.Mutex
+
+// stopConnect interrupt current connect process
+var stopConnect = func() {}
+
+// SetStopSignal let current connect process be interrupted via signal channel, e.g. when connection is lost
+func SetStopSignal(ch chan os.Signal) {
+	stopConnect = func() {
+		select {
+		case ch <- os.Interrupt:
+		default:
+			// process is already interrupting
+		}
+	}
+}
+
+// ServeControlApi expose status of current connect process, and allow disconnecting it via control api
+func ServeControlApi() error {
+	status.StartTime = time.Now().Unix()
+	return control.Serve(GetStatus, func() {
+		stopConnect()
+	})
+}
+
+// SetReady mark network and dns setup finished
+func SetReady() {
+	updateStatus(func(s *control.ConnectStatus) {
+		s.Ready = true
+	})
+}
+
+// GetStatus get a snapshot of current connect status
+func GetStatus() *control.ConnectStatus {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	snapshot := *status
+	snapshot.Routes = append([]string{}, status.Routes...)
+	snapshot.Connections = make(map[string]string)
+	for name, state := range status.Connections {
+		snapshot.Connections[name] = state
+	}
+	snapshot.Pid = os.Getpid()
+	snapshot.Context = opt.Store.KubeContext
+	snapshot.Namespace = opt.Get().Global.Namespace
+	snapshot.Mode = opt.Get().Connect.Mode
+	snapshot.Rootless = opt.Get().Connect.Rootless
+	snapshot.DnsMode = opt.Get().Connect.DnsMode
+	if opt.Get().Connect.Rootless {
+		// service names are not resolved in rootless mode, only local ports and services env file available
+		snapshot.DnsMode = "none"
+	}
+	snapshot.DomainSuffix = opt.Get().Connect.DomainSuffix
+	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks {
+		snapshot.ProxyPort = opt.Get().Connect.ProxyPort
+		if !opt.Get().Connect.DisableTunDevice {
+			snapshot.TunName = tun.Ins().GetName()
+		} else {
+			snapshot.HttpProxyPort = opt.Get().Connect.HttpProxyPort
+		}
+	}
+	return &snapshot
+}
+
+func updateStatus(update func(s *control.ConnectStatus)) {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	update(status)
+}
@@ -5,40 +5,76 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/common"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/net/proxy"
+	"net"
 	"strings"
 	"time"
 )
 
 func ByTun2Socks() error {
-	podIP, podName, privateKeyPath, err := getOrCreateShadow()
+	podIP, _, _, err := getOrCreateShadow()
 	if err != nil {
 		return err
 	}
+	if opt.Get().Connect.Rootless {
+		// tun device requires root permission
+		opt.Get().Connect.DisableTunDevice = true
+	}
 	if opt.Get().Connect.ProxyAddr != common.Localhost && !opt.Get().Connect.DisableTunDevice {
 		return fmt.Errorf("parameter --proxyAddr is valid only when --disableTunDevice is used")
 	}
 
-	localSshPort := util.GetRandomTcpPort()
-	socksAddr := fmt.Sprintf("socks5://%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort)
-	if _, err = transmission.SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
-		return err
+	if opt.Get().Connect.DomainSuffix != "" && !opt.Get().Connect.DisableTunDevice {
+		// socks proxy is only used by tun device, avoid conflicting with connect process of other cluster
+		opt.Get().Connect.ProxyPort = util.GetRandomTcpPort()
 	}
-	if err = startSocks5Connection(podIP, privateKeyPath, localSshPort, true); err != nil {
+
+	if err = setupProxyAuth(); err != nil {
 		return err
 	}
 
-	if opt.Get().Connect.DisableTunDevice {
+	socksAddr := proxyUrl("socks5", fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort))
+	httpAddr := ""
+	if opt.Get().Connect.DisableTunDevice && opt.Get().Connect.HttpProxyPort > 0 {
+		// http proxy is for applications which do not support socks5, no need when tun device is available
+		httpAddr = fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.HttpProxyPort)
+	}
+	if opt.Get().Connect.ShadowReplicas > 1 {
+		if err = startBalancedSocks5Connection(httpAddr); err != nil {
+			return err
+		}
+	} else {
+		localSshPort := util.GetRandomTcpPort()
+		if err = forwardShadowPortToLocal(0, common.StandardSshPort, localSshPort); err != nil {
+			return err
+		}
+		if err = startSocks5Connection(localSshPort, httpAddr); err != nil {
+			return err
+		}
+	}
+
+	if opt.Get().Connect.Rootless {
+		if err = registerSession(""); err != nil {
+			return err
+		}
+		if httpAddr != "" {
+			log.Info().Msgf("Browsers could access cluster via pac file http://%s%s", httpAddr, sshchannel.PacPath)
+		}
+		return setupRootlessForward(fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort),
+			getShortNameNamespaces())
+	} else if opt.Get().Connect.DisableTunDevice {
 		if util.IsWindows() {
 			log.Warn().Msgf("DNS mode will auto switch to 'hosts' when tun device is disabled")
 			opt.Get().Connect.DnsMode = util.DnsModeHosts
 		}
-		showSetupSocksMessage(socksAddr)
+		showSetupSocksMessage(maskedProxyUrl("socks5", fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr,
+			opt.Get().Connect.ProxyPort)), httpAddr)
 	} else {
 		if err = tun.Ins().CheckContext(); err != nil {
 			return err
@@ -55,13 +91,23 @@ func ByTun2Socks() error {
 			log.Info().Msgf("Route to tun device completed")
 		}
 	}
-	return setupDns(podName, podIP)
+	return setupDns(podIP)
 }
 
 func setupTunRoute() error {
 	cidr, excludeCidr := cluster.Ins().ClusterCidr(opt.Get().Global.Namespace)
+	// excluded ranges inside cluster ranges should keep using default route
+	routes := tun.ExcludeRanges(cidr, excludeCidr)
+	// routes of tun devices overlapping each other would hijack traffic to another cluster
+	if r, routed, other := util.FindOverlappedRoute(routes, util.GetConnectSessions()); other != nil {
+		return fmt.Errorf("ip range %s overlaps with %s routed by connect process %d of context '%s', "+
+			"please use '--excludeIps %s' to skip it", r, routed, other.Pid, other.Context, r)
+	}
+	updateStatus(func(s *control.ConnectStatus) {
+		s.Routes = routes
+	})
 
-	err := tun.Ins().SetRoute(cidr, excludeCidr)
+	err := tun.Ins().SetRoute(routes, excludeCidr)
 	if err != nil {
 		if tun.IsAllRouteFailError(err) {
 			if strings.Contains(err.(tun.AllRouteFailError).OriginalError().Error(), "exit status") {
@@ -77,61 +123,149 @@ func setupTunRoute() error {
 			log.Warn().Err(err).Msgf("Some route rule is not setup properly")
 		}
 	}
-	if failedRoutes := tun.Ins().CheckRoute(cidr); len(failedRoutes) > 0 {
+	if failedRoutes := tun.Ins().CheckRoute(routes); len(failedRoutes) > 0 {
 		log.Warn().Msgf("Skipped route to %v", failedRoutes)
 	}
+	watchTunRoute(cidr, routes)
 	return nil
 }
 
-func startSocks5Connection(podIP, privateKey string, localSshPort int, isInitConnect bool) error {
-	var res = make(chan error)
-	var ticker *time.Ticker
+func startSocks5Connection(localSshPort int, httpAddress string) error {
 	sshAddress := fmt.Sprintf("%s:%d", common.LocalhostIp6, localSshPort)
-	socks5Address := fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort)
-	gone := false
-	go func() {
+	options := getProxyOptions(httpAddress)
+	s := transmission.NewSupervisor("Socks proxy", nil)
+	s.Connect = func(ready func()) error {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-time.After(1 * time.Second):
+				var ticker *time.Ticker
+				if dialer, err := proxy.SOCKS5("tcp", options.Socks5Address, proxyAuth, proxy.Direct); err != nil {
+					// proxy works without heartbeat, only its health is not checked
+					log.Warn().Err(err).Msgf("Failed to create socks proxy heart beat ticker")
+				} else {
+					ticker = setupSocks5HeartBeat(dialer.Dial, 0, s)
+				}
+				log.Info().Msgf("Socks proxy established")
+				ready()
+				<-done
+				if ticker != nil {
+					ticker.Stop()
+				}
+			case <-done:
+			}
+		}()
 		// will hang here if not error happen
-		err := sshchannel.Ins().StartSocks5Proxy(privateKey, sshAddress, socks5Address)
-		if !gone {
-			res <-err
+		err := sshchannel.Ins().StartSocks5Proxy(getShadow().privateKey, sshAddress, options)
+		log.Debug().Err(err).Msgf("Socks proxy interrupted")
+		return err
+	}
+	trackConnection(s)
+	if err := s.Start(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to setup socks proxy connection")
+		return err
+	}
+	return nil
+}
+
+// startBalancedSocks5Connection serve socks proxy via ssh tunnels to all shadow replicas, connections are distributed
+// to replicas in turn, and replica not responding to heartbeat is skipped until it recovers
+func startBalancedSocks5Connection(httpAddress string) error {
+	balancer := sshchannel.NewBalancer()
+	for i := 0; i < opt.Get().Connect.ShadowReplicas; i++ {
+		localSshPort := util.GetRandomTcpPort()
+		if err := forwardShadowPortToLocal(i, common.StandardSshPort, localSshPort); err != nil {
+			return err
+		}
+		if err := connectShadowReplica(balancer, i, localSshPort); err != nil {
+			return err
 		}
+	}
+
+	options := getProxyOptions(httpAddress)
+	s := transmission.NewSupervisor("Socks proxy", func(ready func()) error {
+		timer := time.AfterFunc(1*time.Second, func() {
+			log.Info().Msgf("Socks proxy established via %d shadow pods", opt.Get().Connect.ShadowReplicas)
+			ready()
+		})
+		defer timer.Stop()
+		// will hang here if not error happen
+		err := sshchannel.Ins().StartBalancedSocks5Proxy(balancer, options)
 		log.Debug().Err(err).Msgf("Socks proxy interrupted")
+		return err
+	})
+	trackConnection(s)
+	if err := s.Start(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to setup socks proxy")
+		return err
+	}
+	return nil
+}
+
+// connectShadowReplica keep ssh tunnel to shadow replica as a backend of balancer, the backend is only used while the
+// tunnel is healthy
+func connectShadowReplica(balancer *sshchannel.Balancer, index, localSshPort int) error {
+	sshAddress := fmt.Sprintf("%s:%d", common.LocalhostIp6, localSshPort)
+	name := getShadowReplica(index).resource
+	s := transmission.NewSupervisor(fmt.Sprintf("Socks tunnel via %s", name), nil)
+	s.Connect = func(ready func()) error {
+		var ticker *time.Ticker
+		// will hang here if not error happen
+		err := balancer.Connect(name, getShadowReplica(index).privateKey, sshAddress, func() {
+			ticker = setupSocks5HeartBeat(func(network, address string) (net.Conn, error) {
+				return balancer.DialVia(name, network, address)
+			}, index, s)
+			ready()
+		})
 		if ticker != nil {
 			ticker.Stop()
 		}
-		time.Sleep(10 * time.Second)
-		log.Debug().Msgf("Socks proxy reconnecting ...")
-		_ = startSocks5Connection(podIP, privateKey, localSshPort, false)
-	}()
-	select {
-	case err := <-res:
-		if isInitConnect {
-			log.Warn().Err(err).Msgf("Failed to setup socks proxy connection")
-		}
+		log.Debug().Err(err).Msgf("Socks tunnel via %s interrupted", name)
 		return err
-	case <-time.After(1 * time.Second):
-		ticker = setupSocks5HeartBeat(podIP, socks5Address)
-		log.Info().Msgf("Socks proxy established")
-		gone = true
-		return nil
 	}
+	trackConnection(s)
+	track := s.OnStateChange
+	s.OnStateChange = func(state string, err error) {
+		balancer.SetAvailable(name, state == control.HealthHealthy)
+		setReplicaHealthy(index, state != control.HealthReconnecting && state != control.HealthFailed)
+		track(state, err)
+	}
+	if err := s.Start(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to setup socks tunnel via %s", name)
+		return err
+	}
+	return nil
 }
 
-func setupSocks5HeartBeat(podIP, socks5Address string) *time.Ticker {
-	dialer, err := proxy.SOCKS5("tcp", socks5Address, nil, proxy.Direct)
-	if err != nil {
-		log.Warn().Err(err).Msgf("Failed to create socks proxy heart beat ticker")
+func getProxyOptions(httpAddress string) *sshchannel.ProxyOptions {
+	options := &sshchannel.ProxyOptions{
+		Socks5Address: fmt.Sprintf("%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort),
+		HttpAddress:   httpAddress,
+		Pac:           getPacContent,
+	}
+	if proxyAuth != nil {
+		options.Username = proxyAuth.User
+		options.Password = proxyAuth.Password
 	}
+	return options
+}
+
+// setupSocks5HeartBeat periodically access shadow replica via the dial function, to detect whether it still works
+func setupSocks5HeartBeat(dial func(network, address string) (net.Conn, error), index int,
+	s *transmission.Supervisor) *time.Ticker {
 	ticker := time.NewTicker(60 * time.Second)
 	go func() {
 	TickLoop:
 		for {
 			select {
 			case <-ticker.C:
-				if c, err2 := dialer.Dial("tcp", fmt.Sprintf("[%s]:%d", podIP, common.StandardSshPort)); err2 != nil {
+				if c, err2 := dial("tcp", fmt.Sprintf("[%s]:%d", getShadowReplica(index).ip, common.StandardSshPort)); err2 != nil {
 					log.Debug().Err(err2).Msgf("Socks proxy heartbeat interrupted")
+					s.Degrade(err2)
 				} else {
 					_ = c.Close()
+					s.Recover()
 					log.Debug().Msgf("Heartbeat socks proxy ticked at %s", util.FormattedTime())
 				}
 			case <-time.After(2 * 60 * time.Second):
@@ -143,14 +277,27 @@ func setupSocks5HeartBeat(podIP, socks5Address string) *time.Ticker {
 	return ticker
 }
 
-func showSetupSocksMessage(socksAddress string) {
+func showSetupSocksMessage(socksAddress, httpAddress string) {
+	proxyAddress := socksAddress
+	if httpAddress != "" {
+		// http proxy is supported by more tools than socks5
+		proxyAddress = maskedProxyUrl("http", httpAddress)
+	}
+	if proxyAuth != nil {
+		// password is only shown once when authentication enabled, avoid spreading it in logs
+		log.Info().Msgf("Password of proxy is masked as 'xxxxx' in following messages")
+	}
 	if util.IsWindows() {
 		if util.IsCmd() {
-			log.Info().Msgf(">> Please setup proxy config by: set http_proxy=%s <<", socksAddress)
+			log.Info().Msgf(">> Please setup proxy config by: set http_proxy=%s <<", proxyAddress)
 		} else {
-			log.Info().Msgf(">> Please setup proxy config by: $env:http_proxy=\"%s\" <<", socksAddress)
+			log.Info().Msgf(">> Please setup proxy config by: $env:http_proxy=\"%s\" <<", proxyAddress)
 		}
 	} else {
-		log.Info().Msgf(">> Please setup proxy config by: export http_proxy=%s <<", socksAddress)
+		log.Info().Msgf(">> Please setup proxy config by: export http_proxy=%s <<", proxyAddress)
+	}
+	if httpAddress != "" {
+		log.Info().Msgf(">> Or use socks5 proxy %s, or pac file http://%s%s for browser <<",
+			socksAddress, httpAddress, sshchannel.PacPath)
 	}
 }
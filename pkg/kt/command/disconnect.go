@@ -0,0 +1,81 @@
+package command
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"time"
+)
+
+// disconnectTimeout max seconds to wait for connect process cleanup and exit
+const disconnectTimeout = 60
+
+// NewDisconnectCommand return new disconnect command
+func NewDisconnectCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "disconnect",
+		Short: "Stop running connect processes",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			general.SetupLogger()
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Disconnect()
+		},
+		Example: "ktctl disconnect [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(false))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Disconnect, opt.DisconnectFlags())
+	return cmd
+}
+
+// Disconnect ask running connect processes to exit, and wait for them to finish cleanup
+func Disconnect() error {
+	pids := util.GetAllDaemonRunning(util.ComponentConnect)
+	if opt.Get().Disconnect.Pid > 0 {
+		if !util.Contains(pids, opt.Get().Disconnect.Pid) {
+			return fmt.Errorf("connect process %d is not running", opt.Get().Disconnect.Pid)
+		}
+		pids = []int{opt.Get().Disconnect.Pid}
+	}
+	if len(pids) == 0 {
+		log.Info().Msgf("No connect process is running")
+		return nil
+	}
+	for _, pid := range pids {
+		if err := control.Disconnect(pid); err != nil {
+			// connect process without control api still exits when its pid file removed
+			log.Debug().Err(err).Msgf("Failed to disconnect via control api, removing pid file instead")
+			pidFile := fmt.Sprintf("%s/%s-%d.pid", util.KtPidDir, util.ComponentConnect, pid)
+			if err = os.Remove(pidFile); err != nil {
+				return fmt.Errorf("failed to stop connect process %d: %s", pid, err)
+			}
+		}
+		log.Info().Msgf("Disconnecting connect process %d ...", pid)
+	}
+	for i := 0; i < disconnectTimeout; i++ {
+		running := false
+		for _, pid := range pids {
+			if util.IsProcessExist(pid) {
+				running = true
+				break
+			}
+		}
+		if !running {
+			log.Info().Msgf("Disconnected")
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return fmt.Errorf("connect process still running after %d seconds", disconnectTimeout)
+}
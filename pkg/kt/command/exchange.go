@@ -5,6 +5,7 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/kt/command/exchange"
 	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -37,25 +38,82 @@ func NewExchangeCommand() *cobra.Command {
 
 //Exchange exchange kubernetes workload
 func Exchange(resourceName string) error {
+	if err := checkExchangeOptions(); err != nil {
+		return err
+	}
+
 	ch, err := general.SetupProcess(util.ComponentExchange)
 	if err != nil {
 		return err
 	}
 
+	if err = exchangeResource(resourceName); err != nil {
+		return err
+	}
+
+	// watch background process, clean the workspace and exit if background process occur exception
+	s := <-ch
+	log.Info().Msgf("Terminal Signal is %s", s)
+	return nil
+}
+
+func checkExchangeOptions() error {
+	if opt.Get().Exchange.Partial && opt.Get().Exchange.Mode != util.ExchangeModeSelector {
+		return fmt.Errorf("partial exchange is only available in %s mode", util.ExchangeModeSelector)
+	}
+	if opt.Get().Exchange.FailoverThreshold > 0 &&
+		(opt.Get().Exchange.Mode != util.ExchangeModeSelector || opt.Get().Exchange.Partial) {
+		return fmt.Errorf("failover is only available in %s mode without partial exchange", util.ExchangeModeSelector)
+	}
+	if err := transmission.CheckClientIpHeader(opt.Get().Exchange.ClientIpHeader); err != nil {
+		return err
+	}
+	if opt.Get().Exchange.SyncToken && opt.Get().Exchange.SyncEnv == "" {
+		return fmt.Errorf("sync token requires sync env directory to be specified")
+	}
+	if len(util.FindUdpExposePorts(opt.Get().Exchange.Expose)) > 0 && opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		return fmt.Errorf("udp port is not supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if len(util.FindUdpExposePorts(opt.Get().Exchange.Expose)) > 0 && opt.Get().Exchange.Partial {
+		// router pod split traffic at tcp level only
+		return fmt.Errorf("udp port is not supported in partial exchange")
+	}
+	return nil
+}
+
+func exchangeResource(resourceName string) (err error) {
 	if opt.Get().Exchange.SkipPortChecking {
 		if port := util.FindBrokenLocalPort(opt.Get().Exchange.Expose); port != "" {
 			return fmt.Errorf("no application is running on port %s", port)
 		}
 	}
 
+	// sync before redirecting traffic, so that local application could be started with them
+	if opt.Get().Exchange.SyncEnv != "" {
+		workload, err2 := general.GetWorkloadByResourceName(resourceName, opt.Get().Global.Namespace)
+		if err2 != nil {
+			return err2
+		}
+		if _, err2 = general.SyncWorkloadEnv(workload, "", opt.Get().Exchange.SyncEnv, opt.Get().Exchange.SyncRedact); err2 != nil {
+			return err2
+		}
+		if opt.Get().Exchange.SyncToken {
+			if err2 = general.ProjectServiceAccountToken(workload, "", opt.Get().Exchange.SyncEnv); err2 != nil {
+				return err2
+			}
+		}
+	}
+
 	log.Info().Msgf("Using %s mode", opt.Get().Exchange.Mode)
 	if opt.Get().Exchange.Mode == util.ExchangeModeScale {
 		err = exchange.ByScale(resourceName)
 	} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector {
 		err = exchange.BySelector(resourceName)
+	} else if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		err = exchange.ByEphemeral(resourceName)
 	} else {
-		err = fmt.Errorf("invalid exchange method '%s', supportted are %s, %s", opt.Get().Exchange.Mode,
-			util.ExchangeModeSelector, util.ExchangeModeScale)
+		err = fmt.Errorf("invalid exchange method '%s', supportted are %s, %s, %s", opt.Get().Exchange.Mode,
+			util.ExchangeModeSelector, util.ExchangeModeScale, util.ExchangeModeEphemeral)
 	}
 	if err != nil {
 		return err
@@ -64,10 +122,6 @@ func Exchange(resourceName string) error {
 	log.Info().Msg("---------------------------------------------------------------")
 	log.Info().Msgf(" Now all request to %s '%s' will be redirected to local", resourceType, realName)
 	log.Info().Msg("---------------------------------------------------------------")
-
-	// watch background process, clean the workspace and exit if background process occur exception
-	s := <-ch
-	log.Info().Msgf("Terminal Signal is %s", s)
 	return nil
 }
 
@@ -0,0 +1,203 @@
+package exchange
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func ByEphemeral(resourceName string) error {
+	// Get service to exchange
+	svc, err := general.GetServiceByResourceName(resourceName, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	if port := util.FindInvalidRemotePort(opt.Get().Exchange.Expose, general.GetTargetPorts(svc)); port != "" {
+		return fmt.Errorf("target port %s not exists in service %s", port, svc.Name)
+	}
+	if svc.Annotations != nil && svc.Annotations[util.KtSelector] != "" {
+		return fmt.Errorf("service '%s' is exchanged or meshed by another user, cannot apply exchange", svc.Name)
+	}
+
+	pods, err := getPodsToExchange(svc)
+	if err != nil {
+		return err
+	}
+
+	// All pods share the same ssh key and sshd port, the port must not be used by containers of the pods, since ephemeral
+	// container shares network namespace with them
+	containerName := util.KtExchangeContainer + "-" + strings.ToLower(util.RandomString(5))
+	generator, err := util.Generate(general.EphemeralKeyPath())
+	if err != nil {
+		return err
+	}
+	usedPorts := getContainerPorts(pods)
+	sshPort := spareEphemeralPort(usedPorts)
+	envs := map[string]string{
+		"authorizedKey": base64.StdEncoding.EncodeToString(generator.PublicKey),
+		"sshPort":       strconv.Itoa(sshPort),
+	}
+	mirror := transmission.MirrorConfig{
+		Target:      opt.Get().Exchange.MirrorTarget,
+		SampleRate:  opt.Get().Exchange.MirrorSampleRate,
+		RedactRules: opt.Get().Exchange.MirrorRedactRules,
+		LogPath:     opt.Get().Exchange.MirrorLogPath,
+	}
+
+	opt.Store.Origin = svc.Name
+	for _, pod := range pods {
+		log.Info().Msgf("Injecting ephemeral container %s into pod %s", containerName, pod.Name)
+		if err = cluster.Ins().AddEphemeralContainer(containerName, pod.Name, pod.Namespace, envs); err != nil {
+			return rollbackEphemeralExchange(fmt.Errorf("failed to inject ephemeral container into pod %s: %s",
+				pod.Name, err), containerName, "")
+		}
+		if err = exchangePodByEphemeral(svc, pod, containerName, sshPort, usedPorts, generator.PrivateKeyPath,
+			mirror); err != nil {
+			return rollbackEphemeralExchange(err, containerName, pod.Name)
+		}
+	}
+	log.Warn().Msgf("Pods of service %s created from now on will NOT be redirected, please re-run exchange "+
+		"if the pods are scaled or restarted", svc.Name)
+	return nil
+}
+
+func exchangePodByEphemeral(svc *coreV1.Service, pod coreV1.Pod, containerName string, sshPort int,
+	usedPorts map[int]bool, privateKeyPath string, mirror transmission.MirrorConfig) error {
+	readyPod, err := cluster.Ins().WaitEphemeralContainerReady(containerName, pod.Name, pod.Namespace,
+		opt.Get().Global.PodCreationTimeout)
+	if err != nil {
+		return err
+	}
+	// Check before any port is redirected, otherwise the pod would stop serving
+	if err = general.CheckEphemeralIptables(containerName, pod.Name, pod.Namespace); err != nil {
+		return err
+	}
+
+	// Tunnel listens on a spare port inside the pod, and application ports are redirected to it
+	exposePorts, redirects, err := toEphemeralExposePorts(opt.Get().Exchange.Expose, usedPorts)
+	if err != nil {
+		return err
+	}
+	// ephemeral container lives in target pod, which won't be recreated by ktctl
+	if _, err = transmission.ForwardPodToLocalViaSshPort(exposePorts, func() string {
+		return pod.Name
+	}, sshPort, privateKeyPath, toEphemeralClientIp(svc, redirects), mirror); err != nil {
+		return err
+	}
+	return general.SetupEphemeralRedirect(readyPod, containerName, redirects)
+}
+
+// rollbackEphemeralExchange recover pods already exchanged, and stop ephemeral container of the pod failed halfway
+func rollbackEphemeralExchange(err error, containerName, failedPodName string) error {
+	log.Warn().Msgf("Exchange failed, rolling back")
+	namespace := opt.Get().Global.Namespace
+	exchangedPods := strings.Split(opt.Store.Ephemeral, ",")
+	if failedPodName != "" && !util.Contains(exchangedPods, failedPodName) {
+		// redirect config not recorded yet, nothing but the container itself to clean up
+		general.StopEphemeralContainer(containerName, failedPodName, namespace)
+	}
+	for _, podName := range exchangedPods {
+		if podName != "" {
+			general.RecoverEphemeralExchangedPod(podName, namespace)
+		}
+	}
+	opt.Store.Ephemeral = ""
+	if err2 := os.Remove(general.EphemeralKeyPath()); err2 != nil && !os.IsNotExist(err2) {
+		log.Debug().Msgf("Remove key file %s failed", general.EphemeralKeyPath())
+	}
+	return err
+}
+
+func getPodsToExchange(svc *coreV1.Service) ([]coreV1.Pod, error) {
+	podList, err := cluster.Ins().GetPodsByLabel(svc.Spec.Selector, svc.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]coreV1.Pod, 0)
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != coreV1.PodRunning || pod.DeletionTimestamp != nil || pod.Labels[util.KtRole] != "" {
+			continue
+		}
+		if util.String2Map(pod.Annotations[util.KtConfig])["ephemeral"] != "" {
+			return nil, fmt.Errorf("pod %s is already exchanged by another user%s", pod.Name,
+				formatUser(pod.Annotations[util.KtUser]))
+		}
+		pods = append(pods, pod)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no running pod found for service %s", svc.Name)
+	}
+	return pods, nil
+}
+
+// getContainerPorts collect ports declared by containers of pods
+func getContainerPorts(pods []coreV1.Pod) map[int]bool {
+	ports := map[int]bool{}
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				ports[int(p.ContainerPort)] = true
+			}
+		}
+	}
+	return ports
+}
+
+// spareEphemeralPort pick a random port not in used ports, and mark it as used
+func spareEphemeralPort(usedPorts map[int]bool) int {
+	port := util.RandomPort()
+	for usedPorts[port] {
+		port = util.RandomPort()
+	}
+	usedPorts[port] = true
+	return port
+}
+
+func toEphemeralExposePorts(expose string, usedPorts map[int]bool) (string, map[int]int, error) {
+	redirects := map[int]int{}
+	exposePorts := make([]string, 0)
+	for _, exposePort := range strings.Split(expose, ",") {
+		host, localPort, remotePort, err := util.ParseExposeTarget(exposePort)
+		if err != nil {
+			return "", nil, err
+		}
+		tunnelPort := spareEphemeralPort(usedPorts)
+		redirects[remotePort] = tunnelPort
+		if host != "" {
+			exposePorts = append(exposePorts, fmt.Sprintf("%s:%d:%d", host, localPort, tunnelPort))
+		} else {
+			exposePorts = append(exposePorts, fmt.Sprintf("%d:%d", localPort, tunnelPort))
+		}
+	}
+	return strings.Join(exposePorts, ","), redirects, nil
+}
+
+// toEphemeralClientIp http ports are redirected to spare ports where tunnel listens
+func toEphemeralClientIp(svc *coreV1.Service, redirects map[int]int) transmission.ClientIpConfig {
+	clientIp := transmission.ClientIpConfig{
+		Header:    opt.Get().Exchange.ClientIpHeader,
+		HttpPorts: []int{},
+	}
+	for _, p := range general.GetHttpTargetPorts(svc) {
+		if tunnelPort, exists := redirects[p]; exists {
+			clientIp.HttpPorts = append(clientIp.HttpPorts, tunnelPort)
+		}
+	}
+	return clientIp
+}
+
+func formatUser(user string) string {
+	if user == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", user)
+}
@@ -8,19 +8,36 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
-	appV1 "k8s.io/api/apps/v1"
 	"strings"
 )
 
 func ByScale(resourceName string) error {
-	app, err := general.GetDeploymentByResourceName(resourceName, opt.Get().Global.Namespace)
+	app, err := general.GetWorkloadByResourceName(resourceName, opt.Get().Global.Namespace)
 	if err != nil {
 		return err
 	}
 
 	// record context inorder to remove after command exit
 	opt.Store.Origin = app.Name
-	opt.Store.Replicas = *app.Spec.Replicas
+	opt.Store.OriginKind = app.Kind
+	opt.Store.Replicas = app.Replicas
+
+	if app.Kind == cluster.KindDaemonSet && !opt.Get().Global.UseShadowDeployment {
+		// daemonset controller adopts orphan pods match its selector, and would delete them from unscheduled nodes
+		log.Info().Msgf("Using shadow deployment to avoid shadow pod being adopted by daemonset %s", app.Name)
+		opt.Get().Global.UseShadowDeployment = true
+	}
+	if controller := general.GetGitOpsController(app); controller != "" {
+		log.Warn().Msgf("%s %s is managed by %s, auto sync may scale it back during exchange, "+
+			"please consider suspending the sync", app.Kind, app.Name, controller)
+	}
+	hpa := general.GetAutoscalerOfWorkload(app)
+	encodedHpa := ""
+	if hpa != nil {
+		if encodedHpa, err = general.EncodeAutoscaler(hpa); err != nil {
+			return err
+		}
+	}
 
 	shadowPodName := app.Name + util.ExchangePodInfix + strings.ToLower(util.RandomString(5))
 
@@ -31,32 +48,48 @@ func ByScale(resourceName string) error {
 		RedactRules: opt.Get().Exchange.MirrorRedactRules,
 		LogPath:     opt.Get().Exchange.MirrorLogPath,
 	}
+	clientIp := transmission.ClientIpConfig{
+		Header: opt.Get().Exchange.ClientIpHeader,
+	}
+	if clientIp.Header != "" {
+		clientIp.HttpPorts = general.GetHttpTargetPortsOfWorkload(app, opt.Get().Global.Namespace)
+	}
 	if err = general.CreateShadowAndInbound(shadowPodName, opt.Get().Exchange.Expose,
-		getExchangeLabels(app), getExchangeAnnotation(), map[int]string{}, mirror); err != nil {
+		getExchangeLabels(app), getExchangeAnnotation(encodedHpa), map[int]string{}, clientIp, mirror); err != nil {
 		return err
 	}
 
-	down := int32(0)
-	if err = cluster.Ins().ScaleTo(app.Name, opt.Get().Global.Namespace, &down); err != nil {
+	// autoscaler would scale the workload back, set it aside until exchange finished
+	if hpa != nil {
+		if err = general.SetAsideAutoscaler(hpa); err != nil {
+			return err
+		}
+		opt.Store.Autoscaler = encodedHpa
+	}
+
+	if err = cluster.Ins().ScaleWorkload(app.Kind, app.Name, opt.Get().Global.Namespace, 0); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func getExchangeAnnotation() map[string]string {
+func getExchangeAnnotation(encodedHpa string) map[string]string {
+	config := fmt.Sprintf("app=%s,kind=%s,replicas=%d", opt.Store.Origin, opt.Store.OriginKind, opt.Store.Replicas)
+	if encodedHpa != "" {
+		config = fmt.Sprintf("%s,hpa=%s", config, encodedHpa)
+	}
 	return map[string]string{
-		util.KtConfig: fmt.Sprintf("app=%s,replicas=%d",
-			opt.Store.Origin, opt.Store.Replicas),
+		util.KtConfig: config,
 	}
 }
 
-func getExchangeLabels(origin *appV1.Deployment) map[string]string {
+func getExchangeLabels(origin *cluster.Workload) map[string]string {
 	labels := map[string]string{
 		util.KtRole: util.RoleExchangeShadow,
 	}
 	if origin != nil {
-		for k, v := range origin.Spec.Selector.MatchLabels {
+		for k, v := range origin.Selector {
 			labels[k] = v
 		}
 	}
@@ -54,16 +54,37 @@ func BySelector(resourceName string) error {
 		RedactRules: opt.Get().Exchange.MirrorRedactRules,
 		LogPath:     opt.Get().Exchange.MirrorLogPath,
 	}
+	clientIp := transmission.ClientIpConfig{
+		Header:    opt.Get().Exchange.ClientIpHeader,
+		HttpPorts: general.GetHttpTargetPorts(svc),
+	}
 	if err = general.CreateShadowAndInbound(shadowName, opt.Get().Exchange.Expose,
-		shadowLabels, annotation, general.GetTargetPorts(svc), mirror); err != nil {
+		shadowLabels, annotation, general.GetTargetPorts(svc), clientIp, mirror); err != nil {
 		return err
 	}
 
 	// Let target service select shadow pod
 	opt.Store.Origin = svc.Name
+	if opt.Get().Exchange.Partial {
+		return general.SplitServiceTraffic(svc, shadowName, shadowLabels, getExposedRemotePorts(opt.Get().Exchange.Expose))
+	}
 	if err = general.UpdateServiceSelector(svc.Name, opt.Get().Global.Namespace, shadowLabels); err != nil {
 		return err
 	}
+	if opt.Get().Exchange.FailoverThreshold > 0 {
+		general.StartExchangeWatchdog(svc.Name, opt.Get().Global.Namespace, shadowLabels,
+			opt.Get().Exchange.Expose, opt.Get().Exchange.FailoverThreshold)
+	}
 
 	return nil
 }
+
+func getExposedRemotePorts(expose string) []int {
+	ports := make([]int, 0)
+	for _, exposePort := range strings.Split(expose, ",") {
+		if _, remotePort, err := util.ParsePortMapping(exposePort); err == nil {
+			ports = append(ports, remotePort)
+		}
+	}
+	return ports
+}
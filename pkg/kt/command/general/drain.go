@@ -0,0 +1,168 @@
+package general
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tcpEstablished state code of established connection in /proc/net/tcp
+const tcpEstablished = "01"
+
+// WaitPodsReadyToServe wait until any pod of selector is ready, so that service could be switched to it without interruption
+func WaitPodsReadyToServe(selector map[string]string, namespace string, timeoutSec int) error {
+	const interval = 3
+	for i := 0; i <= timeoutSec/interval; i++ {
+		pods, err := cluster.Ins().GetPodsByLabel(selector, namespace)
+		if err != nil {
+			return err
+		}
+		if readyPods := filterReadyPods(pods.Items); len(readyPods) > 0 {
+			log.Info().Msgf("Pod %s is ready to serve", readyPods[0].Name)
+			return nil
+		}
+		log.Info().Msgf("Waiting for pods with label %v ready to serve ...", selector)
+		time.Sleep(interval * time.Second)
+	}
+	return fmt.Errorf("no pod with label %v is ready to serve", selector)
+}
+
+// DrainPreviousEndpoints wait until endpoints of service switched to pods of selector, then wait for in-flight
+// connections to previous pods finished if they are going to be removed, bounded by the grace period
+func DrainPreviousEndpoints(svcName, namespace string, selector, previousSelector map[string]string, gracePeriod int) {
+	if gracePeriod <= 0 {
+		return
+	}
+	deadline := time.Now().Add(time.Duration(gracePeriod) * time.Second)
+	for !isEndpointsSwitched(svcName, namespace, selector) {
+		if time.Now().After(deadline) {
+			log.Warn().Msgf("Endpoints of service %s not switched in %d seconds", svcName, gracePeriod)
+			return
+		}
+		log.Info().Msgf("Waiting for endpoints of service %s to switch ...", svcName)
+		time.Sleep(1 * time.Second)
+	}
+	log.Info().Msgf("Endpoints of service %s switched", svcName)
+	if previousSelector == nil {
+		// previous pods keep running, in-flight connections are not affected
+		return
+	}
+	const interval = 3
+	for {
+		count, err := countPodsConnections(previousSelector, namespace)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Unable to count connections to previous endpoints of service %s", svcName)
+			return
+		} else if count == 0 {
+			log.Info().Msgf("Previous endpoints of service %s drained", svcName)
+			return
+		} else if time.Now().After(deadline) {
+			log.Warn().Msgf("Still %d connections to previous endpoints of service %s after %d seconds",
+				count, svcName, gracePeriod)
+			return
+		}
+		log.Info().Msgf("Draining %d connections to previous endpoints of service %s ...", count, svcName)
+		time.Sleep(interval * time.Second)
+	}
+}
+
+// getDrainPeriod get grace period of draining for current command
+func getDrainPeriod() int {
+	if runningAs(util.ComponentExchange) {
+		return opt.Get().Exchange.DrainPeriod
+	} else if runningAs(util.ComponentMesh) {
+		return opt.Get().Mesh.DrainPeriod
+	}
+	return 0
+}
+
+func isEndpointsSwitched(svcName, namespace string, selector map[string]string) bool {
+	endpoints, err := cluster.Ins().GetEndpoints(svcName, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get endpoints of service %s", svcName)
+		return false
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(selector, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get pods with label %v", selector)
+		return false
+	}
+	podNames := make([]string, 0)
+	for _, pod := range pods.Items {
+		podNames = append(podNames, pod.Name)
+	}
+	return isEndpointsOfPods(endpoints, podNames)
+}
+
+// countPodsConnections count established tcp connections of pods, except ssh connections used by ktctl itself
+func countPodsConnections(selector map[string]string, namespace string) (int, error) {
+	pods, err := cluster.Ins().GetPodsByLabel(selector, namespace)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, pod := range pods.Items {
+		stdout, _, err2 := cluster.Ins().ExecInPod(util.DefaultContainer, pod.Name, namespace,
+			"sh", "-c", "cat /proc/net/tcp /proc/net/tcp6 2>/dev/null")
+		if err2 != nil {
+			return 0, err2
+		}
+		count += countEstablishedConnections(stdout, common.StandardSshPort)
+	}
+	return count, nil
+}
+
+// countEstablishedConnections count established connections in content of /proc/net/tcp, excluding specified local ports
+func countEstablishedConnections(procNetTcp string, excludedPorts ...int) int {
+	count := 0
+	for _, line := range strings.Split(procNetTcp, "\n") {
+		// e.g. "0: 0100007F:1F90 0100007F:C350 01 ..."
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		parts := strings.Split(fields[1], ":")
+		port, err := strconv.ParseInt(parts[len(parts)-1], 16, 32)
+		if err != nil || util.Contains(excludedPorts, int(port)) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func isEndpointsOfPods(endpoints *coreV1.Endpoints, podNames []string) bool {
+	found := false
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.TargetRef == nil || !util.Contains(podNames, address.TargetRef.Name) {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+func filterReadyPods(pods []coreV1.Pod) []coreV1.Pod {
+	readyPods := make([]coreV1.Pod, 0)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == coreV1.PodReady && condition.Status == coreV1.ConditionTrue {
+				readyPods = append(readyPods, pod)
+				break
+			}
+		}
+	}
+	return readyPods
+}
@@ -0,0 +1,57 @@
+package general
+
+import (
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_isEndpointsOfPods(t *testing.T) {
+	toAddress := func(podName string) coreV1.EndpointAddress {
+		return coreV1.EndpointAddress{TargetRef: &coreV1.ObjectReference{Kind: "Pod", Name: podName}}
+	}
+	endpoints := &coreV1.Endpoints{
+		Subsets: []coreV1.EndpointSubset{
+			{Addresses: []coreV1.EndpointAddress{toAddress("tomcat-kt-exchange-abcde")}},
+		},
+	}
+	require.True(t, isEndpointsOfPods(endpoints, []string{"tomcat-kt-exchange-abcde"}))
+	require.False(t, isEndpointsOfPods(endpoints, []string{"tomcat-1"}))
+
+	endpoints.Subsets = append(endpoints.Subsets, coreV1.EndpointSubset{
+		Addresses: []coreV1.EndpointAddress{toAddress("tomcat-1")},
+	})
+	require.False(t, isEndpointsOfPods(endpoints, []string{"tomcat-kt-exchange-abcde"}))
+	require.False(t, isEndpointsOfPods(&coreV1.Endpoints{}, []string{"tomcat-1"}))
+}
+
+func Test_filterReadyPods(t *testing.T) {
+	newPod := func(name, ip string, ready coreV1.ConditionStatus) coreV1.Pod {
+		return coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: coreV1.PodStatus{
+				PodIP:      ip,
+				Conditions: []coreV1.PodCondition{{Type: coreV1.PodReady, Status: ready}},
+			},
+		}
+	}
+	pods := filterReadyPods([]coreV1.Pod{
+		newPod("tomcat-1", "10.0.0.1", coreV1.ConditionTrue),
+		newPod("tomcat-3", "10.0.0.3", coreV1.ConditionFalse),
+		newPod("tomcat-4", "", coreV1.ConditionTrue),
+	})
+	require.Equal(t, 1, len(pods))
+	require.Equal(t, "tomcat-1", pods[0].Name)
+}
+
+func Test_countEstablishedConnections(t *testing.T) {
+	content := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n" +
+		"   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001\n" +
+		"   1: 0A000005:1F90 0A000009:CB20 01 00000000:00000000 00:00000000 00000000     0        0 1002\n" +
+		"   2: 0100007F:0016 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003\n" +
+		"   0: 00000000000000000000000000000000:1F90 0000000000000000FFFF00000A000007:CB21 01 00000000:00000000\n"
+	require.Equal(t, 2, countEstablishedConnections(content, 22))
+	require.Equal(t, 3, countEstablishedConnections(content))
+	require.Equal(t, 0, countEstablishedConnections(""))
+}
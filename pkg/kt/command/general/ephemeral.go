@@ -0,0 +1,146 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EphemeralKeyPath private key used for connecting ephemeral shadow containers of current process
+func EphemeralKeyPath() string {
+	return util.PrivateKeyPath(fmt.Sprintf("%s-%d", util.KtExchangeContainer, os.Getpid()))
+}
+
+// CheckEphemeralIptables make sure iptables is available in ephemeral shadow container, which is required for redirecting
+func CheckEphemeralIptables(containerName, podName, namespace string) error {
+	if _, stderr, err := cluster.Ins().ExecInPod(containerName, podName, namespace, "iptables", "-V"); err != nil {
+		log.Debug().Msgf("Stderr: %s", stderr)
+		return fmt.Errorf("iptables is not available in ephemeral container %s of pod %s, please make sure the "+
+			"shadow image is built on kt-shadow-base v0.5.0 or above: %s", containerName, podName, err)
+	}
+	return nil
+}
+
+// SetupEphemeralRedirect redirect traffic of pod ports to the ports listened by ephemeral shadow container
+func SetupEphemeralRedirect(pod *coreV1.Pod, containerName string, redirects map[int]int) error {
+	// record redirect config before applying it, so that it can be recovered even if process exit unexpectedly
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[util.KtConfig] = fmt.Sprintf("ephemeral=%s,ip=%s,redirect=%s",
+		containerName, pod.Status.PodIP, toRedirectString(redirects))
+	pod.Annotations[util.KtLastHeartBeat] = util.GetTimestamp()
+	pod.Annotations[util.KtUser] = util.GetLocalUserName()
+	if _, err := cluster.Ins().UpdatePod(pod); err != nil {
+		return err
+	}
+	opt.Store.Ephemeral = util.Append(opt.Store.Ephemeral, pod.Name)
+	cluster.SetupHeartBeat(pod.Name, pod.Namespace, cluster.Ins().UpdatePodHeartBeat)
+
+	for _, cmd := range ephemeralRedirectCommands("-A", pod.Status.PodIP, redirects) {
+		if _, stderr, err := cluster.Ins().ExecInPod(containerName, pod.Name, pod.Namespace, cmd...); err != nil {
+			log.Debug().Msgf("Stderr: %s", stderr)
+			return fmt.Errorf("failed to redirect ports of pod %s: %s", pod.Name, err)
+		}
+	}
+	log.Info().Msgf("Ports of pod %s redirected to ephemeral container %s", pod.Name, containerName)
+	return nil
+}
+
+// RecoverEphemeralExchangedPod remove port redirect and stop ephemeral shadow container of specified pod
+func RecoverEphemeralExchangedPod(podName, namespace string) bool {
+	pod, err := cluster.Ins().GetPod(podName, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to fetch exchanged pod %s", podName)
+		return false
+	}
+	config := util.String2Map(pod.Annotations[util.KtConfig])
+	containerName := config["ephemeral"]
+	if containerName == "" {
+		return false
+	}
+	for _, cmd := range ephemeralRedirectCommands("-D", config["ip"], parseRedirectString(config["redirect"])) {
+		if _, stderr, err2 := cluster.Ins().ExecInPod(containerName, pod.Name, pod.Namespace, cmd...); err2 != nil {
+			log.Debug().Msgf("Stderr: %s", stderr)
+			log.Warn().Err(err2).Msgf("Failed to remove port redirect of pod %s, please restart the pod manually", pod.Name)
+		}
+	}
+	// ephemeral container cannot be removed, terminate it by stopping its main process
+	StopEphemeralContainer(containerName, pod.Name, pod.Namespace)
+	delete(pod.Annotations, util.KtConfig)
+	delete(pod.Annotations, util.KtLastHeartBeat)
+	delete(pod.Annotations, util.KtUser)
+	if _, err = cluster.Ins().UpdatePod(pod); err != nil {
+		log.Warn().Err(err).Msgf("Failed to remove kt annotations from pod %s", pod.Name)
+	}
+	log.Info().Msgf("Pod %s recovered", pod.Name)
+	return true
+}
+
+// StopEphemeralContainer kill main process recorded by ephemeral container, other processes may share pid namespace
+func StopEphemeralContainer(containerName, podName, namespace string) {
+	stdout, _, err := cluster.Ins().ExecInPod(containerName, podName, namespace, "cat", util.ShadowPidFile)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to read pid of ephemeral container %s in pod %s", containerName, podName)
+		return
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil || pid <= 0 {
+		log.Warn().Msgf("Invalid pid '%s' recorded by ephemeral container %s in pod %s", stdout, containerName, podName)
+		return
+	}
+	if _, stderr, err2 := cluster.Ins().ExecInPod(containerName, podName, namespace, "kill", strconv.Itoa(pid)); err2 != nil {
+		log.Debug().Msgf("Stderr: %s", stderr)
+		log.Warn().Err(err2).Msgf("Failed to stop ephemeral container %s in pod %s", containerName, podName)
+	}
+}
+
+func ephemeralRedirectCommands(action, podIP string, redirects map[int]int) [][]string {
+	commands := make([][]string, 0)
+	for _, port := range sortedKeys(redirects) {
+		src := strconv.Itoa(port)
+		dst := strconv.Itoa(redirects[port])
+		// PREROUTING for traffic from outside, OUTPUT for traffic forwarded by sidecar inside the pod
+		commands = append(commands,
+			[]string{"iptables", "-t", "nat", action, "PREROUTING", "-p", "tcp", "--dport", src,
+				"-j", "REDIRECT", "--to-ports", dst},
+			[]string{"iptables", "-t", "nat", action, "OUTPUT", "-p", "tcp", "-d", podIP, "--dport", src,
+				"-j", "REDIRECT", "--to-ports", dst})
+	}
+	return commands
+}
+
+func toRedirectString(redirects map[int]int) string {
+	pairs := make([]string, 0)
+	for _, port := range sortedKeys(redirects) {
+		pairs = append(pairs, fmt.Sprintf("%d:%d", port, redirects[port]))
+	}
+	// use ';' since ',' is used to separate config items
+	return strings.Join(pairs, ";")
+}
+
+func parseRedirectString(redirectStr string) map[int]int {
+	redirects := map[int]int{}
+	for _, pair := range strings.Split(redirectStr, ";") {
+		if src, dst, err := util.ParsePortMapping(pair); err == nil {
+			redirects[src] = dst
+		}
+	}
+	return redirects
+}
+
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
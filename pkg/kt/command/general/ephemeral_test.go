@@ -0,0 +1,22 @@
+package general
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_redirectString(t *testing.T) {
+	redirects := map[int]int{8080: 23456, 80: 34567}
+	require.Equal(t, "80:34567;8080:23456", toRedirectString(redirects))
+	require.Equal(t, redirects, parseRedirectString(toRedirectString(redirects)))
+	require.Equal(t, map[int]int{}, parseRedirectString(""))
+}
+
+func Test_ephemeralRedirectCommands(t *testing.T) {
+	commands := ephemeralRedirectCommands("-A", "10.0.0.5", map[int]int{80: 34567})
+	require.Equal(t, 2, len(commands))
+	require.Equal(t, []string{"iptables", "-t", "nat", "-A", "PREROUTING", "-p", "tcp", "--dport", "80",
+		"-j", "REDIRECT", "--to-ports", "34567"}, commands[0])
+	require.Equal(t, []string{"iptables", "-t", "nat", "-A", "OUTPUT", "-p", "tcp", "-d", "10.0.0.5", "--dport", "80",
+		"-j", "REDIRECT", "--to-ports", "34567"}, commands[1])
+}
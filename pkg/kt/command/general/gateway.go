@@ -0,0 +1,266 @@
+package general
+
+import (
+	"encoding/json"
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"strings"
+)
+
+// GetHTTPRoutesOfService get all http routes with backend pointing to specified service
+func GetHTTPRoutesOfService(svcName, namespace string) ([]*unstructured.Unstructured, error) {
+	routes, err := cluster.Ins().GetHTTPRoutes(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch HTTPRoute, please make sure gateway api is installed: %s", err)
+	}
+	matchedRoutes := make([]*unstructured.Unstructured, 0)
+	for i := range routes {
+		if isHTTPRouteOfService(&routes[i], svcName) {
+			matchedRoutes = append(matchedRoutes, &routes[i])
+		}
+	}
+	return matchedRoutes, nil
+}
+
+// ApplyHTTPRouteRules add header matched rules pointing to shadow service into http routes of service
+func ApplyHTTPRouteRules(svcName, namespace, shadowSvcName, meshKey, meshVersion string) error {
+	routes, err := GetHTTPRoutesOfService(svcName, namespace)
+	if err != nil {
+		return err
+	}
+	if len(routes) == 0 {
+		return fmt.Errorf("no HTTPRoute is pointing to service %s", svcName)
+	}
+	for _, route := range routes {
+		if err = snapshotRoutingRule(route); err != nil {
+			return err
+		}
+		if err = addShadowHTTPRouteRules(route, svcName, shadowSvcName, meshKey, meshVersion); err != nil {
+			return err
+		}
+		if _, err = cluster.Ins().UpdateHTTPRoute(route); err != nil {
+			return fmt.Errorf("failed to update HTTPRoute %s: %s", route.GetName(), err)
+		}
+		log.Info().Msgf("HTTPRoute %s updated with header '%s: %s'", route.GetName(), meshKey, meshVersion)
+	}
+	opt.Store.Gateway = util.Append(opt.Store.Gateway, svcName)
+	return nil
+}
+
+// RemoveHTTPRouteRules remove rules pointing to specified shadow service from http routes of service
+func RemoveHTTPRouteRules(svcName, namespace, shadowSvcName string) {
+	routes, err := GetHTTPRoutesOfService(svcName, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to fetch HTTPRoute of service %s", svcName)
+		return
+	}
+	for _, route := range routes {
+		ktRuleLeft, err2 := removeShadowHTTPRouteRules(route, shadowSvcName, svcName+util.MeshPodInfix)
+		if err2 != nil {
+			log.Warn().Err(err2).Msgf("Failed to parse HTTPRoute %s", route.GetName())
+			continue
+		}
+		if ktRuleLeft {
+			if _, err2 = cluster.Ins().UpdateHTTPRoute(route); err2 != nil {
+				log.Warn().Err(err2).Msgf("Failed to remove rules of %s from HTTPRoute %s", shadowSvcName, route.GetName())
+			} else {
+				log.Info().Msgf("Removed rules of %s from HTTPRoute %s", shadowSvcName, route.GetName())
+			}
+		} else {
+			// the last rule added by kt is gone, bring back the very original route
+			restoreHTTPRoute(route)
+		}
+	}
+}
+
+// RestoreHTTPRoutes restore http routes of service to the snapshot before mesh, return whether any route restored
+func RestoreHTTPRoutes(svcName, namespace string) bool {
+	routes, err := GetHTTPRoutesOfService(svcName, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("No HTTPRoute found for service %s", svcName)
+		return false
+	}
+	restored := false
+	for _, route := range routes {
+		if _, exists := route.GetAnnotations()[util.KtOriginRule]; exists {
+			restoreHTTPRoute(route)
+			restored = true
+		}
+	}
+	return restored
+}
+
+func restoreHTTPRoute(route *unstructured.Unstructured) {
+	if ok, err := loadOriginSpec(route); !ok {
+		log.Warn().Msgf("No origin rule annotation found in HTTPRoute %s, skipping", route.GetName())
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Failed to unmarshal origin rule of HTTPRoute %s", route.GetName())
+		return
+	}
+	if _, err := cluster.Ins().UpdateHTTPRoute(route); err != nil {
+		log.Error().Err(err).Msgf("Failed to recover HTTPRoute %s", route.GetName())
+	} else {
+		log.Info().Msgf("HTTPRoute %s recovered", route.GetName())
+	}
+}
+
+// loadOriginSpec put back the spec recorded in annotation, return false if no spec recorded
+func loadOriginSpec(rule *unstructured.Unstructured) (bool, error) {
+	originSpec, exists := rule.GetAnnotations()[util.KtOriginRule]
+	if !exists {
+		return false, nil
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(originSpec), &spec); err != nil {
+		return true, err
+	}
+	rule.Object["spec"] = spec
+	annotations := rule.GetAnnotations()
+	delete(annotations, util.KtOriginRule)
+	rule.SetAnnotations(annotations)
+	return true, nil
+}
+
+func snapshotRoutingRule(rule *unstructured.Unstructured) error {
+	if _, exists := rule.GetAnnotations()[util.KtOriginRule]; exists {
+		// already changed by other kt instance, keep the very original one
+		return nil
+	}
+	spec, err := json.Marshal(rule.Object["spec"])
+	if err != nil {
+		return err
+	}
+	annotations := rule.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[util.KtOriginRule] = string(spec)
+	rule.SetAnnotations(annotations)
+	return nil
+}
+
+func isHTTPRouteOfService(route *unstructured.Unstructured, svcName string) bool {
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	for _, rule := range rules {
+		if isRuleOfService(rule, route.GetNamespace(), func(name string) bool { return name == svcName }) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRuleOfService check whether any backend of http route rule matches the service name
+func isRuleOfService(rule interface{}, namespace string, nameMatcher func(string) bool) bool {
+	r, ok := rule.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	backendRefs, _ := r["backendRefs"].([]interface{})
+	for _, ref := range backendRefs {
+		backend, ok2 := ref.(map[string]interface{})
+		if !ok2 {
+			continue
+		}
+		kind, _ := backend["kind"].(string)
+		ns, _ := backend["namespace"].(string)
+		name, _ := backend["name"].(string)
+		if (kind == "" || kind == "Service") && (ns == "" || ns == namespace) && nameMatcher(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addShadowHTTPRouteRules put a header matched copy before each rule of the service, with backend replaced by shadow service
+func addShadowHTTPRouteRules(route *unstructured.Unstructured, svcName, shadowSvcName, meshKey, meshVersion string) error {
+	if _, err := removeShadowHTTPRouteRules(route, shadowSvcName, svcName+util.MeshPodInfix); err != nil {
+		return err
+	}
+	rules, _, err := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if err != nil {
+		return err
+	}
+	header := map[string]interface{}{
+		"type":  "Exact",
+		"name":  meshKey,
+		"value": meshVersion,
+	}
+	newRules := make([]interface{}, 0, len(rules)*2)
+	for _, rule := range rules {
+		if isRuleOfService(rule, route.GetNamespace(), func(name string) bool { return name == svcName }) {
+			newRules = append(newRules, toShadowHTTPRouteRule(rule.(map[string]interface{}), svcName, shadowSvcName, header))
+		}
+		newRules = append(newRules, rule)
+	}
+	return unstructured.SetNestedSlice(route.Object, newRules, "spec", "rules")
+}
+
+func toShadowHTTPRouteRule(rule map[string]interface{}, svcName, shadowSvcName string, header map[string]interface{}) map[string]interface{} {
+	matches, _ := rule["matches"].([]interface{})
+	if len(matches) == 0 {
+		matches = []interface{}{map[string]interface{}{}}
+	}
+	shadowMatches := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		match, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shadowMatch := map[string]interface{}{}
+		for k, v := range match {
+			shadowMatch[k] = v
+		}
+		headers, _ := match["headers"].([]interface{})
+		shadowMatch["headers"] = append(append([]interface{}{}, headers...), header)
+		shadowMatches = append(shadowMatches, shadowMatch)
+	}
+	shadowBackends := make([]interface{}, 0)
+	backendRefs, _ := rule["backendRefs"].([]interface{})
+	for _, ref := range backendRefs {
+		backend, ok := ref.(map[string]interface{})
+		if !ok || backend["name"] != svcName {
+			continue
+		}
+		shadowBackend := map[string]interface{}{
+			"name": shadowSvcName,
+		}
+		if port, exists := backend["port"]; exists {
+			shadowBackend["port"] = port
+		}
+		shadowBackends = append(shadowBackends, shadowBackend)
+		break
+	}
+	shadowRule := map[string]interface{}{
+		"matches":     shadowMatches,
+		"backendRefs": shadowBackends,
+	}
+	if filters, exists := rule["filters"]; exists {
+		shadowRule["filters"] = filters
+	}
+	return shadowRule
+}
+
+// removeShadowHTTPRouteRules remove rules pointing to shadow service, return whether any other kt rule still exists
+func removeShadowHTTPRouteRules(route *unstructured.Unstructured, shadowSvcName, shadowSvcPrefix string) (bool, error) {
+	rules, found, err := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if err != nil || !found {
+		return false, err
+	}
+	ktRuleLeft := false
+	remainRules := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		if isRuleOfService(rule, route.GetNamespace(), func(name string) bool { return name == shadowSvcName }) {
+			continue
+		}
+		if isRuleOfService(rule, route.GetNamespace(), func(name string) bool { return strings.HasPrefix(name, shadowSvcPrefix) }) {
+			ktRuleLeft = true
+		}
+		remainRules = append(remainRules, rule)
+	}
+	return ktRuleLeft, unstructured.SetNestedSlice(route.Object, remainRules, "spec", "rules")
+}
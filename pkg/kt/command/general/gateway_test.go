@@ -0,0 +1,80 @@
+package general
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+func newTestHTTPRoute() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "HTTPRoute",
+		"metadata": map[string]interface{}{
+			"name":      "tomcat-route",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"matches": []interface{}{
+						map[string]interface{}{
+							"path": map[string]interface{}{"type": "PathPrefix", "value": "/api"},
+						},
+					},
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": "tomcat", "port": int64(8080)},
+					},
+				},
+				map[string]interface{}{
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": "nginx", "port": int64(80)},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func Test_isHTTPRouteOfService(t *testing.T) {
+	route := newTestHTTPRoute()
+	require.True(t, isHTTPRouteOfService(route, "tomcat"))
+	require.True(t, isHTTPRouteOfService(route, "nginx"))
+	require.False(t, isHTTPRouteOfService(route, "redis"))
+}
+
+func Test_addAndRemoveShadowHTTPRouteRules(t *testing.T) {
+	route := newTestHTTPRoute()
+	require.NoError(t, addShadowHTTPRouteRules(route, "tomcat", "tomcat-kt-mesh-a", "version", "a"))
+	require.NoError(t, addShadowHTTPRouteRules(route, "tomcat", "tomcat-kt-mesh-b", "version", "b"))
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	require.Equal(t, 4, len(rules))
+
+	shadowRule := rules[0].(map[string]interface{})
+	require.Equal(t, "tomcat-kt-mesh-a", shadowRule["backendRefs"].([]interface{})[0].(map[string]interface{})["name"])
+	require.Equal(t, int64(8080), shadowRule["backendRefs"].([]interface{})[0].(map[string]interface{})["port"])
+	match := shadowRule["matches"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "/api", match["path"].(map[string]interface{})["value"])
+	require.Equal(t, "a", match["headers"].([]interface{})[0].(map[string]interface{})["value"])
+
+	ktRuleLeft, err := removeShadowHTTPRouteRules(route, "tomcat-kt-mesh-a", "tomcat-kt-mesh-")
+	require.NoError(t, err)
+	require.True(t, ktRuleLeft)
+	ktRuleLeft, err = removeShadowHTTPRouteRules(route, "tomcat-kt-mesh-b", "tomcat-kt-mesh-")
+	require.NoError(t, err)
+	require.False(t, ktRuleLeft)
+	require.Equal(t, newTestHTTPRoute().Object["spec"], route.Object["spec"])
+}
+
+func Test_snapshotAndLoadOriginSpec(t *testing.T) {
+	route := newTestHTTPRoute()
+	require.NoError(t, snapshotRoutingRule(route))
+	require.NoError(t, addShadowHTTPRouteRules(route, "tomcat", "tomcat-kt-mesh-a", "version", "a"))
+	ok, err := loadOriginSpec(route)
+	require.True(t, ok)
+	require.NoError(t, err)
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	require.Equal(t, 2, len(rules))
+	_, exists := route.GetAnnotations()[util.KtOriginRule]
+	require.False(t, exists)
+}
@@ -0,0 +1,320 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"regexp"
+	"strings"
+)
+
+var istioRuleKinds = []string{cluster.KindDestinationRule, cluster.KindVirtualService}
+
+// ApplyIstioRules add subset of specified version to destination rule of shadow service, and add header route to
+// virtual service of service, marked requests would be routed to the subset, which selects nothing but the shadow pod
+func ApplyIstioRules(svc *coreV1.Service, shadowSvcName, meshKey, meshVersion string) error {
+	ruleName := IstioRuleName(meshVersion)
+	// record before applying, so that rules partially applied could be removed as well
+	opt.Store.Istio = util.Append(opt.Store.Istio, svc.Name)
+	// subset must be ready before any route refers to it
+	if err := applyIstioRule(cluster.KindDestinationRule, shadowSvcName, svc.Namespace, ruleName,
+		func(rule *unstructured.Unstructured) error {
+			return addIstioSubset(rule, ruleName, meshKey, meshVersion)
+		}); err != nil {
+		return err
+	}
+	return applyIstioRule(cluster.KindVirtualService, svc.Name, svc.Namespace, ruleName,
+		func(rule *unstructured.Unstructured) error {
+			return addIstioHeaderRoute(rule, ruleName, shadowSvcName, meshKey, meshVersion)
+		})
+}
+
+// RemoveIstioRules remove subset and header route of specified version from istio rules of service
+func RemoveIstioRules(svcName, namespace, meshVersion string) {
+	ruleName := IstioRuleName(meshVersion)
+	for _, kind := range istioRuleKinds {
+		host := istioRuleHost(kind, svcName, meshVersion)
+		rule, err := findIstioRule(kind, host, namespace)
+		if err != nil || rule == nil {
+			log.Debug().Err(err).Msgf("No %s found for service %s", kind, host)
+			continue
+		} else if !isIstioRuleChanged(rule) {
+			log.Debug().Msgf("%s %s is not changed by kt", kind, rule.GetName())
+			continue
+		}
+		ktItemLeft, err := removeIstioItem(rule, ruleName, istioItemPath(kind)...)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to parse %s %s", kind, rule.GetName())
+			continue
+		}
+		if ktItemLeft {
+			if _, err = cluster.Ins().UpdateIstioRule(rule); err != nil {
+				log.Warn().Err(err).Msgf("Failed to remove '%s' from %s %s", ruleName, kind, rule.GetName())
+			} else {
+				log.Info().Msgf("Removed '%s' from %s %s", ruleName, kind, rule.GetName())
+			}
+		} else {
+			// the last version added by kt is gone, bring back the very original rule
+			restoreIstioRule(rule)
+		}
+	}
+}
+
+// RestoreIstioRules restore istio rules of service to the snapshot before mesh, return whether any rule restored
+func RestoreIstioRules(svcName, namespace string) bool {
+	restored := false
+	rule, err := findIstioRule(cluster.KindVirtualService, svcName, namespace)
+	if err != nil || rule == nil {
+		log.Debug().Err(err).Msgf("No %s found for service %s", cluster.KindVirtualService, svcName)
+	} else if isIstioRuleChanged(rule) {
+		restoreIstioRule(rule)
+		restored = true
+	}
+	// destination rules of shadow services are always created by kt
+	rules, err := cluster.Ins().GetIstioRules(cluster.KindDestinationRule, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to fetch %s", cluster.KindDestinationRule)
+		return restored
+	}
+	for i, r := range rules {
+		host, _, _ := unstructured.NestedString(r.Object, "spec", "host")
+		if r.GetLabels()[util.ControlBy] == util.KubernetesToolkit && strings.HasPrefix(host, svcName+util.MeshPodInfix) {
+			restoreIstioRule(&rules[i])
+			restored = true
+		}
+	}
+	return restored
+}
+
+// IstioRuleName get name of istio subset and route for specified version
+func IstioRuleName(meshVersion string) string {
+	name := regexp.MustCompile("[^a-z0-9-]+").ReplaceAllString(strings.ToLower(meshVersion), "-")
+	return util.IstioRulePrefix + strings.Trim(name, "-")
+}
+
+// applyIstioRule create or update istio rule of specified host with the modification
+func applyIstioRule(kind, host, namespace, ruleName string, modify func(*unstructured.Unstructured) error) error {
+	rule, err := findIstioRule(kind, host, namespace)
+	if err != nil {
+		return err
+	}
+	isNew := rule == nil
+	if isNew {
+		rule = newIstioRule(kind, host, namespace)
+	} else if err = snapshotIstioRule(rule); err != nil {
+		return err
+	}
+	if err = modify(rule); err != nil {
+		return err
+	}
+	if isNew {
+		_, err = cluster.Ins().CreateIstioRule(rule)
+	} else {
+		_, err = cluster.Ins().UpdateIstioRule(rule)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply %s %s: %s", kind, rule.GetName(), err)
+	}
+	log.Info().Msgf("%s %s updated with '%s'", kind, rule.GetName(), ruleName)
+	return nil
+}
+
+// istioRuleHost subset lives in destination rule of shadow service, while header route lives in virtual service of
+// the meshed service
+func istioRuleHost(kind, svcName, meshVersion string) string {
+	if kind == cluster.KindDestinationRule {
+		return svcName + util.MeshPodInfix + meshVersion
+	}
+	return svcName
+}
+
+func restoreIstioRule(rule *unstructured.Unstructured) {
+	kind := rule.GetKind()
+	if rule.GetLabels()[util.ControlBy] == util.KubernetesToolkit {
+		if err := cluster.Ins().RemoveIstioRule(kind, rule.GetName(), rule.GetNamespace()); err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete %s %s", kind, rule.GetName())
+		} else {
+			log.Info().Msgf("%s %s deleted", kind, rule.GetName())
+		}
+		return
+	}
+	if ok, err := loadOriginSpec(rule); !ok {
+		log.Warn().Msgf("No origin rule annotation found in %s %s, skipping", kind, rule.GetName())
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Failed to unmarshal origin rule of %s %s", kind, rule.GetName())
+		return
+	}
+	if _, err := cluster.Ins().UpdateIstioRule(rule); err != nil {
+		log.Error().Err(err).Msgf("Failed to recover %s %s", kind, rule.GetName())
+	} else {
+		log.Info().Msgf("%s %s recovered", kind, rule.GetName())
+	}
+}
+
+func isIstioRuleChanged(rule *unstructured.Unstructured) bool {
+	if rule.GetLabels()[util.ControlBy] == util.KubernetesToolkit {
+		return true
+	}
+	_, exists := rule.GetAnnotations()[util.KtOriginRule]
+	return exists
+}
+
+func snapshotIstioRule(rule *unstructured.Unstructured) error {
+	if rule.GetLabels()[util.ControlBy] == util.KubernetesToolkit {
+		// created by kt, will be deleted instead of restored
+		return nil
+	}
+	return snapshotRoutingRule(rule)
+}
+
+func findIstioRule(kind, svcName, namespace string) (*unstructured.Unstructured, error) {
+	rules, err := cluster.Ins().GetIstioRules(kind, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s, please make sure istio is installed: %s", kind, err)
+	}
+	hosts := istioHosts(svcName, namespace)
+	for i, rule := range rules {
+		if isIstioRuleForHost(&rule, hosts) {
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func isIstioRuleForHost(rule *unstructured.Unstructured, hosts []string) bool {
+	if rule.GetKind() == cluster.KindDestinationRule {
+		host, _, _ := unstructured.NestedString(rule.Object, "spec", "host")
+		return util.Contains(hosts, host)
+	}
+	ruleHosts, _, _ := unstructured.NestedStringSlice(rule.Object, "spec", "hosts")
+	for _, host := range ruleHosts {
+		if util.Contains(hosts, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func istioHosts(svcName, namespace string) []string {
+	return []string{
+		svcName,
+		fmt.Sprintf("%s.%s", svcName, namespace),
+		fmt.Sprintf("%s.%s.svc", svcName, namespace),
+		fmt.Sprintf("%s.%s.svc.%s", svcName, namespace, opt.Get().Connect.ClusterDomain),
+	}
+}
+
+func newIstioRule(kind, svcName, namespace string) *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	rule.SetAPIVersion(cluster.IstioApiVersion(kind))
+	rule.SetKind(kind)
+	rule.SetName(svcName + util.IstioRuleSuffix)
+	rule.SetNamespace(namespace)
+	rule.SetLabels(map[string]string{
+		util.ControlBy: util.KubernetesToolkit,
+	})
+	if kind == cluster.KindDestinationRule {
+		rule.Object["spec"] = map[string]interface{}{
+			"host":    svcName,
+			"subsets": []interface{}{},
+		}
+	} else {
+		rule.Object["spec"] = map[string]interface{}{
+			"hosts": []interface{}{svcName},
+			"http": []interface{}{
+				map[string]interface{}{
+					"name":  "default",
+					"route": []interface{}{istioDestination(svcName, "")},
+				},
+			},
+		}
+	}
+	return rule
+}
+
+func addIstioSubset(rule *unstructured.Unstructured, subsetName, meshKey, meshVersion string) error {
+	subset := map[string]interface{}{
+		"name": subsetName,
+		"labels": map[string]interface{}{
+			meshKey: meshVersion,
+		},
+	}
+	return prependIstioItem(rule, subset, istioItemPath(cluster.KindDestinationRule)...)
+}
+
+func addIstioHeaderRoute(rule *unstructured.Unstructured, routeName, shadowSvcName, meshKey, meshVersion string) error {
+	route := map[string]interface{}{
+		"name": routeName,
+		"match": []interface{}{
+			map[string]interface{}{
+				"headers": map[string]interface{}{
+					meshKey: map[string]interface{}{
+						"exact": meshVersion,
+					},
+				},
+			},
+		},
+		"route": []interface{}{istioDestination(shadowSvcName, routeName)},
+	}
+	return prependIstioItem(rule, route, istioItemPath(cluster.KindVirtualService)...)
+}
+
+func istioDestination(host, subset string) map[string]interface{} {
+	destination := map[string]interface{}{
+		"host": host,
+	}
+	if subset != "" {
+		destination["subset"] = subset
+	}
+	return map[string]interface{}{
+		"destination": destination,
+	}
+}
+
+func istioItemPath(kind string) []string {
+	if kind == cluster.KindDestinationRule {
+		return []string{"spec", "subsets"}
+	}
+	return []string{"spec", "http"}
+}
+
+// prependIstioItem put item in front of the list, so that it takes priority over existing routes
+func prependIstioItem(rule *unstructured.Unstructured, item map[string]interface{}, fields ...string) error {
+	if _, err := removeIstioItem(rule, item["name"].(string), fields...); err != nil {
+		return err
+	}
+	items, _, err := unstructured.NestedSlice(rule.Object, fields...)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedSlice(rule.Object, append([]interface{}{item}, items...), fields...)
+}
+
+// removeIstioItem remove item with specified name from the list, return whether any other kt item still exists
+func removeIstioItem(rule *unstructured.Unstructured, name string, fields ...string) (bool, error) {
+	items, found, err := unstructured.NestedSlice(rule.Object, fields...)
+	if err != nil || !found {
+		return false, err
+	}
+	ktItemLeft := false
+	remainItems := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemName := ""
+		if m, ok := item.(map[string]interface{}); ok {
+			itemName, _ = m["name"].(string)
+		}
+		if itemName == name {
+			continue
+		}
+		if strings.HasPrefix(itemName, util.IstioRulePrefix) {
+			ktItemLeft = true
+		}
+		remainItems = append(remainItems, item)
+	}
+	return ktItemLeft, unstructured.SetNestedSlice(rule.Object, remainItems, fields...)
+}
@@ -0,0 +1,71 @@
+package general
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+func TestIstioRuleName(t *testing.T) {
+	require.Equal(t, "kt-abcde", IstioRuleName("abcde"))
+	require.Equal(t, "kt-0-0-1", IstioRuleName("0.0.1"))
+	require.Equal(t, "kt-dev-local", IstioRuleName("Dev_Local."))
+}
+
+func Test_addAndRemoveIstioHeaderRoute(t *testing.T) {
+	rule := newIstioRule(cluster.KindVirtualService, "tomcat", "default")
+	require.True(t, isIstioRuleForHost(rule, istioHosts("tomcat", "default")))
+	require.False(t, isIstioRuleForHost(rule, istioHosts("nginx", "default")))
+
+	require.NoError(t, addIstioHeaderRoute(rule, "kt-a", "tomcat-kt-mesh-a", "version", "a"))
+	require.NoError(t, addIstioHeaderRoute(rule, "kt-b", "tomcat-kt-mesh-b", "version", "b"))
+	require.NoError(t, addIstioHeaderRoute(rule, "kt-a", "tomcat-kt-mesh-a", "version", "a"))
+	routes, _, _ := unstructured.NestedSlice(rule.Object, "spec", "http")
+	require.Equal(t, 3, len(routes))
+	require.Equal(t, "kt-a", routes[0].(map[string]interface{})["name"])
+	require.Equal(t, "kt-b", routes[1].(map[string]interface{})["name"])
+	require.Equal(t, "default", routes[2].(map[string]interface{})["name"])
+	require.Equal(t, []interface{}{istioDestination("tomcat-kt-mesh-a", "kt-a")}, routes[0].(map[string]interface{})["route"])
+	require.Equal(t, []interface{}{istioDestination("tomcat", "")}, routes[2].(map[string]interface{})["route"])
+
+	ktItemLeft, err := removeIstioItem(rule, "kt-a", "spec", "http")
+	require.NoError(t, err)
+	require.True(t, ktItemLeft)
+	ktItemLeft, err = removeIstioItem(rule, "kt-b", "spec", "http")
+	require.NoError(t, err)
+	require.False(t, ktItemLeft)
+	routes, _, _ = unstructured.NestedSlice(rule.Object, "spec", "http")
+	require.Equal(t, 1, len(routes))
+}
+
+func Test_addAndRemoveIstioSubset(t *testing.T) {
+	host := istioRuleHost(cluster.KindDestinationRule, "tomcat", "a")
+	require.Equal(t, "tomcat-kt-mesh-a", host)
+	rule := newIstioRule(cluster.KindDestinationRule, host, "default")
+	require.True(t, isIstioRuleForHost(rule, istioHosts(host, "default")))
+
+	require.NoError(t, addIstioSubset(rule, "kt-a", "version", "a"))
+	require.NoError(t, addIstioSubset(rule, "kt-a", "version", "a"))
+	subsets, _, _ := unstructured.NestedSlice(rule.Object, istioItemPath(cluster.KindDestinationRule)...)
+	require.Equal(t, 1, len(subsets))
+	require.Equal(t, map[string]interface{}{"version": "a"}, subsets[0].(map[string]interface{})["labels"])
+
+	ktItemLeft, err := removeIstioItem(rule, "kt-a", istioItemPath(cluster.KindDestinationRule)...)
+	require.NoError(t, err)
+	require.False(t, ktItemLeft)
+}
+
+func Test_istioRuleChanged(t *testing.T) {
+	rule := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": cluster.KindDestinationRule,
+		"spec": map[string]interface{}{
+			"host": "tomcat.default.svc",
+		},
+	}}
+	require.True(t, isIstioRuleForHost(rule, istioHosts("tomcat", "default")))
+	require.False(t, isIstioRuleChanged(rule))
+	require.NoError(t, snapshotIstioRule(rule))
+	require.True(t, isIstioRuleChanged(rule))
+	require.True(t, isIstioRuleChanged(newIstioRule(cluster.KindVirtualService, "tomcat", "default")))
+}
@@ -0,0 +1,411 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	localEnvFile  = ".env"
+	redactedValue = "<redacted>"
+)
+
+var (
+	plainEnvValue   = regexp.MustCompile(`^[\w./:@%+,-]*$`)
+	envReference    = regexp.MustCompile(`\$\$|\$\(([\w.-]+)\)`)
+	metadataItemRef = regexp.MustCompile(`^metadata\.(labels|annotations)\['(.+)'\]$`)
+)
+
+// files and directories created for synced environment by current process, only they are removed on exit
+var localEnvLock sync.Mutex
+var localEnvFiles = make([]string, 0)
+var localEnvDirs = make([]string, 0)
+
+type envVar struct {
+	name  string
+	value string
+}
+
+type envResolver struct {
+	workload *cluster.Workload
+	redact   []string
+	cache    map[string]map[string][]byte
+}
+
+// SyncWorkloadEnv write environment variables, config maps and secrets used by container of workload to local directory,
+// variables are written to '.env' file, and mounted files are placed under their mount path inside the directory,
+// return the written variables
+func SyncWorkloadEnv(workload *cluster.Workload, containerName, dir, redact string) (map[string]string, error) {
+	container := findContainer(workload.Template.Spec.Containers, containerName)
+	if container == nil && containerName == "" {
+		return nil, fmt.Errorf("no container found in %s '%s'", workload.Kind, workload.Name)
+	} else if container == nil {
+		return nil, fmt.Errorf("container '%s' not found in %s '%s'", containerName, workload.Kind, workload.Name)
+	}
+	// never overwrite existing files of user
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("directory %s already exists and is not empty", dir)
+	}
+
+	r := &envResolver{
+		workload: workload,
+		redact:   parseRedactPatterns(redact),
+		cache:    make(map[string]map[string][]byte),
+	}
+	envs := r.resolveEnv(container)
+	files := r.resolveMounts(container)
+
+	opt.Store.LocalEnv = dir
+	values := make(map[string]string)
+	lines := make([]string, 0)
+	for _, e := range envs {
+		values[e.name] = r.redactValue(e.name, e.value)
+		lines = append(lines, formatEnvLine(e.name, values[e.name]))
+	}
+	if err := writeLocalEnvFile(filepath.Join(dir, localEnvFile), []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return nil, err
+	}
+	for _, p := range sortedDataKeys(files) {
+		if err := writeLocalEnvFile(filepath.Join(dir, filepath.FromSlash(p)), files[p]); err != nil {
+			return nil, err
+		}
+	}
+	localEnvLock.Lock()
+	for _, p := range append(localEnvDirs, localEnvFiles...) {
+		_ = util.FixFileOwner(p)
+	}
+	localEnvLock.Unlock()
+	log.Info().Msgf("Synced %d environment variables and %d mounted files of container '%s' to %s",
+		len(envs), len(files), container.Name, dir)
+	return values, nil
+}
+
+// RemoveLocalEnv remove files written to synced environment directory, and directories created for them
+func RemoveLocalEnv(dir string) {
+	localEnvLock.Lock()
+	defer localEnvLock.Unlock()
+	for _, f := range localEnvFiles {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Msgf("Failed to remove synced file %s", f)
+		}
+	}
+	// directories are recorded parent first, remove children first, skip those not empty
+	for i := len(localEnvDirs) - 1; i >= 0; i-- {
+		if err := os.Remove(localEnvDirs[i]); err != nil && !os.IsNotExist(err) {
+			log.Debug().Err(err).Msgf("Directory %s not removed", localEnvDirs[i])
+		}
+	}
+	localEnvFiles = make([]string, 0)
+	localEnvDirs = make([]string, 0)
+	log.Info().Msgf("Removed synced environment in %s", dir)
+}
+
+func (r *envResolver) resolveEnv(container *coreV1.Container) []envVar {
+	envs := make([]envVar, 0)
+	values := make(map[string]string)
+	put := func(name, value string) {
+		if _, exists := values[name]; exists {
+			for i := range envs {
+				if envs[i].name == name {
+					envs[i].value = value
+				}
+			}
+		} else {
+			envs = append(envs, envVar{name: name, value: value})
+		}
+		values[name] = value
+	}
+
+	for _, from := range container.EnvFrom {
+		var data map[string][]byte
+		if from.ConfigMapRef != nil {
+			data = r.getConfigMapData(from.ConfigMapRef.Name, from.ConfigMapRef.Optional)
+		} else if from.SecretRef != nil {
+			data = r.getSecretData(from.SecretRef.Name, from.SecretRef.Optional)
+		}
+		for _, key := range sortedDataKeys(data) {
+			put(from.Prefix+key, string(data[key]))
+		}
+	}
+	for _, e := range container.Env {
+		if value, ok := r.resolveEnvValue(e, values); ok {
+			put(e.Name, value)
+		}
+	}
+	return envs
+}
+
+func (r *envResolver) resolveEnvValue(e coreV1.EnvVar, values map[string]string) (string, bool) {
+	if e.ValueFrom == nil {
+		return expandEnvValue(e.Value, values), true
+	}
+	var data map[string][]byte
+	var key string
+	if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil {
+		data, key = r.getConfigMapData(ref.Name, ref.Optional), ref.Key
+	} else if ref := e.ValueFrom.SecretKeyRef; ref != nil {
+		data, key = r.getSecretData(ref.Name, ref.Optional), ref.Key
+	} else if ref := e.ValueFrom.FieldRef; ref != nil {
+		return r.resolveFieldRef(e.Name, ref.FieldPath)
+	} else {
+		log.Warn().Msgf("Value of env %s cannot be resolved locally, skipped", e.Name)
+		return "", false
+	}
+	value, exists := data[key]
+	if !exists {
+		log.Debug().Msgf("Key %s of env %s not found", key, e.Name)
+	}
+	return string(value), exists
+}
+
+func (r *envResolver) resolveFieldRef(name, fieldPath string) (string, bool) {
+	template := &r.workload.Template
+	switch fieldPath {
+	case "metadata.namespace":
+		return r.workload.Namespace, true
+	case "metadata.name":
+		// pod name is decided at runtime, use workload name instead
+		return r.workload.Name, true
+	case "spec.serviceAccountName":
+		if template.Spec.ServiceAccountName == "" {
+			return "default", true
+		}
+		return template.Spec.ServiceAccountName, true
+	}
+	if match := metadataItemRef.FindStringSubmatch(fieldPath); match != nil {
+		if match[1] == "labels" {
+			return template.Labels[match[2]], true
+		}
+		return template.Annotations[match[2]], true
+	}
+	log.Warn().Msgf("Field %s of env %s cannot be resolved locally, skipped", fieldPath, name)
+	return "", false
+}
+
+func (r *envResolver) resolveMounts(container *coreV1.Container) map[string][]byte {
+	files := make(map[string][]byte)
+	for _, mount := range container.VolumeMounts {
+		volume := findVolume(r.workload.Template.Spec.Volumes, mount.Name)
+		if volume == nil {
+			continue
+		}
+		volumeFiles, ok := r.getVolumeFiles(volume)
+		if !ok {
+			log.Debug().Msgf("Volume %s is neither config map nor secret, skipped", mount.Name)
+			continue
+		}
+		for p, content := range volumeFiles {
+			target := toMountedPath(mount.MountPath, mount.SubPath, p)
+			if target == "" {
+				continue
+			}
+			files[target] = []byte(r.redactValue(path.Base(p), string(content)))
+		}
+	}
+	return files
+}
+
+func (r *envResolver) getVolumeFiles(volume *coreV1.Volume) (map[string][]byte, bool) {
+	if v := volume.ConfigMap; v != nil {
+		return projectVolumeItems(r.getConfigMapData(v.Name, v.Optional), v.Items), true
+	} else if v := volume.Secret; v != nil {
+		return projectVolumeItems(r.getSecretData(v.SecretName, v.Optional), v.Items), true
+	} else if v := volume.Projected; v != nil {
+		files := make(map[string][]byte)
+		for _, source := range v.Sources {
+			var projected map[string][]byte
+			if s := source.ConfigMap; s != nil {
+				projected = projectVolumeItems(r.getConfigMapData(s.Name, s.Optional), s.Items)
+			} else if s := source.Secret; s != nil {
+				projected = projectVolumeItems(r.getSecretData(s.Name, s.Optional), s.Items)
+			}
+			for p, content := range projected {
+				files[p] = content
+			}
+		}
+		return files, true
+	}
+	return nil, false
+}
+
+func (r *envResolver) getConfigMapData(name string, optional *bool) map[string][]byte {
+	key := "configmap/" + name
+	if data, exists := r.cache[key]; exists {
+		return data
+	}
+	data := make(map[string][]byte)
+	if cm, err := cluster.Ins().GetConfigMap(name, r.workload.Namespace); err != nil {
+		logMissingSource(key, optional, err)
+	} else {
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+	}
+	r.cache[key] = data
+	return data
+}
+
+func (r *envResolver) getSecretData(name string, optional *bool) map[string][]byte {
+	key := "secret/" + name
+	if data, exists := r.cache[key]; exists {
+		return data
+	}
+	data := make(map[string][]byte)
+	if secret, err := cluster.Ins().GetSecret(name, r.workload.Namespace); err != nil {
+		logMissingSource(key, optional, err)
+	} else {
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+	}
+	r.cache[key] = data
+	return data
+}
+
+func (r *envResolver) redactValue(name, value string) string {
+	for _, pattern := range r.redact {
+		if matched, _ := path.Match(pattern, name); matched {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+func logMissingSource(source string, optional *bool, err error) {
+	if optional != nil && *optional {
+		log.Debug().Err(err).Msgf("Optional %s not available", source)
+	} else {
+		log.Warn().Err(err).Msgf("Failed to fetch %s", source)
+	}
+}
+
+// expandEnvValue replace $(VAR) with value of previously defined variable, as kubernetes does
+func expandEnvValue(value string, values map[string]string) string {
+	return envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		if v, exists := values[ref[2:len(ref)-1]]; exists {
+			return v
+		}
+		return ref
+	})
+}
+
+// projectVolumeItems map keys to file paths, all keys are used as file name if items not specified
+func projectVolumeItems(data map[string][]byte, items []coreV1.KeyToPath) map[string][]byte {
+	files := make(map[string][]byte)
+	if len(items) == 0 {
+		for k, v := range data {
+			files[k] = v
+		}
+		return files
+	}
+	for _, item := range items {
+		if v, exists := data[item.Key]; exists {
+			files[item.Path] = v
+		}
+	}
+	return files
+}
+
+// toMountedPath get relative path of volume file in local directory, return empty if file is not mounted
+func toMountedPath(mountPath, subPath, filePath string) string {
+	if subPath != "" {
+		if filePath == subPath {
+			filePath = ""
+		} else if strings.HasPrefix(filePath, subPath+"/") {
+			filePath = strings.TrimPrefix(filePath, subPath+"/")
+		} else {
+			return ""
+		}
+	}
+	// joining with root prevents path escaping from local directory
+	return strings.TrimPrefix(path.Join("/", mountPath, filePath), "/")
+}
+
+func formatEnvLine(name, value string) string {
+	if plainEnvValue.MatchString(value) {
+		return name + "=" + value
+	}
+	return name + "=" + strconv.Quote(value)
+}
+
+func parseRedactPatterns(redact string) []string {
+	patterns := make([]string, 0)
+	for _, p := range strings.Split(redact, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func writeLocalEnvFile(file string, content []byte) error {
+	dirs := getAbsentDirs(filepath.Dir(file))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	recordLocalEnvFile(file, dirs...)
+	return os.WriteFile(file, content, 0600)
+}
+
+// recordLocalEnvFile remember file and directories created by current process, so that they can be removed on exit
+func recordLocalEnvFile(file string, dirs ...string) {
+	localEnvLock.Lock()
+	defer localEnvLock.Unlock()
+	localEnvDirs = append(localEnvDirs, dirs...)
+	if !util.Contains(localEnvFiles, file) {
+		localEnvFiles = append(localEnvFiles, file)
+	}
+}
+
+// getAbsentDirs get directories to be created before the specified one exists, parent first
+func getAbsentDirs(dir string) []string {
+	if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+		return []string{}
+	}
+	return append(getAbsentDirs(filepath.Dir(dir)), dir)
+}
+
+func findContainer(containers []coreV1.Container, name string) *coreV1.Container {
+	for i := range containers {
+		if name == "" || containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+func findVolume(volumes []coreV1.Volume, name string) *coreV1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+func sortedDataKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package general
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_resolveEnv(t *testing.T) {
+	r := &envResolver{
+		workload: &cluster.Workload{
+			Kind:      cluster.KindDeployment,
+			Name:      "tomcat",
+			Namespace: "default",
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "tomcat"}},
+			},
+		},
+		cache: map[string]map[string][]byte{
+			"configmap/tomcat-config": {"LOG_LEVEL": []byte("info"), "PORT": []byte("8080")},
+		},
+	}
+	container := &coreV1.Container{
+		EnvFrom: []coreV1.EnvFromSource{{
+			ConfigMapRef: &coreV1.ConfigMapEnvSource{LocalObjectReference: coreV1.LocalObjectReference{Name: "tomcat-config"}},
+		}},
+		Env: []coreV1.EnvVar{
+			{Name: "LOG_LEVEL", Value: "debug"},
+			{Name: "URL", Value: "http://$(APP):$(PORT)/$(UNKNOWN)?cost=$$5"},
+			{Name: "APP", ValueFrom: &coreV1.EnvVarSource{FieldRef: &coreV1.ObjectFieldSelector{FieldPath: "metadata.labels['app']"}}},
+			{Name: "NS", ValueFrom: &coreV1.EnvVarSource{FieldRef: &coreV1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+			{Name: "POD_IP", ValueFrom: &coreV1.EnvVarSource{FieldRef: &coreV1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
+		},
+	}
+	require.Equal(t, []envVar{
+		{name: "LOG_LEVEL", value: "debug"},
+		{name: "PORT", value: "8080"},
+		{name: "URL", value: "http://$(APP):8080/$(UNKNOWN)?cost=$5"},
+		{name: "APP", value: "tomcat"},
+		{name: "NS", value: "default"},
+	}, r.resolveEnv(container))
+}
+
+func Test_toMountedPath(t *testing.T) {
+	require.Equal(t, "etc/config/app.yaml", toMountedPath("/etc/config", "", "app.yaml"))
+	require.Equal(t, "etc/app.yaml", toMountedPath("/etc/app.yaml", "app.yaml", "app.yaml"))
+	require.Equal(t, "etc/conf/a.yaml", toMountedPath("/etc/conf", "sub", "sub/a.yaml"))
+	require.Equal(t, "", toMountedPath("/etc/app.yaml", "app.yaml", "other.yaml"))
+	require.Equal(t, "etc/passwd", toMountedPath("/etc/config", "", "../../../etc/passwd"))
+}
+
+func Test_projectVolumeItems(t *testing.T) {
+	data := map[string][]byte{"a": []byte("1"), "b": []byte("2")}
+	require.Equal(t, data, projectVolumeItems(data, nil))
+	require.Equal(t, map[string][]byte{"conf/b.txt": []byte("2")},
+		projectVolumeItems(data, []coreV1.KeyToPath{{Key: "b", Path: "conf/b.txt"}, {Key: "c", Path: "c.txt"}}))
+}
+
+func Test_formatEnvLineAndRedact(t *testing.T) {
+	require.Equal(t, "PORT=8080", formatEnvLine("PORT", "8080"))
+	require.Equal(t, `GREETING="hello world\n"`, formatEnvLine("GREETING", "hello world\n"))
+	r := &envResolver{redact: parseRedactPatterns("*_PASSWORD, token")}
+	require.Equal(t, redactedValue, r.redactValue("DB_PASSWORD", "secret"))
+	require.Equal(t, redactedValue, r.redactValue("token", "secret"))
+	require.Equal(t, "admin", r.redactValue("DB_USER", "admin"))
+}
+
+func Test_removeLocalEnv(t *testing.T) {
+	existingDir := t.TempDir()
+	userFile := filepath.Join(existingDir, "notes.txt")
+	require.NoError(t, os.WriteFile(userFile, []byte("keep"), 0600))
+	require.NoError(t, writeLocalEnvFile(filepath.Join(existingDir, localEnvFile), []byte("A=1\n")))
+	require.NoError(t, writeLocalEnvFile(filepath.Join(existingDir, "etc", "config", "app.yaml"), []byte("a: 1")))
+	newDir := filepath.Join(t.TempDir(), "kt-env")
+	require.NoError(t, writeLocalEnvFile(filepath.Join(newDir, "etc", "app.yaml"), []byte("a: 1")))
+
+	RemoveLocalEnv(existingDir)
+	_, err := os.Stat(userFile)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(existingDir, localEnvFile))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(existingDir, "etc"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(newDir)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,107 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+)
+
+// SplitServiceTraffic let exposed ports of service reach shadow pod, while other ports keep reaching original pods,
+// traffic is split by a router pod in cluster, so that it keeps working even if local process exits unexpectedly
+func SplitServiceTraffic(svc *coreV1.Service, shadowName string, shadowLabels map[string]string, exposedPorts []int) error {
+	if len(svc.Spec.Selector) == 0 {
+		return fmt.Errorf("service %s has no selector, cannot apply partial exchange", svc.Name)
+	}
+	ports, err := GetServicePorts(svc)
+	if err != nil {
+		return err
+	}
+	namespace := svc.Namespace
+
+	// Create stuntman service for ports which keep reaching original pods
+	stuntmanSvcName := svc.Name + util.StuntmanServiceSuffix
+	if _, err = cluster.Ins().GetService(stuntmanSvcName, namespace); err == nil {
+		return fmt.Errorf("service %s is already meshed, cannot apply partial exchange", svc.Name)
+	}
+	if _, err = cluster.Ins().CreateService(&cluster.SvcMetaAndSpec{
+		Meta: &cluster.ResourceMeta{
+			Name:        stuntmanSvcName,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		External:  false,
+		Ports:     ports,
+		Selectors: svc.Spec.Selector,
+	}); err != nil {
+		return err
+	}
+	log.Info().Msgf("Service %s created", stuntmanSvcName)
+	// stuntman service is removed together with router pod on exit
+	routerPodName := svc.Name + util.RouterPodSuffix
+	opt.Store.Router = routerPodName
+
+	// Create shadow service for exposed ports
+	if _, err = cluster.Ins().CreateService(&cluster.SvcMetaAndSpec{
+		Meta: &cluster.ResourceMeta{
+			Name:        shadowName,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		External:  false,
+		Ports:     ports,
+		Selectors: shadowLabels,
+	}); err != nil {
+		return err
+	}
+	opt.Store.Service = util.Append(opt.Store.Service, shadowName)
+	log.Info().Msgf("Service %s created", shadowName)
+
+	// Create router pod
+	// Must after stuntman service and shadow service, otherwise will cause 'host not found in upstream' error
+	routerLabels := map[string]string{
+		util.KtRole:   util.RoleRouter,
+		util.KtTarget: util.RandomString(20),
+	}
+	annotations := map[string]string{
+		util.KtRefCount: "1",
+		util.KtConfig:   fmt.Sprintf("service=%s,partial=%s", svc.Name, shadowName),
+	}
+	if _, err = cluster.Ins().CreateRouterPod(routerPodName, opt.Get().Exchange.RouterImage,
+		routerLabels, annotations, ports); err != nil {
+		log.Error().Err(err).Msgf("Failed to create router pod")
+		return err
+	}
+	log.Info().Msgf("Router pod is ready")
+
+	stdout, stderr, err := cluster.Ins().ExecInPod(util.DefaultContainer, routerPodName, namespace,
+		util.RouterBin, "split", svc.Name, ToPortMapParameter(ports), shadowName, toExposedParameter(exposedPorts))
+	log.Debug().Msgf("Stdout: %s", stdout)
+	log.Debug().Msgf("Stderr: %s", stderr)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Router pod configuration done")
+
+	// Let target service select router pod
+	// Must after router pod created, otherwise request will be interrupted
+	if err = UpdateServiceSelector(svc.Name, namespace, routerLabels); err != nil {
+		return err
+	}
+	log.Info().Msgf("Ports %v of service %s redirected to shadow pod", exposedPorts, svc.Name)
+	return nil
+}
+
+func toExposedParameter(exposedPorts []int) string {
+	ports := make([]string, 0)
+	for _, p := range exposedPorts {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	return strings.Join(ports, ",")
+}
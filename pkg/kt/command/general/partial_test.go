@@ -0,0 +1,12 @@
+package general
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_toExposedParameter(t *testing.T) {
+	require.Equal(t, "", toExposedParameter([]int{}))
+	require.Equal(t, "8080", toExposedParameter([]int{8080}))
+	require.Equal(t, "8080,53", toExposedParameter([]int{8080, 53}))
+}
@@ -8,15 +8,15 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
-	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func CreateShadowAndInbound(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string, mirror transmission.MirrorConfig) error {
+func CreateShadowAndInbound(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string, clientIp transmission.ClientIpConfig, mirror transmission.MirrorConfig) error {
 
 	envs := make(map[string]string)
 	_, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, labels, annotations, envs, portsToExpose, portNameDict)
@@ -24,7 +24,8 @@ func CreateShadowAndInbound(shadowPodName, portsToExpose string, labels, annotat
 		return err
 	}
 
-	if _, err = transmission.ForwardPodToLocal(portsToExpose, podName, privateKeyPath, mirror); err != nil {
+	currentPod := WatchShadow(shadowPodName, podName, nil)
+	if _, err = transmission.ForwardPodToLocal(portsToExpose, currentPod, privateKeyPath, clientIp, mirror); err != nil {
 		return err
 	}
 	return nil
@@ -37,17 +38,6 @@ func GetServiceByResourceName(resourceName, namespace string) (*coreV1.Service,
 	}
 
 	switch resourceType {
-	case "deploy":
-		fallthrough
-	case "deployment":
-		app, err2 := cluster.Ins().GetDeployment(name, namespace)
-		if err2 != nil {
-			if k8sErrors.IsNotFound(err2) {
-				return nil, fmt.Errorf("deployment '%s' is not found in namespace %s", name, namespace)
-			}
-			return nil, err2
-		}
-		return getServiceByDeployment(app, namespace)
 	case "svc":
 		fallthrough
 	case "service":
@@ -57,25 +47,70 @@ func GetServiceByResourceName(resourceName, namespace string) (*coreV1.Service,
 		}
 		return svc, err2
 	default:
-		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+		workload, err2 := getWorkload(resourceType, name, namespace)
+		if err2 != nil {
+			return nil, err2
+		}
+		return getServiceByWorkload(workload, namespace)
 	}
 }
 
-func GetDeploymentByResourceName(resourceName, namespace string) (*appV1.Deployment, error) {
+// GetServicesByResourceName get all services of the resource, a workload could be selected by more than one service
+func GetServicesByResourceName(resourceName, namespace string) ([]*coreV1.Service, error) {
 	resourceType, name, err := ParseResourceName(resourceName)
 	if err != nil {
 		return nil, err
 	}
+	if toWorkloadKind(resourceType) == "" {
+		svc, err2 := GetServiceByResourceName(resourceName, namespace)
+		if err2 != nil {
+			return nil, err2
+		}
+		return []*coreV1.Service{svc}, nil
+	}
 
-	switch resourceType {
-	case "deploy":
-		fallthrough
-	case "deployment":
-		app, err2 := cluster.Ins().GetDeployment(name, namespace)
-		if err2 != nil && k8sErrors.IsNotFound(err2) {
-			return nil, fmt.Errorf("deployment '%s' is not found in namespace %s", name, namespace)
+	workload, err := getWorkload(resourceType, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	svcList, err := cluster.Ins().GetServicesBySelector(workload.Selector, namespace)
+	if err != nil {
+		return nil, err
+	}
+	svcs := make([]*coreV1.Service, 0)
+	svcNames := make([]string, 0)
+	for i := range svcList {
+		svc := &svcList[i]
+		if svc.Labels[util.ControlBy] == util.KubernetesToolkit {
+			if !strings.HasSuffix(svc.Name, util.StuntmanServiceSuffix) {
+				continue
+			}
+			// stuntman service stands for a meshed service
+			if svc, err = cluster.Ins().GetService(strings.TrimSuffix(svc.Name, util.StuntmanServiceSuffix), namespace); err != nil {
+				return nil, err
+			}
 		}
-		return app, err2
+		if !util.Contains(svcNames, svc.Name) {
+			svcNames = append(svcNames, svc.Name)
+			svcs = append(svcs, svc)
+		}
+	}
+	if len(svcs) == 0 {
+		return nil, fmt.Errorf("failed to find service for %s '%s', with labels '%v'",
+			workload.Kind, workload.Name, workload.Selector)
+	}
+	log.Info().Msgf("Found %d services of %s '%s': %s", len(svcs), workload.Kind, workload.Name, strings.Join(svcNames, ", "))
+	return svcs, nil
+}
+
+// GetWorkloadByResourceName get deployment, statefulset, daemonset or argo rollout by resource name
+func GetWorkloadByResourceName(resourceName, namespace string) (*cluster.Workload, error) {
+	resourceType, name, err := ParseResourceName(resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resourceType {
 	case "svc":
 		fallthrough
 	case "service":
@@ -86,9 +121,9 @@ func GetDeploymentByResourceName(resourceName, namespace string) (*appV1.Deploym
 			}
 			return nil, err2
 		}
-		return getDeploymentByService(svc, namespace)
+		return getWorkloadByService(svc, namespace)
 	default:
-		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+		return getWorkload(resourceType, name, namespace)
 	}
 }
 
@@ -133,11 +168,19 @@ func UpdateServiceSelector(svcName, namespace string, selector map[string]string
 		}
 	}
 
+	drainPeriod := getDrainPeriod()
 	if isServiceChanged(svc, selector, marshaledSelector) {
+		if drainPeriod > 0 {
+			if err = WaitPodsReadyToServe(selector, namespace, opt.Get().Global.PodCreationTimeout); err != nil {
+				return err
+			}
+		}
 		svc.Spec.Selector = selector
 		if _, err = cluster.Ins().UpdateService(svc); err != nil {
 			return err
 		}
+		// previous pods are left running, only wait for the switch
+		DrainPreviousEndpoints(svcName, namespace, selector, nil, drainPeriod)
 	}
 
 	go cluster.Ins().WatchService(svcName, namespace, nil, nil, func(newSvc *coreV1.Service) {
@@ -150,14 +193,15 @@ func UpdateServiceSelector(svcName, namespace string, selector map[string]string
 		} else if len(pods.Items) > 1 {
 			log.Warn().Msgf("More than one router pod selected")
 		}
-		if !isServiceChanged(newSvc, selector, marshaledSelector) {
+		if isFailedOver(svcName) || !isServiceChanged(newSvc, selector, marshaledSelector) {
 			return
 		}
 		log.Debug().Msgf("Change in service %s detected", svcName)
 		// delay and double check to avoid multiple clients conflict
 		time.Sleep(util.RandomSeconds(1, 10))
 		if svc, err = cluster.Ins().GetService(svcName, namespace); err == nil {
-			if isServiceChanged(svc, selector, marshaledSelector) {
+			// watchdog marks failed over only after the change is applied
+			if !isFailedOver(svcName) && isServiceChanged(svc, selector, marshaledSelector) {
 				svc.Spec.Selector = selector
 				svc.Annotations = util.MapPut(svc.Annotations, util.KtSelector, marshaledSelector)
 				if _, err = cluster.Ins().UpdateService(svc); err != nil {
@@ -199,17 +243,108 @@ func GetTargetPorts(svc *coreV1.Service) map[int]string {
 	return targetPorts
 }
 
+// GetHttpTargetPorts get target port numbers of services which serve http, according to app protocol or port name
+func GetHttpTargetPorts(svcs ...*coreV1.Service) []int {
+	httpPorts := make([]int, 0)
+	for _, svc := range svcs {
+		var portToNames map[int]string
+		for _, specPort := range svc.Spec.Ports {
+			if !isHttpServicePort(specPort) {
+				continue
+			}
+			if specPort.TargetPort.Type == intstr.Int {
+				httpPorts = append(httpPorts, specPort.TargetPort.IntValue())
+				continue
+			}
+			if portToNames == nil {
+				portToNames = GetTargetPorts(svc)
+			}
+			for p, n := range portToNames {
+				if n == specPort.TargetPort.StrVal {
+					httpPorts = append(httpPorts, p)
+				}
+			}
+		}
+	}
+	return httpPorts
+}
+
+// GetHttpTargetPortsOfWorkload get http target ports of the service which selects specified workload
+func GetHttpTargetPortsOfWorkload(workload *cluster.Workload, namespace string) []int {
+	svc, err := getServiceByWorkload(workload, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to find http ports of %s '%s'", workload.Kind, workload.Name)
+		return []int{}
+	}
+	return GetHttpTargetPorts(svc)
+}
+
+// GetServicePorts get map of service port to target port number
+func GetServicePorts(svc *coreV1.Service) (map[int]int, error) {
+	portToNames := GetTargetPorts(svc)
+	ports := make(map[int]int)
+	for _, specPort := range svc.Spec.Ports {
+		if specPort.TargetPort.Type == intstr.Int {
+			ports[int(specPort.Port)] = specPort.TargetPort.IntValue()
+		} else {
+			podPort := -1
+			for p, n := range portToNames {
+				if n == specPort.TargetPort.StrVal {
+					podPort = p
+					break
+				}
+			}
+			if podPort < 0 {
+				return nil, fmt.Errorf("cannot found port number of target port '%s' of service %s",
+					specPort.TargetPort.StrVal, svc.Name)
+			}
+			ports[int(specPort.Port)] = podPort
+		}
+	}
+	return ports, nil
+}
+
+// ToPortMapParameter convert port map to parameter of router command
+func ToPortMapParameter(ports map[int]int) string {
+	// input: { 80:8080, 70:7000 }
+	// output: "80:8080,70:7000"
+	if len(ports) == 0 {
+		return ""
+	}
+	s := ""
+	for k, v := range ports {
+		s = s + "," + strconv.Itoa(k) + ":" + strconv.Itoa(v)
+	}
+	return s[1:]
+}
+
+// isHttpServicePort check app protocol of service port, or port name if app protocol not specified,
+// following the "<protocol>[-<suffix>]" naming convention
+func isHttpServicePort(port coreV1.ServicePort) bool {
+	protocol := port.Name
+	if port.AppProtocol != nil && *port.AppProtocol != "" {
+		protocol = strings.TrimPrefix(*port.AppProtocol, "kubernetes.io/")
+	}
+	protocol = strings.ToLower(protocol)
+	for _, p := range []string{"http", "http2", "https", "grpc", "h2c", "ws", "wss"} {
+		if protocol == p || strings.HasPrefix(protocol, p+"-") {
+			return true
+		}
+	}
+	return false
+}
+
 func isServiceChanged(svc *coreV1.Service, selector map[string]string, marshaledSelector string) bool {
 	return !util.MapEquals(svc.Spec.Selector, selector) || svc.Annotations == nil || svc.Annotations[util.KtSelector] != marshaledSelector
 }
 
-func getServiceByDeployment(app *appV1.Deployment, namespace string) (*coreV1.Service, error) {
-	svcList, err := cluster.Ins().GetServicesBySelector(app.Spec.Selector.MatchLabels, namespace)
+func getServiceByWorkload(workload *cluster.Workload, namespace string) (*coreV1.Service, error) {
+	svcList, err := cluster.Ins().GetServicesBySelector(workload.Selector, namespace)
 	if err != nil {
 		return nil, err
 	} else if len(svcList) == 0 {
-		return nil, fmt.Errorf("failed to find service for deployment '%s', with labels '%v'",
-			app.Name, app.Spec.Selector.MatchLabels)
+		return nil, fmt.Errorf("failed to find service for %s '%s', with labels '%v'",
+			workload.Kind, workload.Name, workload.Selector)
 	} else if len(svcList) > 1 {
 		svcNames := svcList[0].Name
 		for i, svc := range svcList {
@@ -217,8 +352,8 @@ func getServiceByDeployment(app *appV1.Deployment, namespace string) (*coreV1.Se
 				svcNames = svcNames + ", " + svc.Name
 			}
 		}
-		log.Warn().Msgf("Found %d services match deployment '%s': %s. First one will be used.",
-			len(svcList), app.Name, svcNames)
+		log.Warn().Msgf("Found %d services match %s '%s': %s. First one will be used.",
+			len(svcList), workload.Kind, workload.Name, svcNames)
 	}
 	svc := svcList[0]
 	if strings.HasSuffix(svc.Name, util.StuntmanServiceSuffix) {
@@ -227,19 +362,46 @@ func getServiceByDeployment(app *appV1.Deployment, namespace string) (*coreV1.Se
 	return &svc, nil
 }
 
-func getDeploymentByService(svc *coreV1.Service, namespace string) (*appV1.Deployment, error) {
-	apps, err := cluster.Ins().GetAllDeploymentInNamespace(namespace)
+func getWorkloadByService(svc *coreV1.Service, namespace string) (*cluster.Workload, error) {
+	workloads, err := cluster.Ins().GetAllWorkloadsInNamespace(namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, app := range apps.Items {
-		if util.MapContains(svc.Spec.Selector, app.Spec.Template.Labels) {
-			log.Info().Msgf("Using first matched deployment '%s'", app.Name)
-			return &app, nil
+	for i, workload := range workloads {
+		if util.MapContains(svc.Spec.Selector, workload.TemplateLabels) {
+			log.Info().Msgf("Using first matched %s '%s'", workload.Kind, workload.Name)
+			return &workloads[i], nil
 		}
 	}
-	return nil, fmt.Errorf("failed to find deployment for service '%s', with selector '%v'", svc.Name, svc.Spec.Selector)
+	return nil, fmt.Errorf("failed to find workload for service '%s', with selector '%v'", svc.Name, svc.Spec.Selector)
+}
+
+func getWorkload(resourceType, name, namespace string) (*cluster.Workload, error) {
+	kind := toWorkloadKind(resourceType)
+	if kind == "" {
+		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+	}
+	workload, err := cluster.Ins().GetWorkload(kind, name, namespace)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%s '%s' is not found in namespace %s", kind, name, namespace)
+	}
+	return workload, err
+}
+
+func toWorkloadKind(resourceType string) string {
+	switch resourceType {
+	case "deploy", "deployment":
+		return cluster.KindDeployment
+	case "sts", "statefulset":
+		return cluster.KindStatefulSet
+	case "ds", "daemonset":
+		return cluster.KindDaemonSet
+	case "ro", "rollout":
+		return cluster.KindRollout
+	default:
+		return ""
+	}
 }
 
 func GetOccupiedUser(labels map[string]string) string {
@@ -0,0 +1,26 @@
+package general
+
+import (
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestToPortMapParameter(t *testing.T) {
+	require.Equal(t, ToPortMapParameter(map[int]int{ }), "", "port map parameter incorrect")
+	require.Equal(t, ToPortMapParameter(map[int]int{ 80:8080 }), "80:8080", "port map parameter incorrect")
+	res := ToPortMapParameter(map[int]int{ 80:8080, 70:7000 })
+	require.True(t, res == "80:8080,70:7000" || res == "70:7000,80:8080", "port map parameter incorrect")
+}
+
+func Test_isHttpServicePort(t *testing.T) {
+	h2c := "kubernetes.io/h2c"
+	tcp := "tcp"
+	require.True(t, isHttpServicePort(coreV1.ServicePort{Name: "http"}))
+	require.True(t, isHttpServicePort(coreV1.ServicePort{Name: "http-web"}))
+	require.True(t, isHttpServicePort(coreV1.ServicePort{Name: "grpc-api"}))
+	require.True(t, isHttpServicePort(coreV1.ServicePort{Name: "db", AppProtocol: &h2c}))
+	require.False(t, isHttpServicePort(coreV1.ServicePort{Name: "http", AppProtocol: &tcp}))
+	require.False(t, isHttpServicePort(coreV1.ServicePort{Name: "httpd"}))
+	require.False(t, isHttpServicePort(coreV1.ServicePort{Name: "mysql"}))
+}
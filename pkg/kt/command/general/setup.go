@@ -56,12 +56,20 @@ func SetupLogger() {
 
 // SetupProcess write pid file and set component type
 func SetupProcess(componentName string) (chan os.Signal, error) {
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGQUIT)
 	opt.Store.Component = componentName
 	return ch, util.WritePidFile(componentName, ch)
 }
 
+// runningAs check whether current process works as specified component, run command could work as several components
+func runningAs(componentName string) bool {
+	if opt.Store.Component == util.ComponentRun {
+		return util.Contains(strings.Split(opt.Store.RunAs, ","), componentName)
+	}
+	return opt.Store.Component == componentName
+}
+
 // combineKubeOpts set default options of kubectl if not assign
 func combineKubeOpts() (err error) {
 	var config *clientcmdapi.Config
@@ -130,6 +138,7 @@ func combineKubeOpts() (err error) {
 			break
 		}
 	}
+	opt.Store.KubeContext = config.CurrentContext
 	log.Info().Msgf("Using cluster context %s (%s)", config.CurrentContext, clusterName)
 
 	return nil
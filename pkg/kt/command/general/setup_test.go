@@ -0,0 +1,25 @@
+package general
+
+import (
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_runningAs(t *testing.T) {
+	defer func() {
+		opt.Store.Component = ""
+		opt.Store.RunAs = ""
+	}()
+	opt.Store.Component = util.ComponentExchange
+	require.True(t, runningAs(util.ComponentExchange))
+	require.False(t, runningAs(util.ComponentConnect))
+
+	opt.Store.Component = util.ComponentRun
+	opt.Store.RunAs = "connect,exchange"
+	require.True(t, runningAs(util.ComponentConnect))
+	require.True(t, runningAs(util.ComponentExchange))
+	require.False(t, runningAs(util.ComponentMesh))
+	require.False(t, runningAs(util.ComponentRun))
+}
@@ -0,0 +1,120 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sync"
+	"time"
+)
+
+// recreateLock avoid recreating the same shadow concurrently
+var recreateLock sync.Mutex
+
+type shadowWatcher struct {
+	shadowName  string
+	podName     string
+	onRecreated func(pod *coreV1.Pod)
+	// stop watching previous pod, which is replaced by the recreated one
+	stopWatch chan struct{}
+	lock      sync.RWMutex
+}
+
+// WatchShadow recreate shadow pod with the same ssh key and labels once it's deleted or evicted, so that services
+// selecting it keep working, returns a function to get name of the latest shadow pod
+func WatchShadow(shadowName, podName string, onRecreated func(pod *coreV1.Pod)) func() string {
+	w := &shadowWatcher{
+		shadowName:  shadowName,
+		podName:     podName,
+		onRecreated: onRecreated,
+	}
+	w.watch(podName)
+	return w.currentPod
+}
+
+func (w *shadowWatcher) currentPod() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.podName
+}
+
+func (w *shadowWatcher) watch(podName string) {
+	if w.stopWatch != nil {
+		close(w.stopWatch)
+	}
+	w.stopWatch = make(chan struct{})
+	var once sync.Once
+	onGone := func(pod *coreV1.Pod) {
+		if opt.Store.Terminating.Load() {
+			return
+		}
+		once.Do(func() {
+			go w.recreate(podName)
+		})
+	}
+	cluster.Ins().WatchPodUntil(podName, opt.Get().Global.Namespace, w.stopWatch, nil, onGone, func(pod *coreV1.Pod) {
+		if !IsPodAlive(pod) {
+			onGone(pod)
+		}
+	})
+}
+
+func (w *shadowWatcher) recreate(podName string) {
+	backoff := transmission.DefaultBackoff()
+	for attempt := 0; !opt.Store.Terminating.Load(); attempt++ {
+		pod, err := EnsureShadowAlive(w.shadowName, podName)
+		if err == nil {
+			w.lock.Lock()
+			w.podName = pod.Name
+			w.lock.Unlock()
+			if w.onRecreated != nil {
+				w.onRecreated(pod)
+			}
+			w.watch(pod.Name)
+			return
+		}
+		log.Warn().Err(err).Msgf("Failed to recreate shadow pod %s", podName)
+		if opt.Get().Global.MaxReconnect > 0 && attempt+1 >= opt.Get().Global.MaxReconnect {
+			log.Error().Msgf("Shadow pod %s is gone and cannot be recreated", podName)
+			return
+		}
+		time.Sleep(backoff.Delay(attempt))
+	}
+}
+
+// EnsureShadowAlive check whether shadow pod is deleted or evicted, and recreate it in that case, returns the shadow
+// pod currently available
+func EnsureShadowAlive(shadowName, podName string) (*coreV1.Pod, error) {
+	recreateLock.Lock()
+	defer recreateLock.Unlock()
+	if opt.Store.Terminating.Load() {
+		return nil, fmt.Errorf("process is terminating")
+	}
+	pod, err := cluster.Ins().GetPod(podName, opt.Get().Global.Namespace)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		// cluster not reachable, shadow pod may still alive
+		return nil, err
+	}
+	if err == nil && IsPodAlive(pod) {
+		return pod, nil
+	}
+	log.Warn().Msgf("Shadow pod %s is gone, recreating it", podName)
+	return cluster.Ins().RecreateShadow(shadowName)
+}
+
+// waitShadowRecreation wait for ongoing shadow recreation to finish, since the terminating flag is already set, no more
+// recreation would start afterwards, so that no shadow is created after workspace cleaned up
+func waitShadowRecreation() {
+	recreateLock.Lock()
+	defer recreateLock.Unlock()
+}
+
+// IsPodAlive check whether pod is neither terminating nor evicted
+func IsPodAlive(pod *coreV1.Pod) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != coreV1.PodFailed &&
+		pod.Status.Phase != coreV1.PodSucceeded
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/dns"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
@@ -19,21 +20,37 @@ import (
 // CleanupWorkspace clean workspace
 func CleanupWorkspace() {
 	log.Debug().Msgf("Cleaning workspace")
+	opt.Store.Terminating.Store(true)
 	cleanLocalFiles()
-	if opt.Store.Component == util.ComponentConnect {
+	if runningAs(util.ComponentConnect) {
 		recoverGlobalHostsAndProxy()
 	}
 
-	if opt.Store.Component == util.ComponentExchange {
+	if runningAs(util.ComponentExchange) {
 		recoverExchangedTarget()
-	} else if opt.Store.Component == util.ComponentMesh {
+	} else if runningAs(util.ComponentMesh) {
 		recoverAutoMeshRoute()
+		recoverIstioRules()
+		recoverHTTPRoutes()
 	}
 	cleanService()
+	waitShadowRecreation()
 	cleanShadowPodAndConfigMap()
 }
 
 func recoverGlobalHostsAndProxy() {
+	util.RemoveConnectSession()
+	control.RemoveSocket()
+	if opt.Get().Connect.Rootless {
+		// neither hosts, dns nor route is changed in rootless mode
+		_ = os.Remove(util.KtServicesEnvFile)
+		return
+	}
+	if opt.Get().Connect.DomainSuffix != "" {
+		// hosts and system dns are not touched when serving domain suffix only
+		dns.RestoreDomainNameServer(opt.Get().Connect.DomainSuffix)
+		return
+	}
 	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeHosts) ||
 		strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) {
 		log.Debug().Msg("Dropping hosts records ...")
@@ -59,6 +76,17 @@ func cleanLocalFiles() {
 		log.Info().Msgf("Removed pid file %s", pidFile)
 	}
 
+	if opt.Store.LocalEnv != "" {
+		RemoveLocalEnv(opt.Store.LocalEnv)
+	}
+
+	if opt.Store.Ephemeral != "" {
+		file := EphemeralKeyPath()
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Debug().Msgf("Remove key file %s failed", file)
+		}
+	}
+
 	if opt.Store.Shadow != "" {
 		for _, sshcm := range strings.Split(opt.Store.Shadow, ",") {
 			file := util.PrivateKeyPath(sshcm)
@@ -79,54 +107,94 @@ func recoverExchangedTarget() {
 		return
 	}
 	if opt.Get().Exchange.Mode == util.ExchangeModeScale {
-		log.Info().Msgf("Recovering origin deployment %s", opt.Store.Origin)
-		err := cluster.Ins().ScaleTo(opt.Store.Origin, opt.Get().Global.Namespace, &opt.Store.Replicas)
+		log.Info().Msgf("Recovering origin %s %s", opt.Store.OriginKind, opt.Store.Origin)
+		err := cluster.Ins().ScaleWorkload(opt.Store.OriginKind, opt.Store.Origin, opt.Get().Global.Namespace, opt.Store.Replicas)
 		if err != nil {
-			log.Error().Err(err).Msgf("Scale deployment %s to %d failed",
-				opt.Store.Origin, opt.Store.Replicas)
+			log.Error().Err(err).Msgf("Scale %s %s to %d failed",
+				opt.Store.OriginKind, opt.Store.Origin, opt.Store.Replicas)
+		}
+		if opt.Store.Autoscaler != "" {
+			if err = RestoreAutoscaler(opt.Store.Autoscaler); err != nil {
+				log.Error().Err(err).Msgf("Failed to restore horizontal pod autoscaler of %s %s",
+					opt.Store.OriginKind, opt.Store.Origin)
+			}
 		}
 		// wait for scale complete
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 		go func() {
-			waitDeploymentRecoverComplete()
+			waitWorkloadRecoverComplete()
 			ch <- os.Interrupt
 		}()
 		_ = <-ch
 	} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector {
+		if opt.Store.Router != "" {
+			// partial exchange split traffic via router pod, recover it the same way as auto mesh
+			recoverAutoMeshRoute()
+			return
+		}
 		RecoverOriginalService(opt.Store.Origin, opt.Get().Global.Namespace)
 		log.Info().Msgf("Original service %s recovered", opt.Store.Origin)
+	} else if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		for _, podName := range strings.Split(opt.Store.Ephemeral, ",") {
+			RecoverEphemeralExchangedPod(podName, opt.Get().Global.Namespace)
+		}
 	}
 }
 
 func recoverAutoMeshRoute() {
 	if opt.Store.Router != "" {
-		routerPod, err := cluster.Ins().GetPod(opt.Store.Router, opt.Get().Global.Namespace)
-		if err != nil {
-			log.Error().Err(err).Msgf("Router pod has been removed unexpectedly")
-			// in case of router pod gone, try recover origin service via runtime store
-			if opt.Store.Origin != "" {
-				recoverService(opt.Store.Origin)
-			}
-			return
+		for _, router := range strings.Split(opt.Store.Router, ",") {
+			recoverAutoMeshRouter(router)
 		}
-		if shouldDelRouter, err2 := cluster.Ins().DecreasePodRef(opt.Store.Router, opt.Get().Global.Namespace); err2 != nil {
-			log.Error().Err(err2).Msgf("Decrease router pod %s reference failed", opt.Store.Shadow)
-		} else if shouldDelRouter {
-			routerConfig := routerPod.Annotations[util.KtConfig]
-			config := util.String2Map(routerConfig)
-			recoverService(config["service"])
-			if err = cluster.Ins().RemovePod(opt.Store.Router, opt.Get().Global.Namespace); err != nil {
-				log.Warn().Err(err).Msgf("Failed to remove router pod")
-			}
-		} else {
-			stdout, stderr, err3 := cluster.Ins().ExecInPod(util.DefaultContainer, opt.Store.Router, opt.Get().Global.Namespace,
-				util.RouterBin, "remove", opt.Store.Mesh)
-			log.Debug().Msgf("Stdout: %s", stdout)
-			log.Debug().Msgf("Stderr: %s", stderr)
-			if err3 != nil {
-				log.Warn().Err(err3).Msgf("Failed to remove version %s from router pod", opt.Store.Mesh)
-			}
+	}
+}
+
+func recoverIstioRules() {
+	if opt.Store.Istio != "" {
+		meshVersion := opt.Store.Mesh[strings.Index(opt.Store.Mesh, ":")+1:]
+		for _, svcName := range strings.Split(opt.Store.Istio, ",") {
+			RemoveIstioRules(svcName, opt.Get().Global.Namespace, meshVersion)
+		}
+	}
+}
+
+func recoverHTTPRoutes() {
+	if opt.Store.Gateway != "" {
+		meshVersion := opt.Store.Mesh[strings.Index(opt.Store.Mesh, ":")+1:]
+		for _, svcName := range strings.Split(opt.Store.Gateway, ",") {
+			RemoveHTTPRouteRules(svcName, opt.Get().Global.Namespace, svcName+util.MeshPodInfix+meshVersion)
+		}
+	}
+}
+
+func recoverAutoMeshRouter(routerName string) {
+	routerPod, err := cluster.Ins().GetPod(routerName, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Error().Err(err).Msgf("Router pod has been removed unexpectedly")
+		// in case of router pod gone, try recover origin service via runtime store
+		originSvcName := strings.TrimSuffix(routerName, util.RouterPodSuffix)
+		if util.Contains(strings.Split(opt.Store.Origin, ","), originSvcName) {
+			recoverService(originSvcName)
+		}
+		return
+	}
+	if shouldDelRouter, err2 := cluster.Ins().DecreasePodRef(routerName, opt.Get().Global.Namespace); err2 != nil {
+		log.Error().Err(err2).Msgf("Decrease router pod %s reference failed", routerName)
+	} else if shouldDelRouter {
+		routerConfig := routerPod.Annotations[util.KtConfig]
+		config := util.String2Map(routerConfig)
+		recoverService(config["service"])
+		if err = cluster.Ins().RemovePod(routerName, opt.Get().Global.Namespace); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove router pod")
+		}
+	} else {
+		stdout, stderr, err3 := cluster.Ins().ExecInPod(util.DefaultContainer, routerName, opt.Get().Global.Namespace,
+			util.RouterBin, "remove", opt.Store.Mesh)
+		log.Debug().Msgf("Stdout: %s", stdout)
+		log.Debug().Msgf("Stderr: %s", stderr)
+		if err3 != nil {
+			log.Warn().Err(err3).Msgf("Failed to remove version %s from router pod", opt.Store.Mesh)
 		}
 	}
 }
@@ -161,41 +229,53 @@ func RecoverOriginalService(svcName, namespace string) {
 			log.Error().Err(err).Msgf("Failed to unmarshal original selector of service %s", svcName)
 			return
 		}
+		drainPeriod := getDrainPeriod()
+		if drainPeriod > 0 {
+			if err = WaitPodsReadyToServe(selector, namespace, opt.Get().Global.PodCreationTimeout); err != nil {
+				log.Warn().Err(err).Msgf("Original pods of service %s not ready", svcName)
+			}
+		}
+		// pods selected before are removed after recovered
+		previousSelector := svc.Spec.Selector
 		svc.Spec.Selector = selector
 		delete(svc.Annotations, util.KtSelector)
 		if _, err = cluster.Ins().UpdateService(svc); err != nil {
 			log.Error().Err(err).Msgf("Failed to recover selector of original service %s", svcName)
+			return
 		}
+		DrainPreviousEndpoints(svcName, namespace, selector, previousSelector, drainPeriod)
 	}
 }
 
-func waitDeploymentRecoverComplete() {
+func waitWorkloadRecoverComplete() {
 	ok := false
 	counts := opt.Get().Exchange.RecoverWaitTime / 5
 	for i := 0; i < counts; i++ {
-		deployment, err := cluster.Ins().GetDeployment(opt.Store.Origin, opt.Get().Global.Namespace)
+		workload, err := cluster.Ins().GetWorkload(opt.Store.OriginKind, opt.Store.Origin, opt.Get().Global.Namespace)
 		if err != nil {
-			log.Error().Err(err).Msgf("Cannot fetch original deployment %s", opt.Store.Origin)
+			log.Error().Err(err).Msgf("Cannot fetch original %s %s", opt.Store.OriginKind, opt.Store.Origin)
 			break
-		} else if deployment.Status.ReadyReplicas == opt.Store.Replicas {
+		} else if workload.ReadyReplicas == opt.Store.Replicas {
 			ok = true
 			break
 		} else {
-			log.Info().Msgf("Wait for deployment %s recover ...", opt.Store.Origin)
+			log.Info().Msgf("Wait for %s %s recover ...", opt.Store.OriginKind, opt.Store.Origin)
 			time.Sleep(5 * time.Second)
 		}
 	}
 	if !ok {
-		log.Warn().Msgf("Deployment %s recover timeout", opt.Store.Origin)
+		log.Warn().Msgf("%s %s recover timeout", opt.Store.OriginKind, opt.Store.Origin)
 	}
 }
 
 func cleanService() {
 	if opt.Store.Service != "" {
-		log.Info().Msgf("Cleaning service %s", opt.Store.Service)
-		err := cluster.Ins().RemoveService(opt.Store.Service, opt.Get().Global.Namespace)
-		if err != nil {
-			log.Error().Err(err).Msgf("Delete service %s failed", opt.Store.Service)
+		for _, svc := range strings.Split(opt.Store.Service, ",") {
+			log.Info().Msgf("Cleaning service %s", svc)
+			err := cluster.Ins().RemoveService(svc, opt.Get().Global.Namespace)
+			if err != nil {
+				log.Error().Err(err).Msgf("Delete service %s failed", svc)
+			}
 		}
 	}
 }
@@ -203,20 +283,20 @@ func cleanService() {
 func cleanShadowPodAndConfigMap() {
 	var err error
 	if opt.Store.Shadow != "" {
-		shouldDelWithShared := false
-		if opt.Get().Connect.ShareShadow {
-			// There is always exactly one shadow pod or deployment for connect
-			if opt.Get().Global.UseShadowDeployment {
-				shouldDelWithShared, err = cluster.Ins().DecreaseDeploymentRef(opt.Store.Shadow, opt.Get().Global.Namespace)
-			} else {
-				shouldDelWithShared, err = cluster.Ins().DecreasePodRef(opt.Store.Shadow, opt.Get().Global.Namespace)
-			}
-			if err != nil {
-				log.Error().Err(err).Msgf("Decrease shadow daemon %s ref count failed", opt.Store.Shadow)
+		for _, shadow := range strings.Split(opt.Store.Shadow, ",") {
+			shouldDelWithShared := false
+			if opt.Get().Connect.ShareShadow {
+				// Each shared shadow replica is reference counted separately
+				if opt.Get().Global.UseShadowDeployment {
+					shouldDelWithShared, err = cluster.Ins().DecreaseDeploymentRef(shadow, opt.Get().Global.Namespace)
+				} else {
+					shouldDelWithShared, err = cluster.Ins().DecreasePodRef(shadow, opt.Get().Global.Namespace)
+				}
+				if err != nil {
+					log.Error().Err(err).Msgf("Decrease shadow daemon %s ref count failed", shadow)
+				}
 			}
-		}
-		if shouldDelWithShared || !opt.Get().Connect.ShareShadow {
-			for _, shadow := range strings.Split(opt.Store.Shadow, ",") {
+			if shouldDelWithShared || !opt.Get().Connect.ShareShadow {
 				log.Info().Msgf("Cleaning configmap %s", shadow)
 				err = cluster.Ins().RemoveConfigMap(shadow, opt.Get().Global.Namespace)
 				if err != nil {
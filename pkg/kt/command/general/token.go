@@ -0,0 +1,177 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	serviceAccountTokenPath   = "var/run/secrets/kubernetes.io/serviceaccount"
+	serviceAccountTokenExpiry = 3600
+	// kubernetes requires token to be valid for at least 10 minutes
+	minTokenExpiry     = 600
+	tokenRetryInterval = 10
+)
+
+// tokenProjection a service account token file to keep fresh
+type tokenProjection struct {
+	serviceAccount string
+	namespace      string
+	audiences      []string
+	expiry         int64
+	file           string
+}
+
+// ProjectServiceAccountToken request token of service account used by workload container, write it to local directory
+// in the same layout as kubernetes mounts it into pod, and keep refreshing it before expired
+func ProjectServiceAccountToken(workload *cluster.Workload, containerName, dir string) error {
+	spec := &workload.Template.Spec
+	projections := getTokenProjections(workload, findContainer(spec.Containers, containerName), dir)
+	if len(projections) == 0 {
+		log.Debug().Msgf("Service account token is not mounted by %s '%s'", workload.Kind, workload.Name)
+		return nil
+	}
+	caData, err := getClusterCaData()
+	if err != nil {
+		return err
+	}
+
+	opt.Store.LocalEnv = dir
+	for _, p := range projections {
+		expireAt, err2 := p.refresh()
+		if err2 != nil {
+			return fmt.Errorf("failed to request token of service account %s: %s", p.serviceAccount, err2)
+		}
+		log.Info().Msgf("Token of service account %s written to %s", p.serviceAccount, p.file)
+		go p.keepFresh(expireAt)
+	}
+	if automountToken(spec) {
+		tokenDir := filepath.Join(dir, filepath.FromSlash(serviceAccountTokenPath))
+		for name, content := range map[string][]byte{"ca.crt": caData, "namespace": []byte(workload.Namespace)} {
+			if err = writeLocalEnvFile(filepath.Join(tokenDir, name), content); err != nil {
+				return err
+			}
+			_ = util.FixFileOwner(filepath.Join(tokenDir, name))
+		}
+	}
+	return nil
+}
+
+// getTokenProjections get default service account token and tokens projected to volumes mounted by container
+func getTokenProjections(workload *cluster.Workload, container *coreV1.Container, dir string) []*tokenProjection {
+	spec := &workload.Template.Spec
+	saName := spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	projections := make([]*tokenProjection, 0)
+	if automountToken(spec) {
+		projections = append(projections, &tokenProjection{
+			serviceAccount: saName,
+			namespace:      workload.Namespace,
+			expiry:         serviceAccountTokenExpiry,
+			file:           filepath.Join(dir, filepath.FromSlash(serviceAccountTokenPath), "token"),
+		})
+	}
+	if container == nil {
+		return projections
+	}
+	// e.g. token for aws irsa or gcp workload identity, which is requested with specified audience
+	for _, mount := range container.VolumeMounts {
+		volume := findVolume(spec.Volumes, mount.Name)
+		if volume == nil || volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken == nil {
+				continue
+			}
+			target := toMountedPath(mount.MountPath, mount.SubPath, source.ServiceAccountToken.Path)
+			if target == "" {
+				continue
+			}
+			expiry := int64(serviceAccountTokenExpiry)
+			if source.ServiceAccountToken.ExpirationSeconds != nil {
+				expiry = max(*source.ServiceAccountToken.ExpirationSeconds, minTokenExpiry)
+			}
+			audiences := make([]string, 0)
+			if source.ServiceAccountToken.Audience != "" {
+				audiences = append(audiences, source.ServiceAccountToken.Audience)
+			}
+			projections = append(projections, &tokenProjection{
+				serviceAccount: saName,
+				namespace:      workload.Namespace,
+				audiences:      audiences,
+				expiry:         expiry,
+				file:           filepath.Join(dir, filepath.FromSlash(target)),
+			})
+		}
+	}
+	return projections
+}
+
+// refresh request a new token and write it to file, return expiration time of the token
+func (p *tokenProjection) refresh() (time.Time, error) {
+	tokenRequest, err := cluster.Ins().CreateServiceAccountToken(p.serviceAccount, p.namespace, p.audiences, p.expiry)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// write to temporary file first, so that application never reads a partial token
+	tmpFile := p.file + ".tmp"
+	if err = writeLocalEnvFile(tmpFile, []byte(tokenRequest.Status.Token)); err != nil {
+		return time.Time{}, err
+	}
+	_ = util.FixFileOwner(tmpFile)
+	if err = os.Rename(tmpFile, p.file); err != nil {
+		return time.Time{}, err
+	}
+	recordLocalEnvFile(p.file)
+	return tokenRequest.Status.ExpirationTimestamp.Time, nil
+}
+
+// keepFresh refresh token after 80% of its lifetime passed, as kubelet does
+func (p *tokenProjection) keepFresh(expireAt time.Time) {
+	for {
+		time.Sleep(getTokenRefreshDelay(time.Now(), expireAt))
+		if _, err := os.Stat(filepath.Dir(p.file)); err != nil {
+			log.Debug().Msgf("Token directory of %s removed, stop refreshing", p.file)
+			return
+		}
+		if newExpireAt, err := p.refresh(); err != nil {
+			log.Warn().Err(err).Msgf("Failed to refresh token of service account %s", p.serviceAccount)
+		} else {
+			log.Debug().Msgf("Token %s refreshed, expires at %s", p.file, newExpireAt.Format(time.RFC3339))
+			expireAt = newExpireAt
+		}
+	}
+}
+
+func getTokenRefreshDelay(now, expireAt time.Time) time.Duration {
+	delay := expireAt.Sub(now) * 4 / 5
+	if delay < tokenRetryInterval*time.Second {
+		return tokenRetryInterval * time.Second
+	}
+	return delay
+}
+
+func automountToken(spec *coreV1.PodSpec) bool {
+	return spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken
+}
+
+func getClusterCaData() ([]byte, error) {
+	if opt.Store.RestConfig == nil {
+		return nil, nil
+	} else if len(opt.Store.RestConfig.CAData) > 0 {
+		return opt.Store.RestConfig.CAData, nil
+	} else if opt.Store.RestConfig.CAFile != "" {
+		return os.ReadFile(opt.Store.RestConfig.CAFile)
+	}
+	return nil, nil
+}
@@ -0,0 +1,53 @@
+package general
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_getTokenProjections(t *testing.T) {
+	expiry := int64(300)
+	workload := &cluster.Workload{
+		Name:      "tomcat",
+		Namespace: "default",
+		Template: coreV1.PodTemplateSpec{
+			Spec: coreV1.PodSpec{
+				ServiceAccountName: "tomcat-sa",
+				Volumes: []coreV1.Volume{{
+					Name: "aws-iam-token",
+					VolumeSource: coreV1.VolumeSource{Projected: &coreV1.ProjectedVolumeSource{
+						Sources: []coreV1.VolumeProjection{{ServiceAccountToken: &coreV1.ServiceAccountTokenProjection{
+							Audience: "sts.amazonaws.com", ExpirationSeconds: &expiry, Path: "token",
+						}}},
+					}},
+				}},
+			},
+		},
+	}
+	container := &coreV1.Container{VolumeMounts: []coreV1.VolumeMount{
+		{Name: "aws-iam-token", MountPath: "/var/run/secrets/eks.amazonaws.com/serviceaccount"},
+	}}
+
+	projections := getTokenProjections(workload, container, "env")
+	require.Equal(t, 2, len(projections))
+	require.Equal(t, filepath.Join("env", "var", "run", "secrets", "kubernetes.io", "serviceaccount", "token"), projections[0].file)
+	require.Empty(t, projections[0].audiences)
+	require.Equal(t, "tomcat-sa", projections[1].serviceAccount)
+	require.Equal(t, []string{"sts.amazonaws.com"}, projections[1].audiences)
+	require.Equal(t, int64(minTokenExpiry), projections[1].expiry)
+	require.Equal(t, filepath.Join("env", "var", "run", "secrets", "eks.amazonaws.com", "serviceaccount", "token"), projections[1].file)
+
+	automount := false
+	workload.Template.Spec.AutomountServiceAccountToken = &automount
+	require.Equal(t, 1, len(getTokenProjections(workload, container, "env")))
+}
+
+func Test_getTokenRefreshDelay(t *testing.T) {
+	now := time.Now()
+	require.Equal(t, 48*time.Minute, getTokenRefreshDelay(now, now.Add(time.Hour)))
+	require.Equal(t, tokenRetryInterval*time.Second, getTokenRefreshDelay(now, now.Add(-time.Minute)))
+}
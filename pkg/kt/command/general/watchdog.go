@@ -0,0 +1,128 @@
+package general
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+const (
+	watchdogKeep = iota
+	watchdogFailover
+	watchdogFailback
+)
+
+// services temporarily pointing back to original pods, which should not be recovered by selector watcher
+var failedOverServices sync.Map
+
+type watchdog struct {
+	svcName    string
+	threshold  int
+	failures   int
+	failedOver bool
+	// switchTo let service select pods of selector, or original pods if selector is nil,
+	// returns false if service is no longer exchanged
+	switchTo func(selector map[string]string) (bool, error)
+}
+
+// next calculate action to take according to latest probe result, action is taken again on next probe if it failed
+func (w *watchdog) next(healthy bool) int {
+	if healthy {
+		w.failures = 0
+		if w.failedOver {
+			return watchdogFailback
+		}
+		return watchdogKeep
+	}
+	w.failures++
+	if !w.failedOver && w.failures >= w.threshold {
+		return watchdogFailover
+	}
+	return watchdogKeep
+}
+
+// probe handle latest probe result, returns false if service is no longer exchanged
+func (w *watchdog) probe(brokenPort string, shadowLabels map[string]string) bool {
+	action := w.next(brokenPort == "")
+	var selector map[string]string
+	switch action {
+	case watchdogFailover:
+		log.Warn().Msgf("Local port %s unreachable for %d times, routing service %s back to original pods",
+			brokenPort, w.failures, w.svcName)
+	case watchdogFailback:
+		log.Info().Msgf("Local ports recovered, routing service %s to local again", w.svcName)
+		selector = shadowLabels
+	default:
+		if brokenPort != "" {
+			log.Debug().Msgf("Local port %s unreachable (%d/%d)", brokenPort, w.failures, w.threshold)
+		}
+		return true
+	}
+	exchanged, err := w.switchTo(selector)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to switch selector of service %s, will retry", w.svcName)
+		return true
+	} else if !exchanged {
+		log.Debug().Msgf("Service %s already recovered, stop watchdog", w.svcName)
+		return false
+	}
+	w.failedOver = action == watchdogFailover
+	if w.failedOver {
+		failedOverServices.Store(w.svcName, true)
+	} else {
+		failedOverServices.Delete(w.svcName)
+	}
+	return true
+}
+
+// StartExchangeWatchdog probe local ports periodically, let service select original pods when local application is down,
+// and switch it back to shadow pod after local application recovered
+func StartExchangeWatchdog(svcName, namespace string, shadowLabels map[string]string, exposePorts string, threshold int) {
+	const interval = 3
+	w := &watchdog{
+		svcName:   svcName,
+		threshold: threshold,
+		switchTo: func(selector map[string]string) (bool, error) {
+			return switchExchangedService(svcName, namespace, selector)
+		},
+	}
+	go func() {
+		for {
+			time.Sleep(interval * time.Second)
+			if !w.probe(util.FindBrokenLocalPort(exposePorts), shadowLabels) {
+				return
+			}
+		}
+	}()
+}
+
+// switchExchangedService let service select shadow pod, or original pods if selector is nil,
+// return false if service is no longer exchanged
+func switchExchangedService(svcName, namespace string, selector map[string]string) (bool, error) {
+	svc, err := cluster.Ins().GetService(svcName, namespace)
+	if err != nil {
+		return true, err
+	}
+	originSelector := svc.Annotations[util.KtSelector]
+	if originSelector == "" {
+		return false, nil
+	}
+	if selector == nil {
+		if err = json.Unmarshal([]byte(originSelector), &selector); err != nil {
+			return true, fmt.Errorf("invalid %s annotation: %s", util.KtSelector, err)
+		}
+	}
+	// origin selector annotation is kept, so that service can still be recovered by teardown or clean
+	svc.Spec.Selector = selector
+	_, err = cluster.Ins().UpdateService(svc)
+	return true, err
+}
+
+func isFailedOver(svcName string) bool {
+	_, exists := failedOverServices.Load(svcName)
+	return exists
+}
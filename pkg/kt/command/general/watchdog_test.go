@@ -0,0 +1,57 @@
+package general
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_watchdogNext(t *testing.T) {
+	w := &watchdog{threshold: 3}
+	require.Equal(t, watchdogKeep, w.next(false))
+	require.Equal(t, watchdogKeep, w.next(true))
+	require.Equal(t, watchdogKeep, w.next(false))
+	require.Equal(t, watchdogKeep, w.next(false))
+	require.Equal(t, watchdogFailover, w.next(false))
+	// failover not applied yet, should try again
+	require.Equal(t, watchdogFailover, w.next(false))
+	w.failedOver = true
+	require.Equal(t, watchdogKeep, w.next(false))
+	require.Equal(t, watchdogFailback, w.next(true))
+	// failback not applied yet, should try again
+	require.Equal(t, watchdogFailback, w.next(true))
+	w.failedOver = false
+	require.Equal(t, watchdogKeep, w.next(true))
+}
+
+func Test_watchdogProbeSwitchFailed(t *testing.T) {
+	switchErr := fmt.Errorf("conflict")
+	selectors := make([]map[string]string, 0)
+	w := &watchdog{
+		svcName:   "tomcat",
+		threshold: 1,
+		switchTo: func(selector map[string]string) (bool, error) {
+			selectors = append(selectors, selector)
+			return true, switchErr
+		},
+	}
+	shadowLabels := map[string]string{"kt-role": "shadow"}
+
+	require.True(t, w.probe("8080", shadowLabels))
+	require.False(t, w.failedOver)
+	require.False(t, isFailedOver("tomcat"))
+	switchErr = nil
+	require.True(t, w.probe("8080", shadowLabels))
+	require.True(t, w.failedOver)
+	require.True(t, isFailedOver("tomcat"))
+	require.Equal(t, []map[string]string{nil, nil}, selectors)
+
+	switchErr = fmt.Errorf("timeout")
+	require.True(t, w.probe("", shadowLabels))
+	require.True(t, isFailedOver("tomcat"))
+	switchErr = nil
+	require.True(t, w.probe("", shadowLabels))
+	require.False(t, w.failedOver)
+	require.False(t, isFailedOver("tomcat"))
+	require.Equal(t, shadowLabels, selectors[3])
+}
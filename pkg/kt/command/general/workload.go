@@ -0,0 +1,113 @@
+package general
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/rs/zerolog/log"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+// labels or annotations marked by gitops controllers on resources they manage
+var gitOpsMarks = map[string]string{
+	"argocd.argoproj.io/tracking-id":   "Argo CD",
+	"argocd.argoproj.io/instance":      "Argo CD",
+	"kustomize.toolkit.fluxcd.io/name": "Flux",
+	"helm.toolkit.fluxcd.io/name":      "Flux",
+}
+
+// GetAutoscalerOfWorkload get horizontal pod autoscaler targeting specified workload, return nil if not exist
+// or not accessible (e.g. autoscaling/v2 unavailable, or lack of permission)
+func GetAutoscalerOfWorkload(workload *cluster.Workload) *autoscalingV2.HorizontalPodAutoscaler {
+	hpaList, err := cluster.Ins().GetHorizontalPodAutoscalers(workload.Namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Unable to list horizontal pod autoscalers, skip checking autoscaler of %s %s",
+			workload.Kind, workload.Name)
+		return nil
+	}
+	for i, hpa := range hpaList {
+		if isAutoscalerOf(&hpa, workload) {
+			return &hpaList[i]
+		}
+	}
+	return nil
+}
+
+// EncodeAutoscaler encode spec and metadata of hpa into a string which can be put into kt-config annotation
+func EncodeAutoscaler(hpa *autoscalingV2.HorizontalPodAutoscaler) (string, error) {
+	origin := autoscalingV2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        hpa.Name,
+			Namespace:   hpa.Namespace,
+			Labels:      hpa.Labels,
+			Annotations: hpa.Annotations,
+		},
+		Spec: hpa.Spec,
+	}
+	rawHpa, err := json.Marshal(origin)
+	if err != nil {
+		return "", err
+	}
+	// base64 encoded to avoid ',' conflicting with config items separator
+	return base64.StdEncoding.EncodeToString(rawHpa), nil
+}
+
+// DecodeAutoscaler decode hpa from string generated by EncodeAutoscaler
+func DecodeAutoscaler(encoded string) (*autoscalingV2.HorizontalPodAutoscaler, error) {
+	rawHpa, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var hpa autoscalingV2.HorizontalPodAutoscaler
+	if err = json.Unmarshal(rawHpa, &hpa); err != nil {
+		return nil, err
+	}
+	return &hpa, nil
+}
+
+// SetAsideAutoscaler pin replicas range of hpa to minimum during exchange, autoscaler stays passive while workload
+// has zero replica, and would not scale it far if anything else scales the workload back
+func SetAsideAutoscaler(hpa *autoscalingV2.HorizontalPodAutoscaler) error {
+	minReplicas := int32(1)
+	log.Info().Msgf("Pinning replicas of horizontal pod autoscaler %s to %d temporarily", hpa.Name, minReplicas)
+	return cluster.Ins().PatchHorizontalPodAutoscalerRange(hpa.Name, hpa.Namespace, &minReplicas, minReplicas)
+}
+
+// RestoreAutoscaler patch replicas range of hpa which was set aside during exchange back to origin
+func RestoreAutoscaler(encoded string) error {
+	hpa, err := DecodeAutoscaler(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid horizontal pod autoscaler record: %s", err)
+	}
+	if err = cluster.Ins().PatchHorizontalPodAutoscalerRange(hpa.Name, hpa.Namespace,
+		hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			log.Warn().Msgf("Horizontal pod autoscaler %s no longer exists", hpa.Name)
+			return nil
+		}
+		return err
+	}
+	log.Info().Msgf("Horizontal pod autoscaler %s restored", hpa.Name)
+	return nil
+}
+
+// GetGitOpsController get name of gitops controller which manages specified workload, return empty if not managed
+func GetGitOpsController(workload *cluster.Workload) string {
+	for mark, controller := range gitOpsMarks {
+		if _, exists := workload.Labels[mark]; exists {
+			return controller
+		}
+		if _, exists := workload.Annotations[mark]; exists {
+			return controller
+		}
+	}
+	return ""
+}
+
+func isAutoscalerOf(hpa *autoscalingV2.HorizontalPodAutoscaler, workload *cluster.Workload) bool {
+	return strings.ToLower(hpa.Spec.ScaleTargetRef.Kind) == workload.Kind && hpa.Spec.ScaleTargetRef.Name == workload.Name
+}
@@ -0,0 +1,60 @@
+package general
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
+	"github.com/stretchr/testify/require"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func Test_encodeAndDecodeAutoscaler(t *testing.T) {
+	minReplicas := int32(2)
+	hpa := &autoscalingV2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "tomcat",
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "tomcat"},
+			ResourceVersion: "12345",
+		},
+		Spec: autoscalingV2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingV2.CrossVersionObjectReference{Kind: "Deployment", Name: "tomcat", APIVersion: "apps/v1"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    5,
+		},
+	}
+	encoded, err := EncodeAutoscaler(hpa)
+	require.NoError(t, err)
+	require.False(t, strings.Contains(encoded, ","))
+
+	decoded, err := DecodeAutoscaler(encoded)
+	require.NoError(t, err)
+	require.Equal(t, hpa.Name, decoded.Name)
+	require.Equal(t, hpa.Labels, decoded.Labels)
+	require.Equal(t, hpa.Spec, decoded.Spec)
+	require.Empty(t, decoded.ResourceVersion)
+}
+
+func Test_isAutoscalerOf(t *testing.T) {
+	hpa := &autoscalingV2.HorizontalPodAutoscaler{
+		Spec: autoscalingV2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingV2.CrossVersionObjectReference{Kind: "StatefulSet", Name: "db"},
+		},
+	}
+	require.True(t, isAutoscalerOf(hpa, &cluster.Workload{Kind: cluster.KindStatefulSet, Name: "db"}))
+	require.False(t, isAutoscalerOf(hpa, &cluster.Workload{Kind: cluster.KindDeployment, Name: "db"}))
+	require.False(t, isAutoscalerOf(hpa, &cluster.Workload{Kind: cluster.KindStatefulSet, Name: "redis"}))
+}
+
+func Test_getGitOpsController(t *testing.T) {
+	require.Equal(t, "Argo CD", GetGitOpsController(&cluster.Workload{
+		Annotations: map[string]string{"argocd.argoproj.io/tracking-id": "demo:apps/Deployment:default/tomcat"},
+	}))
+	require.Equal(t, "Flux", GetGitOpsController(&cluster.Workload{
+		Labels: map[string]string{"kustomize.toolkit.fluxcd.io/name": "demo"},
+	}))
+	require.Equal(t, "", GetGitOpsController(&cluster.Workload{
+		Labels: map[string]string{"app": "tomcat"},
+	}))
+}
@@ -5,9 +5,11 @@ import (
 	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
 	"github.com/gitlayzer/kt-connect/pkg/kt/command/mesh"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	coreV1 "k8s.io/api/core/v1"
 	"strings"
 )
 
@@ -19,15 +21,13 @@ func NewMeshCommand() *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("name of service to mesh is required")
-			} else if len(args) > 1 {
-				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ",") )
 			}
 			return general.Prepare()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return Mesh(args[0])
+			return Mesh(args)
 		},
-		Example: "ktctl mesh <service-name> [command options]",
+		Example: "ktctl mesh <service-name> [<service-name> ...] [command options]",
 	}
 
 	cmd.SetUsageTemplate(general.UsageTemplate(true))
@@ -36,44 +36,97 @@ func NewMeshCommand() *cobra.Command {
 }
 
 //Mesh exchange kubernetes workload
-func Mesh(resourceName string) error {
+func Mesh(resourceNames []string) error {
+	if err := checkMeshOptions(); err != nil {
+		return err
+	}
+
 	ch, err := general.SetupProcess(util.ComponentMesh)
 	if err != nil {
 		return err
 	}
 
+	if err = meshResources(resourceNames); err != nil {
+		return err
+	}
+
+	// watch background process, clean the workspace and exit if background process occur exception
+	s := <-ch
+	log.Info().Msgf("Terminal Signal is %s", s)
+	return nil
+}
+
+func checkMeshOptions() error {
+	return transmission.CheckClientIpHeader(opt.Get().Mesh.ClientIpHeader)
+}
+
+func meshResources(resourceNames []string) error {
 	if opt.Get().Mesh.SkipPortChecking {
 		if port := util.FindBrokenLocalPort(opt.Get().Mesh.Expose); port != "" {
 			return fmt.Errorf("no application is running on port %s", port)
 		}
 	}
 
-  // Get service to mesh
-	svc, err := general.GetServiceByResourceName(resourceName, opt.Get().Global.Namespace)
+	// Get services to mesh
+	svcs, err := getServicesToMesh(resourceNames)
 	if err != nil {
 		return err
 	}
 
-	if port := util.FindInvalidRemotePort(opt.Get().Mesh.Expose, general.GetTargetPorts(svc)); port != "" {
-		return fmt.Errorf("target port %s not exists in service %s", port, svc.Name)
+	targetPorts := map[int]string{}
+	for _, svc := range svcs {
+		for port, name := range general.GetTargetPorts(svc) {
+			targetPorts[port] = name
+		}
+	}
+	if port := util.FindInvalidRemotePort(opt.Get().Mesh.Expose, targetPorts); port != "" {
+		return fmt.Errorf("target port %s not exists in service %s", port, serviceNames(svcs))
+	}
+	// other modes route traffic by http header
+	if len(util.FindUdpExposePorts(opt.Get().Mesh.Expose)) > 0 && opt.Get().Mesh.Mode != util.MeshModeManual {
+		return fmt.Errorf("udp port is only supported in %s mode", util.MeshModeManual)
 	}
 
 	log.Info().Msgf("Using %s mode", opt.Get().Mesh.Mode)
 	if opt.Get().Mesh.Mode == util.MeshModeManual {
-		err = mesh.ManualMesh(svc)
+		err = mesh.ManualMesh(svcs)
 	} else if opt.Get().Mesh.Mode == util.MeshModeAuto {
-		err = mesh.AutoMesh(svc)
+		err = mesh.AutoMesh(svcs)
+	} else if opt.Get().Mesh.Mode == util.MeshModeIstio {
+		err = mesh.IstioMesh(svcs)
+	} else if opt.Get().Mesh.Mode == util.MeshModeGateway {
+		err = mesh.GatewayMesh(svcs)
 	} else {
-		err = fmt.Errorf("invalid mesh method '%s', supportted are %s, %s", opt.Get().Mesh.Mode,
-			util.MeshModeAuto, util.MeshModeManual)
-	}
-	if err != nil {
-		return err
+		err = fmt.Errorf("invalid mesh method '%s', supportted are %s, %s, %s, %s", opt.Get().Mesh.Mode,
+			util.MeshModeAuto, util.MeshModeManual, util.MeshModeIstio, util.MeshModeGateway)
 	}
+	return err
+}
 
-	// watch background process, clean the workspace and exit if background process occur exception
-	s := <-ch
-	log.Info().Msgf("Terminal Signal is %s", s)
-	return nil
+func getServicesToMesh(resourceNames []string) ([]*coreV1.Service, error) {
+	svcs := make([]*coreV1.Service, 0)
+	for _, resourceName := range resourceNames {
+		matchedSvcs, err := general.GetServicesByResourceName(resourceName, opt.Get().Global.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	svcLoop:
+		for _, svc := range matchedSvcs {
+			for _, existSvc := range svcs {
+				if existSvc.Name == svc.Name {
+					continue svcLoop
+				}
+			}
+			svcs = append(svcs, svc)
+		}
+	}
+	return svcs, nil
 }
 
+func serviceNames(svcs []*coreV1.Service) string {
+	names := make([]string, 0)
+	for _, svc := range svcs {
+		names = append(names, svc.Name)
+	}
+	return strings.Join(names, ",")
+}
@@ -10,49 +10,71 @@ import (
 	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func AutoMesh(svc *coreV1.Service) error {
-	// Lock service to avoid conflict, must be first step
-	svc, err := general.LockService(svc.Name, opt.Get().Global.Namespace, 0)
-	if err != nil {
+func AutoMesh(svcs []*coreV1.Service) error {
+	// Parse or generate mesh kv
+	meshKey, meshVersion := getVersion(opt.Get().Mesh.VersionMark)
+	versionMark := meshKey + ":" + meshVersion
+	opt.Store.Mesh = versionMark
+
+	// All meshed services share the same shadow pod
+	shadowLabels := map[string]string{
+		util.KtRole:   util.RoleMeshShadow,
+		util.KtTarget: util.RandomString(20),
+	}
+	portToNames := map[int]string{}
+	svcNames := make([]string, 0)
+	for _, svc := range svcs {
+		// Lock service to avoid conflict, must be first step
+		lockedSvc, err := general.LockService(svc.Name, opt.Get().Global.Namespace, 0)
+		if err != nil {
+			return err
+		}
+		defer general.UnlockService(svc.Name, opt.Get().Global.Namespace)
+		if err = meshService(lockedSvc, meshVersion, versionMark, shadowLabels); err != nil {
+			return err
+		}
+		for port, name := range general.GetTargetPorts(lockedSvc) {
+			portToNames[port] = name
+		}
+		svcNames = append(svcNames, svc.Name)
+	}
+
+	// Create shadow pod
+	shadowName := svcs[0].Name + util.MeshPodInfix + meshVersion
+	// service names are separated by ';' since ',' is used to separate config items
+	annotations := map[string]string{
+		util.KtConfig: fmt.Sprintf("service=%s,version=%s", strings.Join(svcNames, ";"), meshVersion),
+	}
+	mirror := transmission.MirrorConfig{
+		Target:      opt.Get().Mesh.MirrorTarget,
+		SampleRate:  opt.Get().Mesh.MirrorSampleRate,
+		RedactRules: opt.Get().Mesh.MirrorRedactRules,
+		LogPath:     opt.Get().Mesh.MirrorLogPath,
+	}
+	if err := general.CreateShadowAndInbound(shadowName, opt.Get().Mesh.Expose,
+		shadowLabels, annotations, portToNames, meshClientIp(svcs), mirror); err != nil {
 		return err
 	}
-	defer general.UnlockService(svc.Name, opt.Get().Global.Namespace)
+	log.Info().Msg("---------------------------------------------------------------")
+	log.Info().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
+	log.Info().Msg("---------------------------------------------------------------")
+	return nil
+}
 
+func meshService(svc *coreV1.Service, meshVersion, versionMark string, shadowLabels map[string]string) error {
 	if svc.Annotations != nil && svc.Annotations[util.KtSelector] != "" && svc.Spec.Selector[util.KtRole] == util.RoleExchangeShadow {
 		return fmt.Errorf("another user%s is exchanging service '%s', cannot apply mesh",
 			general.GetOccupiedUser(svc.Spec.Selector), svc.Name)
 	}
 
-	// Parse or generate mesh kv
-	meshKey, meshVersion := getVersion(opt.Get().Mesh.VersionMark)
-	versionMark := meshKey + ":" + meshVersion
-	opt.Store.Mesh = versionMark
-
-	portToNames := general.GetTargetPorts(svc)
-	ports := make(map[int]int)
-	for _, specPort := range svc.Spec.Ports {
-		if specPort.TargetPort.Type == intstr.Int {
-			ports[int(specPort.Port)] = specPort.TargetPort.IntValue()
-		} else {
-			podPort := -1
-			for p, n := range portToNames {
-				if n == specPort.TargetPort.StrVal {
-					podPort = p
-					break
-				}
-			}
-			if podPort < 0 {
-				return fmt.Errorf("cannot found port number of target port '%s' of service %s",
-					specPort.TargetPort.StrVal, svc.Name)
-			}
-			ports[int(specPort.Port)] = podPort
-		}
+	ports, err := general.GetServicePorts(svc)
+	if err != nil {
+		return err
 	}
 
 	// Check name usable
@@ -67,11 +89,7 @@ func AutoMesh(svc *coreV1.Service) error {
 
 	// Create shadow service
 	shadowName := svc.Name + util.MeshPodInfix + meshVersion
-	shadowLabels := map[string]string{
-		util.KtRole:   util.RoleMeshShadow,
-		util.KtTarget: util.RandomString(20),
-	}
-	if err = createShadowService(shadowName, ports, shadowLabels); err != nil {
+	if err = createShadowService(shadowName, ports, shadowLabels, map[string]string{}); err != nil {
 		return err
 	}
 
@@ -90,25 +108,7 @@ func AutoMesh(svc *coreV1.Service) error {
 	if err = general.UpdateServiceSelector(svc.Name, opt.Get().Global.Namespace, routerLabels); err != nil {
 		return err
 	}
-	opt.Store.Origin = svc.Name
-
-	// Create shadow pod
-	annotations := map[string]string{
-		util.KtConfig: fmt.Sprintf("service=%s", shadowName),
-	}
-	mirror := transmission.MirrorConfig{
-		Target:      opt.Get().Mesh.MirrorTarget,
-		SampleRate:  opt.Get().Mesh.MirrorSampleRate,
-		RedactRules: opt.Get().Mesh.MirrorRedactRules,
-		LogPath:     opt.Get().Mesh.MirrorLogPath,
-	}
-	if err = general.CreateShadowAndInbound(shadowName, opt.Get().Mesh.Expose,
-		shadowLabels, annotations, portToNames, mirror); err != nil {
-		return err
-	}
-	log.Info().Msg("---------------------------------------------------------------")
-	log.Info().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
-	log.Info().Msg("---------------------------------------------------------------")
+	opt.Store.Origin = util.Append(opt.Store.Origin, svc.Name)
 	return nil
 }
 
@@ -144,13 +144,13 @@ func sanityCheck(svc *coreV1.Service) error {
 }
 
 func createShadowService(shadowSvcName string, ports map[int]int,
-	selectors map[string]string) error {
+	selectors, annotations map[string]string) error {
 	if _, err := cluster.Ins().CreateService(&cluster.SvcMetaAndSpec{
 		Meta: &cluster.ResourceMeta{
 			Name:        shadowSvcName,
 			Namespace:   opt.Get().Global.Namespace,
 			Labels:      map[string]string{},
-			Annotations: map[string]string{},
+			Annotations: annotations,
 		},
 		External:  false,
 		Ports:     ports,
@@ -159,7 +159,7 @@ func createShadowService(shadowSvcName string, ports map[int]int,
 		return err
 	}
 
-	opt.Store.Service = shadowSvcName
+	opt.Store.Service = util.Append(opt.Store.Service, shadowSvcName)
 	log.Info().Msgf("Service %s created", shadowSvcName)
 	return nil
 }
@@ -178,14 +178,14 @@ func createRouter(routerPodName string, svcName string, ports map[int]int, label
 		// Router not exist or just terminated
 		labels[util.KtTarget] = util.RandomString(20)
 		annotations := map[string]string{util.KtRefCount: "1", util.KtConfig: fmt.Sprintf("service=%s", svcName)}
-		if _, err = cluster.Ins().CreateRouterPod(routerPodName, labels, annotations, ports); err != nil {
+		if _, err = cluster.Ins().CreateRouterPod(routerPodName, opt.Get().Mesh.RouterImage, labels, annotations, ports); err != nil {
 			log.Error().Err(err).Msgf("Failed to create router pod")
 			return err
 		}
 		log.Info().Msgf("Router pod is ready")
 
 		stdout, stderr, err2 := cluster.Ins().ExecInPod(util.DefaultContainer, routerPodName, namespace,
-			util.RouterBin, "setup", svcName, toPortMapParameter(ports), versionMark)
+			util.RouterBin, "setup", svcName, general.ToPortMapParameter(ports), versionMark)
 		log.Debug().Msgf("Stdout: %s", stdout)
 		log.Debug().Msgf("Stderr: %s", stderr)
 		if err2 != nil {
@@ -213,7 +213,7 @@ func createRouter(routerPodName string, svcName string, ports map[int]int, label
 		}
 	}
 	log.Info().Msgf("Router pod configuration done")
-	opt.Store.Router = routerPodName
+	opt.Store.Router = util.Append(opt.Store.Router, routerPodName)
 	return nil
 }
 
@@ -251,16 +251,3 @@ func createStuntmanService(svc *coreV1.Service, ports map[int]int) error {
 	}
 	return nil
 }
-
-func toPortMapParameter(ports map[int]int) string {
-	// input: { 80:8080, 70:7000 }
-	// output: "80:8080,70:7000"
-	if len(ports) == 0 {
-		return ""
-	}
-	s := ""
-	for k, v := range ports {
-		s = s + "," + strconv.Itoa(k) + ":" + strconv.Itoa(v)
-	}
-	return s[1:]
-}
@@ -1,8 +1,13 @@
 package mesh
 
 import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
 	"regexp"
 	"strings"
 )
@@ -32,3 +37,54 @@ func isValidKey(key string) bool {
 	ok, err := regexp.MatchString("^[a-z][a-z0-9_-]*$", key)
 	return err == nil && ok
 }
+
+// meshClientIp client address is carried for http ports of meshed services only
+func meshClientIp(svcs []*coreV1.Service) transmission.ClientIpConfig {
+	return transmission.ClientIpConfig{
+		Header:    opt.Get().Mesh.ClientIpHeader,
+		HttpPorts: general.GetHttpTargetPorts(svcs...),
+	}
+}
+
+// createShadowWithServices create a shadow pod with its own labels, and a dedicated shadow service for each of
+// the meshed services, so that only marked requests routed to the shadow service could reach local
+func createShadowWithServices(svcs []*coreV1.Service, meshKey, meshVersion, mode string,
+	annotations map[string]string) error {
+	// All meshed services share the same shadow pod, version label is used for selecting it by istio subset
+	shadowLabels := map[string]string{
+		util.KtRole:   util.RoleMeshShadow,
+		util.KtTarget: util.RandomString(20),
+		meshKey:       meshVersion,
+	}
+	portToNames := map[int]string{}
+	for _, svc := range svcs {
+		ports, err := general.GetServicePorts(svc)
+		if err != nil {
+			return err
+		}
+		if err = isNameUsable(svc.Name, meshVersion, 0); err != nil {
+			return err
+		}
+		shadowSvcName := svc.Name + util.MeshPodInfix + meshVersion
+		shadowSvcAnnotations := map[string]string{
+			util.KtConfig: fmt.Sprintf("%s=%s", mode, svc.Name),
+		}
+		if err = createShadowService(shadowSvcName, ports, shadowLabels, shadowSvcAnnotations); err != nil {
+			return err
+		}
+		for port, name := range general.GetTargetPorts(svc) {
+			portToNames[port] = name
+		}
+	}
+
+	// Create shadow pod
+	shadowName := svcs[0].Name + util.MeshPodInfix + meshVersion
+	mirror := transmission.MirrorConfig{
+		Target:      opt.Get().Mesh.MirrorTarget,
+		SampleRate:  opt.Get().Mesh.MirrorSampleRate,
+		RedactRules: opt.Get().Mesh.MirrorRedactRules,
+		LogPath:     opt.Get().Mesh.MirrorLogPath,
+	}
+	return general.CreateShadowAndInbound(shadowName, opt.Get().Mesh.Expose,
+		shadowLabels, annotations, portToNames, meshClientIp(svcs), mirror)
+}
@@ -0,0 +1,50 @@
+package mesh
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+func GatewayMesh(svcs []*coreV1.Service) error {
+	meshKey, meshVersion := getVersion(opt.Get().Mesh.VersionMark)
+	opt.Store.Mesh = meshKey + ":" + meshVersion
+
+	// Make sure every service is routed by http route, before creating anything
+	svcNames := make([]string, 0)
+	for _, svc := range svcs {
+		routes, err := general.GetHTTPRoutesOfService(svc.Name, svc.Namespace)
+		if err != nil {
+			return err
+		} else if len(routes) == 0 {
+			return fmt.Errorf("no HTTPRoute is pointing to service %s, cannot mesh it in %s mode",
+				svc.Name, util.MeshModeGateway)
+		}
+		svcNames = append(svcNames, svc.Name)
+	}
+
+	// service names are separated by ';' since ',' is used to separate config items
+	annotations := map[string]string{
+		util.KtConfig: fmt.Sprintf("gateway=%s,version=%s", strings.Join(svcNames, ";"), meshVersion),
+	}
+	if err := createShadowWithServices(svcs, meshKey, meshVersion, "gateway", annotations); err != nil {
+		return err
+	}
+
+	// Route marked requests to shadow service, must after shadow pod ready
+	for _, svc := range svcs {
+		shadowSvcName := svc.Name + util.MeshPodInfix + meshVersion
+		if err := general.ApplyHTTPRouteRules(svc.Name, svc.Namespace, shadowSvcName, meshKey, meshVersion); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("---------------------------------------------------------------")
+	log.Info().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
+	log.Info().Msg("---------------------------------------------------------------")
+	return nil
+}
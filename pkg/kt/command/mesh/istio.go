@@ -0,0 +1,42 @@
+package mesh
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+func IstioMesh(svcs []*coreV1.Service) error {
+	meshKey, meshVersion := getVersion(opt.Get().Mesh.VersionMark)
+	opt.Store.Mesh = meshKey + ":" + meshVersion
+
+	svcNames := make([]string, 0)
+	for _, svc := range svcs {
+		svcNames = append(svcNames, svc.Name)
+	}
+	// service names are separated by ';' since ',' is used to separate config items
+	annotations := map[string]string{
+		util.KtConfig: fmt.Sprintf("istio=%s,version=%s", strings.Join(svcNames, ";"), meshVersion),
+	}
+	// Shadow pod must not carry selector of the meshed services, otherwise unmarked requests would reach it as well
+	if err := createShadowWithServices(svcs, meshKey, meshVersion, "istio", annotations); err != nil {
+		return err
+	}
+
+	// Route marked requests to subset of shadow service, must after shadow pod ready
+	for _, svc := range svcs {
+		shadowSvcName := svc.Name + util.MeshPodInfix + meshVersion
+		if err := general.ApplyIstioRules(svc, shadowSvcName, meshKey, meshVersion); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("---------------------------------------------------------")
+	log.Info().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
+	log.Info().Msg("---------------------------------------------------------")
+	return nil
+}
@@ -1,6 +1,7 @@
 package mesh
 
 import (
+	"fmt"
 	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
@@ -9,19 +10,9 @@ import (
 	coreV1 "k8s.io/api/core/v1"
 )
 
-func ManualMesh(svc *coreV1.Service) error {
+func ManualMesh(svcs []*coreV1.Service) error {
 	meshKey, meshVersion := getVersion(opt.Get().Mesh.VersionMark)
-	shadowPodName := svc.Name + util.MeshPodInfix + meshVersion
-	labels := getMeshLabels(meshKey, meshVersion, svc)
-	annotations := make(map[string]string)
-	mirror := transmission.MirrorConfig{
-		Target:      opt.Get().Mesh.MirrorTarget,
-		SampleRate:  opt.Get().Mesh.MirrorSampleRate,
-		RedactRules: opt.Get().Mesh.MirrorRedactRules,
-		LogPath:     opt.Get().Mesh.MirrorLogPath,
-	}
-	if err := general.CreateShadowAndInbound(shadowPodName, opt.Get().Mesh.Expose, labels,
-		annotations, general.GetTargetPorts(svc), mirror); err != nil {
+	if err := createMeshShadow(svcs, meshKey, meshVersion, map[string]string{}); err != nil {
 		return err
 	}
 	log.Info().Msg("---------------------------------------------------------")
@@ -30,14 +21,40 @@ func ManualMesh(svc *coreV1.Service) error {
 	return nil
 }
 
-func getMeshLabels(meshKey, meshVersion string, svc *coreV1.Service) map[string]string {
+func createMeshShadow(svcs []*coreV1.Service, meshKey, meshVersion string, annotations map[string]string) error {
+	shadowPodName := svcs[0].Name + util.MeshPodInfix + meshVersion
+	labels, err := getMeshLabels(meshKey, meshVersion, svcs)
+	if err != nil {
+		return err
+	}
+	portToNames := map[int]string{}
+	for _, svc := range svcs {
+		for port, name := range general.GetTargetPorts(svc) {
+			portToNames[port] = name
+		}
+	}
+	mirror := transmission.MirrorConfig{
+		Target:      opt.Get().Mesh.MirrorTarget,
+		SampleRate:  opt.Get().Mesh.MirrorSampleRate,
+		RedactRules: opt.Get().Mesh.MirrorRedactRules,
+		LogPath:     opt.Get().Mesh.MirrorLogPath,
+	}
+	return general.CreateShadowAndInbound(shadowPodName, opt.Get().Mesh.Expose, labels,
+		annotations, portToNames, meshClientIp(svcs), mirror)
+}
+
+func getMeshLabels(meshKey, meshVersion string, svcs []*coreV1.Service) (map[string]string, error) {
 	labels := map[string]string{}
-	if svc != nil {
+	for _, svc := range svcs {
 		for k, v := range svc.Spec.Selector {
+			if existValue, exists := labels[k]; exists && existValue != v {
+				return nil, fmt.Errorf("selector '%s' of service %s conflicts with other services, cannot mesh them together",
+					k, svc.Name)
+			}
 			labels[k] = v
 		}
 	}
 	labels[util.KtRole] = util.RoleMeshShadow
 	labels[meshKey] = meshVersion
-	return labels
+	return labels, nil
 }
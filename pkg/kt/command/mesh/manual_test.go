@@ -0,0 +1,26 @@
+package mesh
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_getMeshLabels(t *testing.T) {
+	svcA := &coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a"},
+		Spec: coreV1.ServiceSpec{Selector: map[string]string{"app": "demo", "tier": "web"}}}
+	svcB := &coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: "b"},
+		Spec: coreV1.ServiceSpec{Selector: map[string]string{"app": "demo"}}}
+	svcC := &coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: "c"},
+		Spec: coreV1.ServiceSpec{Selector: map[string]string{"app": "other"}}}
+
+	labels, err := getMeshLabels("version", "test", []*coreV1.Service{svcA, svcB})
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"app": "demo", "tier": "web", util.KtRole: util.RoleMeshShadow,
+		"version": "test"}, labels)
+
+	_, err = getMeshLabels("version", "test", []*coreV1.Service{svcA, svcC})
+	require.NotNil(t, err)
+}
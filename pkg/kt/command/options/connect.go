@@ -11,21 +11,41 @@ func ConnectFlags() []OptionConfig {
 			DefaultValue: util.ConnectModeTun2Socks,
 			Description: "Connect mode 'tun2socks' or 'sshuttle'",
 		},
+		{
+			Target:      "Daemon",
+			DefaultValue: false,
+			Description: "Run connect process in background, use 'ktctl status' to check and 'ktctl disconnect' to stop it",
+		},
 		{
 			Target:      "DnsMode",
 			DefaultValue: util.DnsModeLocalDns,
 			Description: "Specify how to resolve service domains, can be 'localDNS', 'podDNS', 'hosts' or 'hosts:<namespaces>', for multiple namespaces use ',' separation",
 		},
+		{
+			Target:      "Namespaces",
+			DefaultValue: "",
+			Description: "(local dns or rootless mode only) Resolve short service names of specified namespaces, former namespace takes precedence when names collide, use ',' separated (default to current namespace)",
+		},
 		{
 			Target:      "ShareShadow",
 			DefaultValue: false,
 			Description: "Use shared shadow pod",
 		},
+		{
+			Target:      "ShadowReplicas",
+			DefaultValue: 1,
+			Description: "(tun2socks mode only) Number of shadow pods to connect through, preferably scheduled on different nodes, socks proxy traffic is balanced across them and fails over when any of them stops responding",
+		},
 		{
 			Target:      "ClusterDomain",
 			DefaultValue: "cluster.local",
 			Description: "The cluster domain provided to kubernetes api-server",
 		},
+		{
+			Target:      "DomainSuffix",
+			DefaultValue: "",
+			Description: "Connect to another cluster alongside the running connect process, services are resolved with specified domain suffix, e.g. '<service>.<namespace>.staging'",
+		},
 		{
 			Target:      "DisablePodIp",
 			DefaultValue: false,
@@ -51,6 +71,11 @@ func ConnectFlags() []OptionConfig {
 			DefaultValue: "",
 			Description: "Specify an IP address which all ingress domains should be resolve to",
 		},
+		{
+			Target:      "Rootless",
+			DefaultValue: false,
+			Description: "(tun2socks mode only) Connect without root permission, only TCP ports of services are forwarded to 127.0.0.1 (random port if taken), service names are not resolved (see ~/.kt/services.env), pod IPs and UDP ports are not accessible",
+		},
 		{
 			Target:      "DisableTunDevice",
 			DefaultValue: false,
@@ -66,11 +91,21 @@ func ConnectFlags() []OptionConfig {
 			DefaultValue: 2223,
 			Description: "(tun2socks mode only) Specify the local port which socks5 proxy should use",
 		},
+		{
+			Target:      "HttpProxyPort",
+			DefaultValue: 0,
+			Description: "(tun2socks mode only) Specify the local port which http proxy and pac file should use when tun device is disabled (e.g. 2224), 0 for disabled",
+		},
 		{
 			Target:      "ProxyAddr",
 			DefaultValue: "127.0.0.1",
 			Description: "(tun2socks mode only) Specify the ip address or hostname which socks5 proxy should use",
 		},
+		{
+			Target:      "ProxyAuth",
+			DefaultValue: "",
+			Description: "(tun2socks mode only) Require authentication for socks5 and http proxy, 'auto' to generate random credential, or specify in '<username>:<password>' format",
+		},
 		{
 			Target:      "DnsCacheTtl",
 			DefaultValue: 60,
@@ -0,0 +1,12 @@
+package options
+
+func DisconnectFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Pid",
+			DefaultValue: 0,
+			Description:  "Only disconnect the connect process of specified pid",
+		},
+	}
+	return flags
+}
@@ -1,13 +1,16 @@
 package options
 
-import "github.com/gitlayzer/kt-connect/pkg/kt/util"
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+)
 
 func ExchangeFlags() []OptionConfig {
 	flags := []OptionConfig{
 		{
 			Target:       "Expose",
 			DefaultValue: "",
-			Description:  "Ports to expose, use ',' separated, in [port] or [local:remote] format, e.g. 7001,8080:80",
+			Description:  "Ports to expose, use ',' separated, in [port], [local:remote] or [host:local:remote] format, prefix with udp/ for udp port, e.g. 7001,8080:80,192.168.1.20:80:80,udp/5353:53",
 			Required:     true,
 		},
 		{
@@ -25,6 +28,31 @@ func ExchangeFlags() []OptionConfig {
 			DefaultValue: 120,
 			Description:  "(scale method only) Seconds to wait for original deployment recover before turn off the shadow pod",
 		},
+		{
+			Target:       "Partial",
+			DefaultValue: false,
+			Description:  "(selector method only) Only redirect exposed ports via a router pod, other service ports keep reaching original pods",
+		},
+		{
+			Target:       "RouterImage",
+			DefaultValue: fmt.Sprintf("%s:v%s", util.ImageKtRouter, Store.Version),
+			Description:  "(partial exchange only) Customize router image",
+		},
+		{
+			Target:       "DrainPeriod",
+			DefaultValue: 0,
+			Description:  "(selector method only) Max seconds to wait for service switched, and for in-flight connections of shadow pod to finish on exit",
+		},
+		{
+			Target:       "FailoverThreshold",
+			DefaultValue: 0,
+			Description:  "(selector method only) Route traffic back to original pods after local ports failed to respond for specified times, 0 means never",
+		},
+		{
+			Target:       "ClientIpHeader",
+			DefaultValue: "",
+			Description:  "Carry client address to local service via 'proxy-v1', 'proxy-v2' (proxy protocol) or 'x-forwarded-for' (http header)",
+		},
 		{
 			Target:       "MirrorTarget",
 			DefaultValue: "",
@@ -45,6 +73,21 @@ func ExchangeFlags() []OptionConfig {
 			DefaultValue: "",
 			Description:  "Directory to write mirror request logs",
 		},
+		{
+			Target:       "SyncEnv",
+			DefaultValue: "",
+			Description:  "Directory to write env variables, config maps and secrets of exchanged workload, synced files are removed on exit",
+		},
+		{
+			Target:       "SyncRedact",
+			DefaultValue: "",
+			Description:  "Env variable or file names to redact when syncing env, use ',' separated, wildcard '*' is supported",
+		},
+		{
+			Target:       "SyncToken",
+			DefaultValue: false,
+			Description:  "Also write service account token of exchanged workload to the sync env directory, and keep it refreshed",
+		},
 	}
 	return flags
 }
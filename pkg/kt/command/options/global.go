@@ -67,6 +67,11 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: 10,
 			Description:  "Seconds to wait before port-forward connection timeout",
 		},
+		{
+			Target:       "MaxReconnect",
+			DefaultValue: 0,
+			Description:  "Maximum consecutive attempts to re-establish a broken connection to shadow pod, 0 for unlimited",
+		},
 		{
 			Target:       "PodCreationTimeout",
 			DefaultValue: 60,
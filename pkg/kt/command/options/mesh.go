@@ -10,13 +10,13 @@ func MeshFlags() []OptionConfig {
 		{
 			Target:       "Expose",
 			DefaultValue: "",
-			Description:  "Ports to expose, use ',' separated, in [port] or [local:remote] format, e.g. 7001,8080:80",
+			Description:  "Ports to expose, use ',' separated, in [port], [local:remote] or [host:local:remote] format, prefix with udp/ for udp port, e.g. 7001,8080:80,192.168.1.20:80:80,udp/5353:53",
 			Required:     true,
 		},
 		{
 			Target:       "Mode",
 			DefaultValue: util.MeshModeAuto,
-			Description:  "Mesh method 'auto' or 'manual'",
+			Description:  "Mesh method 'auto', 'manual', 'istio' or 'gateway'",
 		},
 		{
 			Target:       "VersionMark",
@@ -33,6 +33,16 @@ func MeshFlags() []OptionConfig {
 			DefaultValue: fmt.Sprintf("%s:v%s", util.ImageKtRouter, Store.Version),
 			Description:  "(auto method only) Customize router image",
 		},
+		{
+			Target:       "DrainPeriod",
+			DefaultValue: 0,
+			Description:  "(auto method only) Max seconds to wait for service switched, and for in-flight connections of router pod to finish on exit",
+		},
+		{
+			Target:       "ClientIpHeader",
+			DefaultValue: "",
+			Description:  "Carry client address to local service via 'proxy-v1', 'proxy-v2' (proxy protocol) or 'x-forwarded-for' (http header)",
+		},
 		{
 			Target:       "MirrorTarget",
 			DefaultValue: "",
@@ -28,6 +28,13 @@ type ConnectOptions struct {
 	ClusterDomain    string
 	SkipCleanup      bool
 	IncludeDomains   string
+	DomainSuffix     string
+	Namespaces       string
+	Daemon           bool
+	Rootless         bool
+	HttpProxyPort    int
+	ProxyAuth        string
+	ShadowReplicas   int
 }
 
 // ExchangeOptions ...
@@ -36,10 +43,18 @@ type ExchangeOptions struct {
 	Expose            string
 	RecoverWaitTime   int
 	SkipPortChecking  bool
+	Partial           bool
+	RouterImage       string
+	DrainPeriod       int
+	FailoverThreshold int
+	ClientIpHeader    string
 	MirrorTarget      string
 	MirrorSampleRate  int
 	MirrorRedactRules string
 	MirrorLogPath     string
+	SyncEnv           string
+	SyncRedact        string
+	SyncToken         bool
 }
 
 // MeshOptions ...
@@ -49,6 +64,8 @@ type MeshOptions struct {
 	VersionMark       string
 	RouterImage       string
 	SkipPortChecking  bool
+	DrainPeriod       int
+	ClientIpHeader    string
 	MirrorTarget      string
 	MirrorSampleRate  int
 	MirrorRedactRules string
@@ -57,6 +74,7 @@ type MeshOptions struct {
 
 // RecoverOptions ...
 type RecoverOptions struct {
+	DrainPeriod int
 }
 
 // ReplayOptions ...
@@ -72,6 +90,33 @@ type PreviewOptions struct {
 	SkipPortChecking bool
 }
 
+// RunOptions ...
+type RunOptions struct {
+	Expose      string
+	Mesh        bool
+	SkipConnect bool
+	Container   string
+	EnvDir      string
+}
+
+// SyncOptions ...
+type SyncOptions struct {
+	Output    string
+	Container string
+	Redact    string
+	Token     bool
+}
+
+// StatusOptions ...
+type StatusOptions struct {
+	Json bool
+}
+
+// DisconnectOptions ...
+type DisconnectOptions struct {
+	Pid int
+}
+
 // ForwardOptions ...
 type ForwardOptions struct {
 }
@@ -107,6 +152,7 @@ type GlobalOptions struct {
 	WithLabel           string
 	WithAnnotation      string
 	PortForwardTimeout  int
+	MaxReconnect        int
 	PodCreationTimeout  int
 	UseShadowDeployment bool
 	ForceUpdate         bool
@@ -119,17 +165,21 @@ type GlobalOptions struct {
 
 // DaemonOptions cli options
 type DaemonOptions struct {
-	Connect  *ConnectOptions
-	Exchange *ExchangeOptions
-	Mesh     *MeshOptions
-	Preview  *PreviewOptions
-	Forward  *ForwardOptions
-	Recover  *RecoverOptions
-	Replay   *ReplayOptions
-	Clean    *CleanOptions
-	Config   *ConfigOptions
-	Birdseye *BirdseyeOptions
-	Global   *GlobalOptions
+	Connect    *ConnectOptions
+	Exchange   *ExchangeOptions
+	Mesh       *MeshOptions
+	Preview    *PreviewOptions
+	Forward    *ForwardOptions
+	Sync       *SyncOptions
+	Run        *RunOptions
+	Recover    *RecoverOptions
+	Replay     *ReplayOptions
+	Clean      *CleanOptions
+	Status     *StatusOptions
+	Disconnect *DisconnectOptions
+	Config     *ConfigOptions
+	Birdseye   *BirdseyeOptions
+	Global     *GlobalOptions
 }
 
 var opt *DaemonOptions
@@ -138,17 +188,21 @@ var opt *DaemonOptions
 func Get() *DaemonOptions {
 	if opt == nil {
 		opt = &DaemonOptions{
-			Global:   &GlobalOptions{},
-			Connect:  &ConnectOptions{},
-			Exchange: &ExchangeOptions{},
-			Mesh:     &MeshOptions{},
-			Preview:  &PreviewOptions{},
-			Forward:  &ForwardOptions{},
-			Recover:  &RecoverOptions{},
-			Replay:   &ReplayOptions{},
-			Clean:    &CleanOptions{},
-			Birdseye: &BirdseyeOptions{},
-			Config:   &ConfigOptions{},
+			Global:     &GlobalOptions{},
+			Connect:    &ConnectOptions{},
+			Exchange:   &ExchangeOptions{},
+			Mesh:       &MeshOptions{},
+			Preview:    &PreviewOptions{},
+			Forward:    &ForwardOptions{},
+			Sync:       &SyncOptions{},
+			Run:        &RunOptions{},
+			Recover:    &RecoverOptions{},
+			Replay:     &ReplayOptions{},
+			Clean:      &CleanOptions{},
+			Status:     &StatusOptions{},
+			Disconnect: &DisconnectOptions{},
+			Birdseye:   &BirdseyeOptions{},
+			Config:     &ConfigOptions{},
 		}
 		if customize, exist := GetCustomizeKtConfig(); exist {
 			mergeOptions(opt, []byte(customize))
@@ -5,7 +5,7 @@ func PreviewFlags() []OptionConfig {
 		{
 			Target:       "Expose",
 			DefaultValue: "",
-			Description:  "Ports to expose, use ',' separated, in [port] or [local:remote] format, e.g. 7001,8080:80",
+			Description:  "Ports to expose, use ',' separated, in [port], [local:remote] or [host:local:remote] format, prefix with udp/ for udp port, e.g. 7001,8080:80,192.168.1.20:80:80,udp/5353:53",
 			Required:     true,
 		},
 		{
@@ -2,6 +2,11 @@ package options
 
 func RecoverFlags() []OptionConfig {
 	flags := []OptionConfig{
+		{
+			Target:       "DrainPeriod",
+			DefaultValue: 0,
+			Description:  "Max seconds to wait for in-flight connections of shadow pods to finish after service recovered",
+		},
 	}
 	return flags
-}
\ No newline at end of file
+}
@@ -0,0 +1,32 @@
+package options
+
+func RunFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Expose",
+			DefaultValue: "",
+			Description:  "Ports to expose, in the same format as exchange, traffic is not redirected to local if not specified",
+		},
+		{
+			Target:       "Mesh",
+			DefaultValue: false,
+			Description:  "Mesh the service instead of exchange it",
+		},
+		{
+			Target:       "SkipConnect",
+			DefaultValue: false,
+			Description:  "Do not connect to cluster network, which requires administrator permission",
+		},
+		{
+			Target:       "Container",
+			DefaultValue: "",
+			Description:  "Name of container to take environment from, default to the first container",
+		},
+		{
+			Target:       "EnvDir",
+			DefaultValue: "kt-env",
+			Description:  "Directory to write config maps, secrets and service account token, synced files are removed on exit",
+		},
+	}
+	return flags
+}
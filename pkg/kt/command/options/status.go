@@ -0,0 +1,12 @@
+package options
+
+func StatusFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Json",
+			DefaultValue: false,
+			Description:  "Print status in json format",
+		},
+	}
+	return flags
+}
@@ -3,6 +3,7 @@ package options
 import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"sync/atomic"
 )
 
 var Store = &RuntimeStore{}
@@ -13,10 +14,14 @@ type RuntimeStore struct {
 	Clientset kubernetes.Interface
 	// RestConfig kubectl config
 	RestConfig *rest.Config
+	// KubeContext kubeconfig context in use
+	KubeContext string
 	// Version ktctl version
 	Version string
 	// Component current sub-command (connect, exchange, mesh or preview)
 	Component string
+	// RunAs components activated by run command, e.g. connect and exchange
+	RunAs string
 	// Shadow pod name
 	Shadow string
 	// Router pod name
@@ -25,10 +30,25 @@ type RuntimeStore struct {
 	Mesh string
 	// Origin the origin deployment or service name
 	Origin string
+	// OriginKind kind of the origin workload
+	OriginKind string
 	// Replicas the origin replicas
 	Replicas int32
+	// Autoscaler encoded origin hpa set aside during exchange
+	Autoscaler string
 	// Service exposed service name
 	Service string
+	// Istio services with istio rules changed
+	Istio string
+	// Gateway services with http routes changed
+	Gateway string
+	// LocalEnv directory of synced environment variables, config maps and secrets
+	LocalEnv string
+	// Ephemeral pods with ephemeral shadow container injected
+	Ephemeral string
 	// isIpv6Cluster
 	Ipv6Cluster bool
+	// Terminating current process is cleaning up, shadow pod should no longer be recreated,
+	// it's read by background watchers, thus must be atomic
+	Terminating atomic.Bool
 }
@@ -0,0 +1,27 @@
+package options
+
+func SyncFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Output",
+			DefaultValue: "kt-env",
+			Description:  "Directory to write env variables, config maps and secrets, synced files are removed on exit",
+		},
+		{
+			Target:       "Container",
+			DefaultValue: "",
+			Description:  "Name of container to sync, default to the first container",
+		},
+		{
+			Target:       "Redact",
+			DefaultValue: "",
+			Description:  "Env variable or file names to redact, use ',' separated, wildcard '*' is supported",
+		},
+		{
+			Target:       "Token",
+			DefaultValue: false,
+			Description:  "Also write service account token of the workload, and keep it refreshed",
+		},
+	}
+	return flags
+}
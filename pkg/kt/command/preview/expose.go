@@ -2,11 +2,13 @@ package preview
 
 import (
 	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
 	"github.com/gitlayzer/kt-connect/pkg/kt/transmission"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
 	"strings"
 )
 
@@ -38,6 +40,7 @@ func exposeLocalService(serviceName, shadowPodName string, labels, annotations m
 
 	portPairs := strings.Split(opt.Get().Preview.Expose, ",")
 	ports := make(map[int]int)
+	protocols := make(map[int]coreV1.Protocol)
 	for _, exposePort := range portPairs {
 		_, remotePort, err2 := util.ParsePortMapping(exposePort)
 		if err2 != nil {
@@ -45,6 +48,9 @@ func exposeLocalService(serviceName, shadowPodName string, labels, annotations m
 		}
 		// service port to target port
 		ports[remotePort] = remotePort
+		if protocol, _, _ := util.ParseExposeProtocol(exposePort); protocol == util.ProtocolUdp {
+			protocols[remotePort] = coreV1.ProtocolUDP
+		}
 	}
 	if _, err = cluster.Ins().CreateService(&cluster.SvcMetaAndSpec{
 		Meta: &cluster.ResourceMeta{
@@ -56,12 +62,14 @@ func exposeLocalService(serviceName, shadowPodName string, labels, annotations m
 		External:  opt.Get().Preview.External,
 		Ports:     ports,
 		Selectors: labels,
+		Protocols: protocols,
 	}); err != nil {
 		return err
 	}
 	opt.Store.Service = serviceName
 
-	if _, err = transmission.ForwardPodToLocal(opt.Get().Preview.Expose, podName, privateKeyPath); err != nil {
+	currentPod := general.WatchShadow(shadowPodName, podName, nil)
+	if _, err = transmission.ForwardPodToLocal(opt.Get().Preview.Expose, currentPod, privateKeyPath, transmission.ClientIpConfig{}, transmission.MirrorConfig{}); err != nil {
 		return err
 	}
 
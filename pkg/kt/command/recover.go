@@ -58,6 +58,25 @@ func Recover(serviceName string) error {
 	targetDeployment, targetPod, targetRole := fetchTargetRole(apps, pods)
 	log.Debug().Msgf("Target role is: %s", targetRole)
 
+	if recover.HandleExchangedByEphemeralService(pods) {
+		log.Info().Msgf("Ephemeral exchanged pods of service %s recovered", serviceName)
+		if targetRole == "" {
+			return nil
+		}
+	}
+	if general.RestoreIstioRules(svc.Name, svc.Namespace) {
+		log.Info().Msgf("Istio rules of service %s recovered", serviceName)
+		if targetRole == "" {
+			return recover.HandleMeshedByIstioService(svc)
+		}
+	}
+	if general.RestoreHTTPRoutes(svc.Name, svc.Namespace) {
+		log.Info().Msgf("HTTPRoutes of service %s recovered", serviceName)
+		if targetRole == "" {
+			return recover.HandleMeshedByGatewayService(svc)
+		}
+	}
+
 	if svc.Annotations == nil {
 		// put an empty map to avoid npe
 		svc.Annotations = map[string]string{}
@@ -81,7 +100,11 @@ func Recover(serviceName string) error {
 		log.Debug().Msgf("Recovering selector to %v", selector)
 		svc.Spec.Selector = selector
 		delete(svc.Annotations, util.KtSelector)
-		if targetRole == util.RoleRouter {
+		if targetRole == util.RoleRouter && targetPod != nil &&
+			util.String2Map(targetPod.Annotations[util.KtConfig])["partial"] != "" {
+			log.Info().Msgf("Service %s is partially exchanged, recovering", serviceName)
+			return recover.HandlePartialExchangedService(svc, targetPod)
+		} else if targetRole == util.RoleRouter {
 			log.Info().Msgf("Service %s is meshed, recovering", serviceName)
 			return recover.HandleMeshedByAutoService(svc, targetDeployment, targetPod)
 		} else if targetRole == util.RoleExchangeShadow {
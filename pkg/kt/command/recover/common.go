@@ -2,6 +2,8 @@ package recover
 
 import (
 	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/service/cluster"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
@@ -35,13 +37,33 @@ func HandleExchangedByScaleService(svc *coreV1.Service, deployment *appV1.Deploy
 	}
 	replica, _ := strconv.ParseInt(config["replicas"], 10, 32)
 	app := config["app"]
+	kind := config["kind"]
+	if kind == "" {
+		// exchanged by earlier version
+		kind = cluster.KindDeployment
+	}
 	if replica > 0 && app != "" {
-		originReplica := int32(replica)
-		return cluster.Ins().ScaleTo(app, svc.Namespace, &originReplica)
+		if err := cluster.Ins().ScaleWorkload(kind, app, svc.Namespace, int32(replica)); err != nil {
+			return err
+		}
+	}
+	if config["hpa"] != "" {
+		return general.RestoreAutoscaler(config["hpa"])
 	}
 	return nil
 }
 
+func HandleExchangedByEphemeralService(pods *coreV1.PodList) bool {
+	recovered := false
+	for _, pod := range pods.Items {
+		if util.String2Map(pod.Annotations[util.KtConfig])["ephemeral"] != "" {
+			log.Info().Msgf("Pod %s is exchanged, recovering", pod.Name)
+			recovered = general.RecoverEphemeralExchangedPod(pod.Name, pod.Namespace) || recovered
+		}
+	}
+	return recovered
+}
+
 func HandleMeshedByManualService(svc *coreV1.Service, deployment *appV1.Deployment, pod *coreV1.Pod) error {
 	return HandleServiceSelectorAndRemotePods(svc, deployment, pod)
 }
@@ -57,6 +79,46 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	} else if pod == nil {
 		return fmt.Errorf("service '%s' is meshed without selecting a router pod, cannot auto recover", svc.Name)
 	}
+	if err := removeRouterAndStuntman(svc, pod); err != nil {
+		return err
+	}
+	removeMeshShadows(svc)
+	return nil
+}
+
+func HandlePartialExchangedService(svc *coreV1.Service, pod *coreV1.Pod) error {
+	if err := removeRouterAndStuntman(svc, pod); err != nil {
+		return err
+	}
+	shadowName := util.String2Map(pod.Annotations[util.KtConfig])["partial"]
+	log.Info().Msgf("Deleting shadow service %s", shadowName)
+	if err := cluster.Ins().RemoveService(shadowName, svc.Namespace); err != nil {
+		log.Debug().Err(err).Msgf("Failed to remove service %s", shadowName)
+	}
+	shadowLabels := map[string]string{
+		util.ControlBy: util.KubernetesToolkit,
+		util.KtRole:    util.RoleExchangeShadow,
+	}
+	if apps, err := cluster.Ins().GetDeploymentsByLabel(shadowLabels, svc.Namespace); err == nil {
+		for _, shadowApp := range apps.Items {
+			if util.String2Map(shadowApp.Annotations[util.KtConfig])["service"] == svc.Name {
+				log.Info().Msgf("Deleting shadow deployment %s", shadowApp.Name)
+				_ = cluster.Ins().RemoveDeployment(shadowApp.Name, shadowApp.Namespace)
+			}
+		}
+	}
+	if pods, err := cluster.Ins().GetPodsByLabel(shadowLabels, svc.Namespace); err == nil {
+		for _, shadowPod := range pods.Items {
+			if util.String2Map(shadowPod.Annotations[util.KtConfig])["service"] == svc.Name {
+				log.Info().Msgf("Deleting shadow pod %s", shadowPod.Name)
+				_ = cluster.Ins().RemovePod(shadowPod.Name, shadowPod.Namespace)
+			}
+		}
+	}
+	return nil
+}
+
+func removeRouterAndStuntman(svc *coreV1.Service, pod *coreV1.Pod) error {
 	// must delete router pod first, to avoid origin service recover by mesh watcher
 	log.Info().Msgf("Deleting route pod %s", pod.Name)
 	if err := cluster.Ins().RemovePod(pod.Name, pod.Namespace); err != nil {
@@ -70,6 +132,22 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	if err := cluster.Ins().RemoveService(svc.Name + util.StuntmanServiceSuffix, svc.Namespace); err != nil {
 		log.Debug().Err(err).Msgf("Failed to remove service %s", svc.Name)
 	}
+	return nil
+}
+
+func HandleMeshedByIstioService(svc *coreV1.Service) error {
+	// istio rules already restored, only shadow pods, shadow deployments and shadow services left
+	removeMeshShadows(svc)
+	return nil
+}
+
+func HandleMeshedByGatewayService(svc *coreV1.Service) error {
+	// http routes already restored, only shadow pods, shadow deployments and shadow services left
+	removeMeshShadows(svc)
+	return nil
+}
+
+func removeMeshShadows(svc *coreV1.Service) {
 	shadowLabels := map[string]string{
 		util.ControlBy: util.KubernetesToolkit,
 		util.KtRole:    util.RoleMeshShadow,
@@ -77,7 +155,7 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	shadowSvcNames := make([]string, 0)
 	if apps, err := cluster.Ins().GetDeploymentsByLabel(shadowLabels, svc.Namespace); err == nil {
 		for _, shadowApp := range apps.Items {
-			if strings.HasPrefix(shadowApp.Name, svc.Name + util.MeshPodInfix) {
+			if isMeshShadowOf(shadowApp.Name, shadowApp.Annotations, svc.Name) {
 				log.Info().Msgf("Deleting shadow deployment %s", shadowApp.Name)
 				if err2 := cluster.Ins().RemoveDeployment(shadowApp.Name, shadowApp.Namespace); err2 != nil {
 					log.Debug().Err(err2).Msgf("Failed to remove deployment %s", shadowApp.Name)
@@ -88,28 +166,64 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	}
 	if pods, err := cluster.Ins().GetPodsByLabel(shadowLabels, svc.Namespace); err == nil {
 		for _, shadowPod := range pods.Items {
-			if strings.HasPrefix(shadowPod.Name, svc.Name + util.MeshPodInfix) && shadowPod.DeletionTimestamp == nil {
+			if isMeshShadowOf(shadowPod.Name, shadowPod.Annotations, svc.Name) && shadowPod.DeletionTimestamp == nil {
 				log.Info().Msgf("Deleting shadow pod %s", shadowPod.Name)
 				if err2 := cluster.Ins().RemovePod(shadowPod.Name, shadowPod.Namespace); err2 != nil {
-					log.Debug().Err(err2).Msgf("Failed to remove pod %s", pod.Name)
+					log.Debug().Err(err2).Msgf("Failed to remove pod %s", shadowPod.Name)
 				}
 				shadowSvcNames = append(shadowSvcNames, shadowPod.Name)
 			}
 		}
 	}
+	// shadow service of a service meshed together with others may select shadow pod of different name
+	if ktSvcs, err := cluster.Ins().GetServicesByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit}, svc.Namespace); err == nil {
+		for _, ktSvc := range ktSvcs.Items {
+			if strings.HasPrefix(ktSvc.Name, svc.Name + util.MeshPodInfix) && !util.Contains(shadowSvcNames, ktSvc.Name) {
+				shadowSvcNames = append(shadowSvcNames, ktSvc.Name)
+			}
+		}
+	}
 	for _, shadowSvc := range shadowSvcNames {
 		log.Info().Msgf("Deleting shadow service %s", shadowSvc)
 		if err := cluster.Ins().RemoveService(shadowSvc, svc.Namespace); err != nil {
-			log.Debug().Err(err).Msgf("Failed to remove service %s", svc.Name)
+			log.Debug().Err(err).Msgf("Failed to remove service %s", shadowSvc)
 		}
 	}
-	return nil
+}
+
+// isMeshShadowOf check whether a mesh shadow is created for specified service, the shadow of several
+// services meshed together is named after the first one, but records all of them in kt-config annotation
+func isMeshShadowOf(name string, annotations map[string]string, svcName string) bool {
+	if strings.HasPrefix(name, svcName + util.MeshPodInfix) {
+		return true
+	}
+	config := util.String2Map(annotations[util.KtConfig])
+	for _, key := range []string{"service", "istio", "gateway"} {
+		if config[key] != "" && util.Contains(strings.Split(config[key], ";"), svcName) {
+			return true
+		}
+	}
+	return false
 }
 
 func HandleServiceSelectorAndRemotePods(svc *coreV1.Service, deployment *appV1.Deployment, pod *coreV1.Pod) error {
+	drainPeriod := opt.Get().Recover.DrainPeriod
+	if drainPeriod > 0 {
+		if err := general.WaitPodsReadyToServe(svc.Spec.Selector, svc.Namespace, opt.Get().Global.PodCreationTimeout); err != nil {
+			return err
+		}
+	}
 	if _, err := cluster.Ins().UpdateService(svc); err != nil {
 		return err
 	}
+	// keep shadow pods serving in-flight connections before removing them
+	var previousSelector map[string]string
+	if pod != nil {
+		previousSelector = pod.Labels
+	} else if deployment != nil {
+		previousSelector = deployment.Spec.Template.Labels
+	}
+	general.DrainPreviousEndpoints(svc.Name, svc.Namespace, svc.Spec.Selector, previousSelector, drainPeriod)
 	if deployment != nil {
 		log.Info().Msgf("Deleting shadow deployment %s", deployment.Name)
 		_ = cluster.Ins().RemoveDeployment(deployment.Name, deployment.Namespace)
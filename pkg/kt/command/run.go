@@ -0,0 +1,168 @@
+package command
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewRunCommand return new run command
+func NewRunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run local command with environment of specified workload, and redirect its traffic to local",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash >= len(args) {
+				return fmt.Errorf("command to run is required, please put it after '--'")
+			} else if dash == 0 {
+				return fmt.Errorf("name of service or workload is required")
+			} else if dash > 1 {
+				return fmt.Errorf("too many resource names are spcified (%s), should be one", strings.Join(args[:dash], ","))
+			}
+			if !opt.Get().Run.SkipConnect && util.GetDaemonRunning(util.ComponentConnect) < 0 {
+				if err := checkPermissionAndOptions(); err != nil {
+					return err
+				}
+			}
+			return general.Prepare()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Run(args[0], args[cmd.ArgsLenAtDash():])
+		},
+		Example: "ktctl run <service-name|workload-type/workload-name> [command options] -- <command> [args...]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(true))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Run, opt.RunFlags())
+	return cmd
+}
+
+// Run start local command with environment of workload, keep cluster connected and traffic redirected while it running
+func Run(resourceName string, command []string) error {
+	if opt.Get().Run.Expose != "" && !opt.Get().Run.Mesh {
+		if err := checkExchangeOptions(); err != nil {
+			return err
+		}
+	}
+
+	ch, err := general.SetupProcess(util.ComponentRun)
+	if err != nil {
+		return err
+	}
+
+	workload, err := general.GetWorkloadByResourceName(resourceName, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	envs, err := general.SyncWorkloadEnv(workload, opt.Get().Run.Container, opt.Get().Run.EnvDir, "")
+	if err != nil {
+		return err
+	}
+	if err = general.ProjectServiceAccountToken(workload, opt.Get().Run.Container, opt.Get().Run.EnvDir); err != nil {
+		log.Warn().Err(err).Msgf("Service account token is not available")
+	}
+
+	if !opt.Get().Run.SkipConnect {
+		if pid := util.GetDaemonRunning(util.ComponentConnect); pid > 0 {
+			log.Info().Msgf("Using connect process %d", pid)
+		} else {
+			opt.Store.RunAs = util.Append(opt.Store.RunAs, util.ComponentConnect)
+			if err = connectCluster(); err != nil {
+				return err
+			}
+		}
+	}
+
+	cmd, err := startLocalCommand(command, envs)
+	if err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	// redirect traffic after local command started, so that requests could be served as soon as possible
+	if opt.Get().Run.Expose != "" {
+		if err = redirectToLocal(resourceName); err != nil {
+			stopLocalCommand(cmd, exited)
+			return err
+		}
+	}
+
+	// process lifecycle is tied to the local command
+	select {
+	case err = <-exited:
+		if err != nil {
+			return fmt.Errorf("command exited with error: %s", err)
+		}
+		log.Info().Msgf("Command exited")
+	case s := <-ch:
+		log.Info().Msgf("Terminal Signal is %s", s)
+		stopLocalCommand(cmd, exited)
+	}
+	return nil
+}
+
+func redirectToLocal(resourceName string) error {
+	if opt.Get().Run.Mesh {
+		opt.Get().Mesh.Expose = opt.Get().Run.Expose
+		opt.Store.RunAs = util.Append(opt.Store.RunAs, util.ComponentMesh)
+		return meshResources([]string{resourceName})
+	}
+	opt.Get().Exchange.Expose = opt.Get().Run.Expose
+	// environment already synced to run directory
+	opt.Get().Exchange.SyncEnv = ""
+	opt.Store.RunAs = util.Append(opt.Store.RunAs, util.ComponentExchange)
+	return exchangeResource(resourceName)
+}
+
+func startLocalCommand(command []string, envs map[string]string) (*exec.Cmd, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// local command may not be found if these variables are overwritten
+		if name == "PATH" || name == "HOME" {
+			log.Debug().Msgf("Skip overwriting local env %s", name)
+			continue
+		}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", name, envs[name]))
+	}
+	util.RunAsSudoUser(cmd)
+	log.Info().Msgf("Starting command: %s", strings.Join(command, " "))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start command %s: %s", command[0], err)
+	}
+	return cmd, nil
+}
+
+func stopLocalCommand(cmd *exec.Cmd, exited chan error) {
+	// interrupt signal is not supported in windows
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+		log.Info().Msgf("Command stopped")
+	case <-time.After(10 * time.Second):
+		log.Warn().Msgf("Command not stopped in 10 seconds, killing it")
+		_ = cmd.Process.Kill()
+	}
+}
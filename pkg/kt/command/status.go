@@ -0,0 +1,117 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewStatusCommand return new status command
+func NewStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show status of running connect processes",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			general.SetupLogger()
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Status()
+		},
+		Example: "ktctl status [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(false))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Status, opt.StatusFlags())
+	return cmd
+}
+
+// Status query and print status of all running connect processes
+func Status() error {
+	statuses := make([]*control.ConnectStatus, 0)
+	for _, pid := range util.GetAllDaemonRunning(util.ComponentConnect) {
+		status, err := control.GetStatus(pid)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Failed to query status of connect process %d", pid)
+			status = &control.ConnectStatus{Pid: pid}
+		}
+		statuses = append(statuses, status)
+	}
+
+	if opt.Get().Status.Json {
+		content, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+	if len(statuses) == 0 {
+		log.Info().Msgf("No connect process is running")
+		return nil
+	}
+	for _, status := range statuses {
+		showConnectStatus(status)
+	}
+	return nil
+}
+
+func showConnectStatus(status *control.ConnectStatus) {
+	log.Info().Msgf("---- Connect process %d ----", status.Pid)
+	if status.StartTime == 0 {
+		// process started by ktctl without control api
+		log.Info().Msgf("> Status not available")
+		return
+	}
+	log.Info().Msgf("> Context: %s (namespace %s)", status.Context, status.Namespace)
+	log.Info().Msgf("> Started at: %s", time.Unix(status.StartTime, 0).Format("2006-01-02 15:04:05"))
+	if status.Rootless {
+		log.Info().Msgf("> Connect mode: %s (rootless)", status.Mode)
+	} else {
+		log.Info().Msgf("> Connect mode: %s", status.Mode)
+	}
+	if status.DomainSuffix != "" {
+		log.Info().Msgf("> Dns mode: %s (domain suffix %s)", status.DnsMode, status.DomainSuffix)
+	} else {
+		log.Info().Msgf("> Dns mode: %s", status.DnsMode)
+	}
+	log.Info().Msgf("> Shadow pod: %s (%s)", status.Shadow, status.ShadowIp)
+	if status.TunName != "" {
+		log.Info().Msgf("> Tun device: %s", status.TunName)
+	}
+	if status.ProxyPort > 0 {
+		log.Info().Msgf("> Proxy port: %d", status.ProxyPort)
+	}
+	if status.HttpProxyPort > 0 {
+		log.Info().Msgf("> Http proxy port: %d", status.HttpProxyPort)
+	}
+	log.Info().Msgf("> Routes: %s", strings.Join(status.Routes, ","))
+	log.Info().Msgf("> Reconnect count: %d", status.ReconnectCount)
+	if status.Ready {
+		log.Info().Msgf("> Health: %s", status.Health)
+	} else {
+		log.Info().Msgf("> Health: %s (setting up)", status.Health)
+	}
+	names := make([]string, 0, len(status.Connections))
+	for name := range status.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Info().Msgf(">   %s: %s", name, status.Connections[name])
+	}
+	if status.LastError != "" {
+		log.Info().Msgf("> Last error: %s", status.LastError)
+	}
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/command/general"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// NewSyncCommand return new sync command
+func NewSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Write env variables, config maps and secrets of a workload to local files",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("name of service or workload to sync is required")
+			} else if len(args) > 1 {
+				return fmt.Errorf("too many resource names are spcified (%s), should be one", strings.Join(args, ","))
+			}
+			return general.Prepare()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Sync(args[0])
+		},
+		Example: "ktctl sync <service-name|workload-type/workload-name> [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(true))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Sync, opt.SyncFlags())
+	return cmd
+}
+
+// Sync write env variables, config maps and secrets of workload to local directory, and remove them on exit
+func Sync(resourceName string) error {
+	ch, err := general.SetupProcess(util.ComponentSync)
+	if err != nil {
+		return err
+	}
+
+	workload, err := general.GetWorkloadByResourceName(resourceName, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	if _, err = general.SyncWorkloadEnv(workload, opt.Get().Sync.Container, opt.Get().Sync.Output,
+		opt.Get().Sync.Redact); err != nil {
+		return err
+	}
+	if opt.Get().Sync.Token {
+		if err = general.ProjectServiceAccountToken(workload, opt.Get().Sync.Container, opt.Get().Sync.Output); err != nil {
+			return err
+		}
+	}
+	log.Info().Msg("---------------------------------------------------------------")
+	log.Info().Msgf(" Now env of %s '%s' is available in '%s', removed on exit", workload.Kind, workload.Name,
+		opt.Get().Sync.Output)
+	log.Info().Msg("---------------------------------------------------------------")
+
+	// watch background process, clean the workspace and exit if background process occur exception
+	s := <-ch
+	log.Info().Msgf("Terminal Signal is %s", s)
+	return nil
+}
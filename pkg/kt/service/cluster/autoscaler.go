@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GetHorizontalPodAutoscalers get all horizontal pod autoscalers in namespace
+func (k *Kubernetes) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error) {
+	hpaList, err := k.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{
+		TimeoutSeconds: &apiTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hpaList.Items, nil
+}
+
+// PatchHorizontalPodAutoscalerRange patch min and max replicas of horizontal pod autoscaler, nil min replicas
+// removes the field and falls back to default value
+func (k *Kubernetes) PatchHorizontalPodAutoscalerRange(name, namespace string, minReplicas *int32, maxReplicas int32) error {
+	minValue := "null"
+	if minReplicas != nil {
+		minValue = fmt.Sprintf("%d", *minReplicas)
+	}
+	patch := fmt.Sprintf(`{"spec":{"minReplicas":%s,"maxReplicas":%d}}`, minValue, maxReplicas)
+	_, err := k.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).
+		Patch(context.TODO(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
@@ -6,26 +6,46 @@ import (
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// cidrRefreshDelay wait for more changes before re-calculating cluster CIDR
+const cidrRefreshDelay = 10 * time.Second
+
 // ClusterCidr get cluster CIDR
 func (k *Kubernetes) ClusterCidr(namespace string) ([]string, []string) {
-	ips := getServiceIps(k.Clientset, namespace)
-	log.Debug().Msgf("Found %d IPs", len(ips))
-	svcCidr := calculateMinimalIpRange(ips)
-	log.Debug().Msgf("Service ips are: %v", ips)
+	svcIps := getServiceIps(k.Clientset, namespace)
+	var podIps []string
+	if !opt.Get().Connect.DisablePodIp {
+		podIps = getPodIps(k.Clientset, namespace)
+		// pod ip range of node without any pod yet
+		podIps = append(podIps, getNodePodCidrs(k.Clientset)...)
+	}
+	return calculateClusterCidr(svcIps, podIps)
+}
+
+// calculateClusterCidr get cluster CIDR and excluded CIDR from service ips and pod ips
+func calculateClusterCidr(svcIps, podIps []string) ([]string, []string) {
+	log.Debug().Msgf("Found %d IPs", len(svcIps))
+	svcCidr := calculateMinimalIpRange(svcIps)
+	log.Debug().Msgf("Service ips are: %v", svcIps)
 	log.Debug().Msgf("Service CIDR are: %v", svcCidr)
 
 	var podCidr []string
 	if !opt.Get().Connect.DisablePodIp {
-		ips = getPodIps(k.Clientset, namespace)
-		log.Debug().Msgf("Found %d IPs", len(ips))
-		podCidr = calculateMinimalIpRange(ips)
-		log.Debug().Msgf("Pod ips are: %v", ips)
+		log.Debug().Msgf("Found %d IPs", len(podIps))
+		podCidr = calculateMinimalIpRange(podIps)
+		log.Debug().Msgf("Pod ips are: %v", podIps)
 		log.Debug().Msgf("Pod CIDR are: %v", podCidr)
 	}
 
@@ -91,6 +111,244 @@ func (k *Kubernetes) ClusterCidr(namespace string) ([]string, []string) {
 	return cidr, excludeCidr
 }
 
+// WatchClusterCidr re-calculate cluster CIDR after pods, services or nodes changed, and invoke onChange when it's
+// different from the current one, changes happen in short period are merged
+func (k *Kubernetes) WatchClusterCidr(namespace string, cidr []string, onChange func(cidr, excludeCidr []string)) {
+	// ips are kept locally from watch events, to avoid listing all resources of cluster on every change
+	tracker := &cidrTracker{
+		cidr:      cidr,
+		svcIps:    map[string]string{},
+		podIps:    map[string]string{},
+		nodeCidrs: map[string][]string{},
+		refresh:   make(chan struct{}, 1),
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	var synced []cache.InformerSynced
+
+	// watch current namespace only without cluster level permission, same as calculating cluster CIDR
+	svcScope := ""
+	if _, err := k.Clientset.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{
+		Limit:          1,
+		TimeoutSeconds: &apiTimeout,
+	}); err != nil {
+		svcScope = namespace
+	}
+	synced = append(synced, k.startInformer("", svcScope, string(coreV1.ResourceServices), &coreV1.Service{}, stop,
+		func(obj any) {
+			tracker.onServiceChanged(obj)
+		}, func(obj any) {
+			tracker.onRemoved(tracker.svcIps, obj)
+		}, func(obj any) {
+			tracker.onServiceChanged(obj)
+		}))
+	if !opt.Get().Connect.DisablePodIp {
+		podScope := ""
+		if _, err := k.Clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+			Limit:          1,
+			TimeoutSeconds: &apiTimeout,
+		}); err != nil {
+			podScope = namespace
+		}
+		synced = append(synced, k.startInformer("", podScope, string(coreV1.ResourcePods), &coreV1.Pod{}, stop,
+			func(obj any) {
+				tracker.onPodChanged(obj)
+			}, func(obj any) {
+				tracker.onRemoved(tracker.podIps, obj)
+			}, func(obj any) {
+				tracker.onPodChanged(obj)
+			}))
+		if _, err := k.Clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+			Limit:          1,
+			TimeoutSeconds: &apiTimeout,
+		}); err == nil {
+			synced = append(synced, k.startInformer("", "", "nodes", &coreV1.Node{}, stop,
+				func(obj any) {
+					tracker.onNodeChanged(obj)
+				}, func(obj any) {
+					tracker.onNodeRemoved(obj)
+				}, func(obj any) {
+					tracker.onNodeChanged(obj)
+				}))
+		}
+	}
+
+	for range tracker.refresh {
+		time.Sleep(cidrRefreshDelay)
+		// ranges calculated from partially listed resources would drop routes in use
+		if !cache.WaitForCacheSync(stop, synced...) {
+			continue
+		}
+		if latest, excludeCidr, changed := tracker.recalculate(); changed {
+			log.Debug().Msgf("Cluster CIDR changed to %v", latest)
+			onChange(latest, excludeCidr)
+		}
+	}
+}
+
+// cidrTracker keep ips of services, pods and nodes, keyed by namespace/name
+type cidrTracker struct {
+	lock      sync.Mutex
+	cidr      []string
+	svcIps    map[string]string
+	podIps    map[string]string
+	nodeCidrs map[string][]string
+	refresh   chan struct{}
+}
+
+func (t *cidrTracker) triggerRefresh() {
+	select {
+	case t.refresh <- struct{}{}:
+	default:
+		// refresh already pending
+	}
+}
+
+func (t *cidrTracker) onServiceChanged(obj any) {
+	if svc, ok := obj.(*coreV1.Service); ok {
+		t.onIpChanged(t.svcIps, resourceKey(obj), svc.Spec.ClusterIP)
+	}
+}
+
+func (t *cidrTracker) onPodChanged(obj any) {
+	if pod, ok := obj.(*coreV1.Pod); ok {
+		t.onIpChanged(t.podIps, resourceKey(obj), pod.Status.PodIP)
+	}
+}
+
+// onIpChanged record ip of resource, only ip outside current ranges could change the ranges
+func (t *cidrTracker) onIpChanged(ips map[string]string, key, ip string) {
+	if ip == "" || ip == "None" {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if ips[key] == ip {
+		return
+	}
+	ips[key] = ip
+	if !isIpCovered(t.cidr, ip) {
+		t.triggerRefresh()
+	}
+}
+
+// onRemoved forget ip of resource, ranges could only shrink when no other resource uses ip in the same range
+func (t *cidrTracker) onRemoved(ips map[string]string, obj any) {
+	key := resourceKey(obj)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ip, exists := ips[key]
+	if !exists {
+		return
+	}
+	delete(ips, key)
+	if t.isRangeStillUsed(ip) {
+		return
+	}
+	t.triggerRefresh()
+}
+
+func (t *cidrTracker) onNodeChanged(obj any) {
+	node, ok := obj.(*coreV1.Node)
+	if !ok {
+		return
+	}
+	podCidrs := getNodePodCidr(node)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.nodeCidrs[resourceKey(obj)] = podCidrs
+	for _, podCidr := range podCidrs {
+		if !isIpCovered(t.cidr, podCidr) {
+			t.triggerRefresh()
+			return
+		}
+	}
+}
+
+func (t *cidrTracker) onNodeRemoved(obj any) {
+	key := resourceKey(obj)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, exists := t.nodeCidrs[key]; exists {
+		delete(t.nodeCidrs, key)
+		t.triggerRefresh()
+	}
+}
+
+// isRangeStillUsed check whether the range covering ip is still used by any other service, pod or node
+func (t *cidrTracker) isRangeStillUsed(ip string) bool {
+	for _, r := range t.cidr {
+		if !isPartOfRange(r, ip+"/32") {
+			continue
+		}
+		for _, ips := range []map[string]string{t.svcIps, t.podIps} {
+			for _, other := range ips {
+				if isPartOfRange(r, other+"/32") {
+					return true
+				}
+			}
+		}
+		for _, podCidrs := range t.nodeCidrs {
+			for _, podCidr := range podCidrs {
+				if isPartOfRange(r, podCidr) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// recalculate get cluster CIDR from recorded ips, and whether it differs from current one
+func (t *cidrTracker) recalculate() ([]string, []string, bool) {
+	t.lock.Lock()
+	svcIps := make([]string, 0, len(t.svcIps))
+	for _, ip := range t.svcIps {
+		svcIps = append(svcIps, ip)
+	}
+	podIps := make([]string, 0, len(t.podIps))
+	for _, ip := range t.podIps {
+		podIps = append(podIps, ip)
+	}
+	for _, podCidrs := range t.nodeCidrs {
+		podIps = append(podIps, podCidrs...)
+	}
+	t.lock.Unlock()
+
+	// keep calculation order stable, since minimal ranges depend on the order of ips
+	sort.Strings(svcIps)
+	sort.Strings(podIps)
+	latest, excludeCidr := calculateClusterCidr(svcIps, podIps)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if util.ArrayEquals(t.cidr, latest) {
+		return nil, nil, false
+	}
+	t.cidr = latest
+	return latest, excludeCidr, true
+}
+
+// resourceKey get namespace/name key of resource, including resource in deleted state
+func resourceKey(obj any) string {
+	key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	return key
+}
+
+// isIpCovered check whether ip or ip range is inside any of the ranges
+func isIpCovered(ipRanges []string, ip string) bool {
+	if !strings.Contains(ip, "/") {
+		ip = ip + "/32"
+	}
+	for _, r := range ipRanges {
+		if isPartOfRange(r, ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func mergeIpRange(svcCidr []string, podCidr []string, apiServerIp string) []string {
 	cidr := calculateMinimalIpRange(append(svcCidr, podCidr...))
 	mergedCidr := make([]string, 0)
@@ -163,57 +421,79 @@ func isPartOfRange(ipRange string, subIpRange string) bool {
 }
 
 func getPodIps(k kubernetes.Interface, namespace string) []string {
-	podList, err := k.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-		Limit:          1000,
-		TimeoutSeconds: &apiTimeout,
-	})
-	if err != nil {
-		podList, err = k.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
-			Limit:          1000,
-			TimeoutSeconds: &apiTimeout,
-		})
-		if err != nil {
-			log.Warn().Err(err).Msgf("Failed to fetch pod ips")
-			return []string{}
-		}
-	}
-
 	var ips []string
-	for _, pod := range podList.Items {
-		if pod.Status.PodIP != "" && pod.Status.PodIP != "None" {
+	collectPodIp := func(obj runtime.Object) error {
+		if pod, ok := obj.(*coreV1.Pod); ok && pod.Status.PodIP != "" && pod.Status.PodIP != "None" {
 			ips = append(ips, pod.Status.PodIP)
 		}
+		return nil
+	}
+	if err := listAllPages(k.CoreV1().Pods("").List, collectPodIp); err != nil {
+		ips = nil
+		if err = listAllPages(k.CoreV1().Pods(namespace).List, collectPodIp); err != nil {
+			log.Warn().Err(err).Msgf("Failed to fetch pod ips")
+			return []string{}
+		}
 	}
-
 	return ips
 }
 
-func getServiceIps(k kubernetes.Interface, namespace string) []string {
-	serviceList, err := k.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{
-		Limit:          1000,
+func getNodePodCidrs(k kubernetes.Interface) []string {
+	nodeList, err := k.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
 		TimeoutSeconds: &apiTimeout,
 	})
 	if err != nil {
-		serviceList, err = k.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{
-			Limit:          1000,
-			TimeoutSeconds: &apiTimeout,
-		})
-		if err != nil {
-			log.Warn().Err(err).Msgf("Failed to fetch service ips")
-			return []string{}
-		}
+		// listing nodes requires cluster level permission
+		log.Debug().Err(err).Msgf("Failed to fetch node pod CIDR")
+		return []string{}
 	}
 
+	var cidrs []string
+	for _, node := range nodeList.Items {
+		cidrs = append(cidrs, getNodePodCidr(&node)...)
+	}
+	log.Debug().Msgf("Node pod CIDR are: %v", cidrs)
+	return cidrs
+}
+
+func getNodePodCidr(node *coreV1.Node) []string {
+	if len(node.Spec.PodCIDRs) > 0 {
+		return node.Spec.PodCIDRs
+	} else if node.Spec.PodCIDR != "" {
+		return []string{node.Spec.PodCIDR}
+	}
+	return []string{}
+}
+
+func getServiceIps(k kubernetes.Interface, namespace string) []string {
 	var ips []string
-	for _, service := range serviceList.Items {
-		if service.Spec.ClusterIP != "" && service.Spec.ClusterIP != "None" {
-			ips = append(ips, service.Spec.ClusterIP)
+	collectServiceIp := func(obj runtime.Object) error {
+		if svc, ok := obj.(*coreV1.Service); ok && svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != "None" {
+			ips = append(ips, svc.Spec.ClusterIP)
+		}
+		return nil
+	}
+	if err := listAllPages(k.CoreV1().Services("").List, collectServiceIp); err != nil {
+		ips = nil
+		if err = listAllPages(k.CoreV1().Services(namespace).List, collectServiceIp); err != nil {
+			log.Warn().Err(err).Msgf("Failed to fetch service ips")
+			return []string{}
 		}
 	}
-
 	return ips
 }
 
+// listAllPages list resources page by page, so that none of them is missed in large clusters
+func listAllPages[T runtime.Object](list func(context.Context, metav1.ListOptions) (T, error),
+	fn func(runtime.Object) error) error {
+	p := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		opts.TimeoutSeconds = &apiTimeout
+		return list(ctx, opts)
+	})
+	p.PageSize = 1000
+	return p.EachListItem(context.TODO(), metav1.ListOptions{}, fn)
+}
+
 func calculateMinimalIpv6Range(ips []string) []string {
 	var miniRange []string
 	for _, ip := range ips {
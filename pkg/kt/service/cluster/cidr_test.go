@@ -44,6 +44,22 @@ func TestKubernetes_ClusterCidr(t *testing.T) {
 			},
 			dropCidr: nil,
 		},
+		{
+			name: "shouldIncludePodCidrOfNode",
+			objs: []runtime.Object{
+				buildPod("default", "pod1", "image", "172.168.0.7", map[string]string{"label": "value"}),
+				buildService("default", "svc1", "192.168.0.18"),
+				buildService("default", "svc2", "192.168.1.18"),
+				buildNode("node1", "172.168.0.0/24"),
+				buildNode("node2", "172.169.3.0/24"),
+			},
+			wantCidr: []string{
+				"192.168.0.0/16",
+				"172.168.0.0/24",
+				"172.169.3.0/24",
+			},
+			dropCidr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -250,3 +266,51 @@ func buildPod(namespace, name, image string, ip string, labels map[string]string
 		},
 	}
 }
+
+func buildNode(name, podCidr string) *coreV1.Node {
+	return &coreV1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: coreV1.NodeSpec{
+			PodCIDR:  podCidr,
+			PodCIDRs: []string{podCidr},
+		},
+	}
+}
+
+func Test_isIpCovered(t *testing.T) {
+	ranges := []string{"10.96.0.0/16", "172.168.0.0/24"}
+	require.True(t, isIpCovered(ranges, "10.96.3.4"))
+	require.True(t, isIpCovered(ranges, "172.168.0.0/24"))
+	require.False(t, isIpCovered(ranges, "172.168.1.3"))
+	require.False(t, isIpCovered(ranges, "172.168.0.0/16"))
+}
+
+func Test_cidrTracker(t *testing.T) {
+	tracker := &cidrTracker{
+		cidr:      []string{"172.168.0.0/24", "192.168.0.0/16"},
+		svcIps:    map[string]string{"default/svc1": "192.168.0.18"},
+		podIps:    map[string]string{"default/pod1": "172.168.0.7", "default/pod2": "172.168.0.8"},
+		nodeCidrs: map[string][]string{},
+		refresh:   make(chan struct{}, 1),
+	}
+	pending := func() bool {
+		select {
+		case <-tracker.refresh:
+			return true
+		default:
+			return false
+		}
+	}
+
+	tracker.onPodChanged(buildPod("default", "pod3", "image", "172.168.0.9", map[string]string{}))
+	require.False(t, pending(), "covered ip should not trigger refresh")
+	tracker.onPodChanged(buildPod("default", "pod4", "image", "172.169.0.9", map[string]string{}))
+	require.True(t, pending(), "uncovered ip should trigger refresh")
+
+	tracker.onRemoved(tracker.podIps, buildPod("default", "pod1", "image", "172.168.0.7", map[string]string{}))
+	require.False(t, pending(), "range still used by other pods should not trigger refresh")
+	tracker.onRemoved(tracker.svcIps, buildService("default", "svc1", "192.168.0.18"))
+	require.True(t, pending(), "range no longer used should trigger refresh")
+	tracker.onRemoved(tracker.podIps, buildPod("default", "pod9", "image", "172.168.0.10", map[string]string{}))
+	require.False(t, pending(), "unknown resource should not trigger refresh")
+}
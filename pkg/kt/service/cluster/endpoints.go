@@ -0,0 +1,12 @@
+package cluster
+
+import (
+	"context"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetEndpoints get endpoints of service
+func (k *Kubernetes) GetEndpoints(name, namespace string) (*coreV1.Endpoints, error) {
+	return k.Clientset.CoreV1().Endpoints(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
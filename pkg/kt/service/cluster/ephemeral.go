@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// AddEphemeralContainer inject shadow as ephemeral container into specified pod
+func (k *Kubernetes) AddEphemeralContainer(containerName, podName, namespace string, envs map[string]string) error {
+	pod, err := k.GetPod(podName, namespace)
+	if err != nil {
+		return err
+	}
+	container := createContainer(opt.Get().Global.Image, []string{}, envs, map[string]int{}, nil)
+	// ephemeral container shares network namespace with the pod, NET_ADMIN is required for redirecting ports
+	container.SecurityContext.Capabilities.Add = append(container.SecurityContext.Capabilities.Add, "NET_ADMIN")
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, coreV1.EphemeralContainer{
+		EphemeralContainerCommon: coreV1.EphemeralContainerCommon{
+			Name:            containerName,
+			Image:           container.Image,
+			ImagePullPolicy: container.ImagePullPolicy,
+			Env:             container.Env,
+			SecurityContext: container.SecurityContext,
+		},
+	})
+	ctx, cancel := apiContext()
+	defer cancel()
+	_, err = k.Clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{})
+	return err
+}
+
+// WaitEphemeralContainerReady wait for ephemeral container running
+func (k *Kubernetes) WaitEphemeralContainerReady(containerName, podName, namespace string, timeoutSec int) (*coreV1.Pod, error) {
+	const interval = 3
+	for i := 0; i <= timeoutSec/interval; i++ {
+		pod, err := k.GetPod(podName, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != containerName {
+				continue
+			}
+			if status.State.Running != nil {
+				log.Info().Msgf("Ephemeral container %s in pod %s is ready", containerName, podName)
+				return pod, nil
+			} else if status.State.Terminated != nil {
+				return nil, fmt.Errorf("ephemeral container %s in pod %s terminated: %s",
+					containerName, podName, status.State.Terminated.Reason)
+			}
+		}
+		log.Info().Msgf("Waiting for ephemeral container %s in pod %s ...", containerName, podName)
+		time.Sleep(interval * time.Second)
+	}
+	return nil, fmt.Errorf("ephemeral container %s in pod %s failed to start", containerName, podName)
+}
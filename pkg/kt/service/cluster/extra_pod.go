@@ -10,7 +10,7 @@ import (
 )
 
 // CreateRouterPod create router pod
-func (k *Kubernetes) CreateRouterPod(name string, labels, annotations map[string]string, ports map[int]int) (*coreV1.Pod, error) {
+func (k *Kubernetes) CreateRouterPod(name, image string, labels, annotations map[string]string, ports map[int]int) (*coreV1.Pod, error) {
 	targetPorts := map[string]int{}
 	for _, remotePort := range ports {
 		targetPorts[fmt.Sprintf("router-%d", remotePort)] = remotePort
@@ -20,7 +20,7 @@ func (k *Kubernetes) CreateRouterPod(name string, labels, annotations map[string
 		Namespace:   opt.Get().Global.Namespace,
 		Labels:      labels,
 		Annotations: annotations,
-	}, opt.Get().Mesh.RouterImage, map[string]string{}, targetPorts, true}
+	}, image, map[string]string{}, targetPorts, true, nil, nil}
 	pod := createPod(metaAndSpec)
 	if _, err := k.Clientset.CoreV1().Pods(metaAndSpec.Meta.Namespace).
 		Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
@@ -38,7 +38,7 @@ func (k *Kubernetes) CreateRectifierPod(name string) (*coreV1.Pod, error) {
 		Namespace:   opt.Get().Global.Namespace,
 		Labels:      map[string]string{},
 		Annotations: map[string]string{},
-	}, opt.Get().Global.Image, map[string]string{}, map[string]int{}, true}
+	}, opt.Get().Global.Image, map[string]string{}, map[string]int{}, true, nil, nil}
 	pod := createPod(metaAndSpec)
 	pod.Spec.Containers[0].Command = []string{"tail", "-f", "/dev/null"}
 	if _, err := k.Clientset.CoreV1().Pods(metaAndSpec.Meta.Namespace).
@@ -0,0 +1,37 @@
+package cluster
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var httpRouteResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+
+// GetHTTPRoutes get all gateway api http routes in namespace
+func (k *Kubernetes) GetHTTPRoutes(namespace string) ([]unstructured.Unstructured, error) {
+	client, err := customResourceClient(httpRouteResource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	routes, err := client.List(ctx, metav1.ListOptions{
+		TimeoutSeconds: &apiTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return routes.Items, nil
+}
+
+// UpdateHTTPRoute update gateway api http route
+func (k *Kubernetes) UpdateHTTPRoute(route *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client, err := customResourceClient(httpRouteResource, route.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	return client.Update(ctx, route, metav1.UpdateOptions{})
+}
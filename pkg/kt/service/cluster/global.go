@@ -63,6 +63,18 @@ func (k *Kubernetes) GetKtResources(namespace string) ([]coreV1.Pod, []coreV1.Co
 // namespace: empty for all namespace
 // fAdd, fDel, fMod: nil for ignore
 func (k *Kubernetes) watchResource(name, namespace, resourceType string, objType runtime.Object, fAdd, fDel, fMod func(any)) {
+	stop := make(chan struct{})
+	defer close(stop)
+	k.startInformer(name, namespace, resourceType, objType, stop, fAdd, fDel, fMod)
+	for {
+		time.Sleep(1000 * time.Second)
+	}
+}
+
+// startInformer run informer of resource in background until stop channel closed, the returned function reports
+// whether initial list of resources has been delivered
+func (k *Kubernetes) startInformer(name, namespace, resourceType string, objType runtime.Object, stop <-chan struct{},
+	fAdd, fDel, fMod func(any)) cache.InformerSynced {
 	selector := fields.Nothing()
 	if name != "" {
 		selector = fields.OneTermEqualSelector("metadata.name", name)
@@ -83,13 +95,8 @@ func (k *Kubernetes) watchResource(name, namespace, resourceType string, objType
 			UpdateFunc: func(oldObj, newObj any) { fMod(newObj) },
 		},
 	)
-
-	stop := make(chan struct{})
-	defer close(stop)
 	go controller.Run(stop)
-	for {
-		time.Sleep(1000 * time.Second)
-	}
+	return controller.HasSynced
 }
 
 func isSingleIp(ipRange string) bool {
@@ -54,8 +54,13 @@ func createService(metaAndSpec *SvcMetaAndSpec) *coreV1.Service {
 	metaAndSpec.Meta.Labels = util.MergeMap(metaAndSpec.Meta.Labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
 
 	for srcPort, targetPort := range metaAndSpec.Ports {
+		protocol := coreV1.ProtocolTCP
+		if p, exists := metaAndSpec.Protocols[srcPort]; exists {
+			protocol = p
+		}
 		servicePorts = append(servicePorts, coreV1.ServicePort{
 			Name:       fmt.Sprintf("kt-%d", srcPort),
+			Protocol:   protocol,
 			Port:       int32(srcPort),
 			TargetPort: intstr.FromInt(targetPort),
 		})
@@ -126,7 +131,7 @@ func createPod(metaAndSpec *PodMetaAndSpec) *coreV1.Pod {
 		Spec: coreV1.PodSpec{
 			ServiceAccountName: opt.Get().Global.ServiceAccount,
 			Containers: []coreV1.Container{
-				createContainer(metaAndSpec.Image, []string{}, metaAndSpec.Envs, metaAndSpec.Ports),
+				createContainer(metaAndSpec.Image, []string{}, metaAndSpec.Envs, metaAndSpec.Ports, metaAndSpec.Protocols),
 			},
 		},
 	}
@@ -139,10 +144,34 @@ func createPod(metaAndSpec *PodMetaAndSpec) *coreV1.Pod {
 		pod.Spec.NodeSelector = util.String2Map(opt.Get().Global.NodeSelector)
 	}
 
+	if len(metaAndSpec.SpreadLabels) > 0 {
+		pod.Spec.Affinity = createSpreadAffinity(metaAndSpec.SpreadLabels)
+	}
+
 	return pod
 }
 
-func createContainer(image string, args []string, envs map[string]string, ports map[string]int) coreV1.Container {
+// createSpreadAffinity prefer not placing pods with the same labels on one node, but still schedulable on single node cluster
+func createSpreadAffinity(labels map[string]string) *coreV1.Affinity {
+	return &coreV1.Affinity{
+		PodAntiAffinity: &coreV1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []coreV1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: coreV1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: labels,
+						},
+						TopologyKey: "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+}
+
+func createContainer(image string, args []string, envs map[string]string, ports map[string]int,
+	protocols map[string]coreV1.Protocol) coreV1.Container {
 	var envVar []coreV1.EnvVar
 	for k, v := range envs {
 		envVar = append(envVar, coreV1.EnvVar{Name: k, Value: v})
@@ -176,9 +205,13 @@ func createContainer(image string, args []string, envs map[string]string, ports
 		addResourceLimit(&container, opt.Get().Global.PodQuota)
 	}
 	for name, port := range ports {
+		protocol := coreV1.ProtocolTCP
+		if p, exists := protocols[name]; exists {
+			protocol = p
+		}
 		container.Ports = append(container.Ports, coreV1.ContainerPort{
 			Name: name,
-			Protocol: coreV1.ProtocolTCP,
+			Protocol: protocol,
 			ContainerPort: int32(port),
 		})
 	}
@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
 	"k8s.io/client-go/kubernetes"
 	"reflect"
 	"testing"
@@ -38,3 +40,24 @@ func Test_getKubernetesClient(t *testing.T) {
 		})
 	}
 }
+
+func Test_createPodWithSpreadLabels(t *testing.T) {
+	pod := createPod(&PodMetaAndSpec{
+		Meta: &ResourceMeta{
+			Name:        "kt-connect-shadow-abcde-1",
+			Namespace:   "default",
+			Labels:      map[string]string{util.KtShadowGroup: "kt-connect-shadow-abcde"},
+			Annotations: map[string]string{},
+		},
+		SpreadLabels: map[string]string{util.KtShadowGroup: "kt-connect-shadow-abcde"},
+	})
+	terms := pod.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	require.Len(t, terms, 1)
+	require.Equal(t, "kubernetes.io/hostname", terms[0].PodAffinityTerm.TopologyKey)
+	require.Equal(t, "kt-connect-shadow-abcde", terms[0].PodAffinityTerm.LabelSelector.MatchLabels[util.KtShadowGroup])
+
+	pod = createPod(&PodMetaAndSpec{
+		Meta: &ResourceMeta{Name: "kt-connect-shadow-abcde", Namespace: "default"},
+	})
+	require.Nil(t, pod.Spec.Affinity)
+}
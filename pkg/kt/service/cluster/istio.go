@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// KindVirtualService istio virtual service
+	KindVirtualService = "VirtualService"
+	// KindDestinationRule istio destination rule
+	KindDestinationRule = "DestinationRule"
+)
+
+var istioResources = map[string]schema.GroupVersionResource{
+	KindVirtualService:  {Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"},
+	KindDestinationRule: {Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"},
+}
+
+// GetIstioRules get all istio rules of specified kind in namespace
+func (k *Kubernetes) GetIstioRules(kind, namespace string) ([]unstructured.Unstructured, error) {
+	client, err := istioClient(kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	rules, err := client.List(ctx, metav1.ListOptions{
+		TimeoutSeconds: &apiTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules.Items, nil
+}
+
+// CreateIstioRule create istio rule
+func (k *Kubernetes) CreateIstioRule(rule *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client, err := istioClient(rule.GetKind(), rule.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	return client.Create(ctx, rule, metav1.CreateOptions{})
+}
+
+// UpdateIstioRule update istio rule
+func (k *Kubernetes) UpdateIstioRule(rule *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client, err := istioClient(rule.GetKind(), rule.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	return client.Update(ctx, rule, metav1.UpdateOptions{})
+}
+
+// RemoveIstioRule remove istio rule
+func (k *Kubernetes) RemoveIstioRule(kind, name, namespace string) error {
+	client, err := istioClient(kind, namespace)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	return client.Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// IstioApiVersion get api version of istio rule kind
+func IstioApiVersion(kind string) string {
+	return istioResources[kind].GroupVersion().String()
+}
+
+func istioClient(kind, namespace string) (dynamic.ResourceInterface, error) {
+	gvr, ok := istioResources[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported istio resource kind '%s'", kind)
+	}
+	return customResourceClient(gvr, namespace)
+}
+
+func customResourceClient(gvr schema.GroupVersionResource, namespace string) (dynamic.ResourceInterface, error) {
+	if opt.Store.RestConfig == nil {
+		return nil, fmt.Errorf("kubernetes config is not initialized")
+	}
+	client, err := dynamic.NewForConfig(opt.Store.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client.Resource(gvr).Namespace(namespace), nil
+}
@@ -30,6 +30,10 @@ type PodMetaAndSpec struct {
 	Envs   map[string]string
 	Ports  map[string]int
 	IsLeaf bool
+	// Protocols protocol of ports by name, tcp if not specified
+	Protocols map[string]coreV1.Protocol
+	// SpreadLabels prefer scheduling on nodes without other pods having these labels
+	SpreadLabels map[string]string
 }
 
 // GetPod ...
@@ -103,6 +107,21 @@ func (k *Kubernetes) WatchPod(name, namespace string, fAdd, fDel, fMod func(*cor
 	)
 }
 
+// WatchPodUntil watch pod in background, until stop channel closed
+func (k *Kubernetes) WatchPodUntil(name, namespace string, stop <-chan struct{}, fAdd, fDel, fMod func(*coreV1.Pod)) {
+	k.startInformer(name, namespace, string(coreV1.ResourcePods), &coreV1.Pod{}, stop,
+		func(obj any) {
+			handlePodEvent(obj, "added", fAdd)
+		},
+		func(obj any) {
+			handlePodEvent(obj, "deleted", fDel)
+		},
+		func(obj any) {
+			handlePodEvent(obj, "modified", fMod)
+		},
+	)
+}
+
 func (k *Kubernetes) ExecInPod(containerName, podName, namespace string, cmd ...string) (string, string, error) {
 	req := k.Clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -0,0 +1,12 @@
+package cluster
+
+import (
+	"context"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetSecret get secret
+func (k *Kubernetes) GetSecret(name, namespace string) (*coreV1.Secret, error) {
+	return k.Clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
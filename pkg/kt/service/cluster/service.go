@@ -17,6 +17,8 @@ type SvcMetaAndSpec struct {
 	External  bool
 	Ports     map[int]int
 	Selectors map[string]string
+	// Protocols protocol of service ports, tcp if not specified
+	Protocols map[int]coreV1.Protocol
 }
 
 // GetService get service
@@ -0,0 +1,19 @@
+package cluster
+
+import (
+	"context"
+	authV1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreateServiceAccountToken request a short-lived token of service account, audiences default to api server if empty
+func (k *Kubernetes) CreateServiceAccountToken(name, namespace string, audiences []string,
+	expirationSeconds int64) (*authV1.TokenRequest, error) {
+	request := &authV1.TokenRequest{
+		Spec: authV1.TokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	return k.Clientset.CoreV1().ServiceAccounts(namespace).CreateToken(context.TODO(), name, request, metav1.CreateOptions{})
+}
@@ -3,6 +3,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/common"
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
@@ -10,14 +11,26 @@ import (
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
 	"strings"
+	"sync"
 )
 
+// shadowSpec everything required to recreate a shadow
+type shadowSpec struct {
+	metaAndSpec *PodMetaAndSpec
+	sshKeyMeta  *SSHkeyMeta
+	generator   *util.SSHGenerator
+}
+
+var shadowSpecs = make(map[string]*shadowSpec)
+var shadowSpecsLock sync.Mutex
+
 // GetOrCreateShadow create shadow pod or deployment
 func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs map[string]string, exposePorts string, portNameDict map[int]string) (
 	string, string, string, error) {
-	// record context data
-	opt.Store.Shadow = name
+	// record context data, connect may create multiple shadow replicas
+	opt.Store.Shadow = util.Append(opt.Store.Shadow, name)
 
 	// extra labels must be applied after origin labels
 	for key, val := range util.String2Map(opt.Get().Global.WithLabel) {
@@ -39,12 +52,22 @@ func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs ma
 	}
 
 	ports := map[string]int{}
+	protocols := map[string]coreV1.Protocol{}
 	if exposePorts != "" {
 		portPairs := strings.Split(exposePorts, ",")
 		for _, exposePort := range portPairs {
+			protocol, _, err := util.ParseExposeProtocol(exposePort)
+			if err != nil {
+				log.Warn().Err(err).Msgf("invalid port")
+				continue
+			}
 			_, port, err := util.ParsePortMapping(exposePort)
 			if err != nil {
 				log.Warn().Err(err).Msgf("invalid port")
+			} else if protocol == util.ProtocolUdp {
+				name = fmt.Sprintf("udp-%d", port)
+				ports[name] = port
+				protocols[name] = coreV1.ProtocolUDP
 			} else {
 				// TODO: assume port using http protocol for istio constraint, should support user-defined protocol
 				name = fmt.Sprintf("http-%d", port)
@@ -55,24 +78,89 @@ func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs ma
 			}
 		}
 	}
+	udpPorts := ""
+	for _, port := range util.FindUdpExposePorts(exposePorts) {
+		udpPorts = util.Append(udpPorts, strconv.Itoa(port))
+	}
+	if udpPorts != "" {
+		// udp datagrams are relayed by shadow process in pod
+		envs[common.EnvVarUdpPorts] = udpPorts
+	}
 
+	podMeta := PodMetaAndSpec{
+		Meta:  &resourceMeta,
+		Image: opt.Get().Global.Image,
+		Envs:  envs,
+		Ports: ports,
+		Protocols: protocols,
+	}
+	if group, exists := labels[util.KtShadowGroup]; exists {
+		podMeta.SpreadLabels = map[string]string{util.KtShadowGroup: group}
+	}
 	if opt.Store.Component == util.ComponentConnect && opt.Get().Connect.ShareShadow {
 		pod, generator, err2 := k.tryGetExistingShadows(&resourceMeta, &sshKeyMeta)
 		if err2 != nil {
 			return "", "", "", err2
 		}
 		if pod != nil && generator != nil {
+			recordShadowSpec(&podMeta, &sshKeyMeta, generator)
 			return pod.Status.PodIP, pod.Name, generator.PrivateKeyPath, nil
 		}
 	}
+	return k.createShadow(&podMeta, &sshKeyMeta)
+}
 
-	podMeta := PodMetaAndSpec{
-		Meta:  &resourceMeta,
-		Image: opt.Get().Global.Image,
-		Envs:  envs,
-		Ports: ports,
+// RecreateShadow create shadow pod or deployment again with the same spec, labels and ssh key, after the previous one
+// deleted or evicted, only shadow created by current process could be recreated
+func (k *Kubernetes) RecreateShadow(name string) (*coreV1.Pod, error) {
+	spec := getShadowSpec(name)
+	if spec == nil {
+		return nil, fmt.Errorf("shadow %s is not created by current process", name)
 	}
-	return k.createShadow(&podMeta, &sshKeyMeta)
+	namespace := spec.metaAndSpec.Meta.Namespace
+	sshcm := spec.sshKeyMeta.SshConfigMapName
+	if _, err := k.GetConfigMap(sshcm, namespace); k8sErrors.IsNotFound(err) {
+		if _, err = k.createConfigMapWithSshKey(spec.metaAndSpec.Meta.Labels, sshcm, namespace, spec.generator); err != nil {
+			return nil, err
+		}
+		log.Info().Msgf("Config map %s recreated", sshcm)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if opt.Get().Global.UseShadowDeployment {
+		if _, err := k.GetDeployment(name, namespace); k8sErrors.IsNotFound(err) {
+			// pods of existing deployment are re-scheduled automatically
+			if err = k.createShadowDeployment(spec.metaAndSpec, sshcm); err != nil && !k8sErrors.IsAlreadyExists(err) {
+				return nil, err
+			}
+			delete(spec.metaAndSpec.Meta.Labels, util.ControlBy)
+			log.Info().Msgf("Shadow deployment %s recreated", name)
+		}
+		pods, err := k.WaitPodsReady(spec.metaAndSpec.Meta.Labels, namespace, opt.Get().Global.PodCreationTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &pods[0], nil
+	}
+
+	if pod, err := k.GetPod(name, namespace); err == nil {
+		// evicted pod is kept until removed manually, and occupies the pod name
+		if pod.DeletionTimestamp == nil {
+			if err = k.RemovePod(name, namespace); err != nil {
+				return nil, err
+			}
+		}
+		if _, err = k.WaitPodTerminate(name, namespace); err != nil && !k8sErrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	// shared shadow pod may have been recreated by other user
+	if err := k.createShadowPod(spec.metaAndSpec, sshcm); err != nil && !k8sErrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	log.Info().Msgf("Shadow pod %s recreated", name)
+	return k.WaitPodReady(name, namespace, opt.Get().Global.PodCreationTimeout)
 }
 
 func (k *Kubernetes) createShadow(metaAndSpec *PodMetaAndSpec, sshKeyMeta *SSHkeyMeta) (
@@ -82,6 +170,7 @@ func (k *Kubernetes) createShadow(metaAndSpec *PodMetaAndSpec, sshKeyMeta *SSHke
 	if err != nil {
 		return
 	}
+	recordShadowSpec(metaAndSpec, sshKeyMeta, generator)
 
 	configMap, err := k.createConfigMapWithSshKey(metaAndSpec.Meta.Labels, sshKeyMeta.SshConfigMapName, metaAndSpec.Meta.Namespace, generator)
 	if err != nil {
@@ -227,6 +316,22 @@ func (k *Kubernetes) tryGetExistingShadows(resourceMeta *ResourceMeta, sshKeyMet
 	return pod, generator, nil
 }
 
+func recordShadowSpec(metaAndSpec *PodMetaAndSpec, sshKeyMeta *SSHkeyMeta, generator *util.SSHGenerator) {
+	shadowSpecsLock.Lock()
+	defer shadowSpecsLock.Unlock()
+	shadowSpecs[metaAndSpec.Meta.Name] = &shadowSpec{
+		metaAndSpec: metaAndSpec,
+		sshKeyMeta:  sshKeyMeta,
+		generator:   generator,
+	}
+}
+
+func getShadowSpec(name string) *shadowSpec {
+	shadowSpecsLock.Lock()
+	defer shadowSpecsLock.Unlock()
+	return shadowSpecs[name]
+}
+
 func getSSHVolume(volume string) coreV1.Volume {
 	sshVolume := coreV1.Volume{
 		Name: "ssh-public-key",
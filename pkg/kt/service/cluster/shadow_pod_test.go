@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8sTesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestKubernetes_RecreateShadow(t *testing.T) {
+	clientset := testclient.NewSimpleClientset()
+	// pod is considered running once created
+	clientset.PrependReactor("create", "pods", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8sTesting.CreateAction).GetObject().(*coreV1.Pod)
+		pod.Status.Phase = coreV1.PodRunning
+		return false, nil, nil
+	})
+	k := &Kubernetes{Clientset: clientset}
+
+	_, err := k.RecreateShadow("kt-connect-shadow-abcde")
+	require.Error(t, err)
+
+	labels := map[string]string{util.KtRole: util.RoleExchangeShadow, util.KtTarget: "abcde"}
+	recordShadowSpec(&PodMetaAndSpec{
+		Meta: &ResourceMeta{
+			Name:        "kt-connect-shadow-abcde",
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: map[string]string{},
+		},
+		Image: "shadow:latest",
+		Envs:  map[string]string{},
+	}, &SSHkeyMeta{
+		SshConfigMapName: "kt-connect-shadow-abcde",
+		PrivateKeyPath:   "/tmp/kt-connect-shadow-abcde.pem",
+	}, util.NewSSHGenerator("private-key", "public-key", "/tmp/kt-connect-shadow-abcde.pem"))
+
+	pod, err := k.RecreateShadow("kt-connect-shadow-abcde")
+	require.NoError(t, err)
+	require.Equal(t, "kt-connect-shadow-abcde", pod.Name)
+	require.Equal(t, "abcde", pod.Labels[util.KtTarget])
+	configMap, err := k.GetConfigMap("kt-connect-shadow-abcde", "default")
+	require.NoError(t, err)
+	require.Equal(t, "public-key", configMap.Data[util.SshAuthKey])
+	require.Equal(t, "private-key", configMap.Data[util.SshAuthPrivateKey])
+}
@@ -3,8 +3,11 @@ package cluster
 import (
 	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
 	appV1 "k8s.io/api/apps/v1"
+	authV1 "k8s.io/api/authentication/v1"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
 	coreV1 "k8s.io/api/core/v1"
 	extV1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -15,15 +18,19 @@ type KubernetesInterface interface {
 	UpdatePod(pod *coreV1.Pod) (*coreV1.Pod, error)
 	RemovePod(name, namespace string) error
 	GetOrCreateShadow(name string, labels, annotations, envs map[string]string, portsToExpose string, portNameDict map[int]string) (string, string, string, error)
-	CreateRouterPod(name string, labels, annotations map[string]string, ports map[int]int) (*coreV1.Pod, error)
+	RecreateShadow(name string) (*coreV1.Pod, error)
+	CreateRouterPod(name, image string, labels, annotations map[string]string, ports map[int]int) (*coreV1.Pod, error)
 	CreateRectifierPod(name string) (*coreV1.Pod, error)
 	UpdatePodHeartBeat(name, namespace string)
 	WaitPodReady(name, namespace string, timeoutSec int) (*coreV1.Pod, error)
 	WaitPodTerminate(name, namespace string) (*coreV1.Pod, error)
 	WatchPod(name, namespace string, fAdd, fDel, fMod func(*coreV1.Pod))
+	WatchPodUntil(name, namespace string, stop <-chan struct{}, fAdd, fDel, fMod func(*coreV1.Pod))
 	ExecInPod(containerName, podName, namespace string, cmd ...string) (string, string, error)
 	IncreasePodRef(name ,namespace string) error
 	DecreasePodRef(name, namespace string) (bool, error)
+	AddEphemeralContainer(containerName, podName, namespace string, envs map[string]string) error
+	WaitEphemeralContainerReady(containerName, podName, namespace string, timeoutSec int) (*coreV1.Pod, error)
 
 	GetDeployment(name string, namespace string) (*appV1.Deployment, error)
 	GetDeploymentsByLabel(labels map[string]string, namespace string) (*appV1.DeploymentList, error)
@@ -33,6 +40,12 @@ type KubernetesInterface interface {
 	IncreaseDeploymentRef(name ,namespace string) error
 	DecreaseDeploymentRef(name, namespace string) (bool, error)
 	ScaleTo(deployment, namespace string, replicas *int32) (err error)
+	GetWorkload(kind, name, namespace string) (*Workload, error)
+	GetAllWorkloadsInNamespace(namespace string) ([]Workload, error)
+	ScaleWorkload(kind, name, namespace string, replicas int32) error
+	GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error)
+	PatchHorizontalPodAutoscalerRange(name, namespace string, minReplicas *int32, maxReplicas int32) error
+	GetEndpoints(name, namespace string) (*coreV1.Endpoints, error)
 
 	GetService(name, namespace string) (*coreV1.Service, error)
 	GetServicesBySelector(matchLabels map[string]string, namespace string) ([]coreV1.Service, error)
@@ -49,11 +62,23 @@ type KubernetesInterface interface {
 	RemoveConfigMap(name, namespace string) (err error)
 	UpdateConfigMapHeartBeat(name, namespace string)
 
+	GetSecret(name, namespace string) (*coreV1.Secret, error)
+	CreateServiceAccountToken(name, namespace string, audiences []string, expirationSeconds int64) (*authV1.TokenRequest, error)
+
 	GetAllIngressInNamespace(namespace string) (*extV1.IngressList, error)
 
+	GetIstioRules(kind, namespace string) ([]unstructured.Unstructured, error)
+	CreateIstioRule(rule *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	UpdateIstioRule(rule *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	RemoveIstioRule(kind, name, namespace string) error
+
+	GetHTTPRoutes(namespace string) ([]unstructured.Unstructured, error)
+	UpdateHTTPRoute(route *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
 	GetKtResources(namespace string) ([]coreV1.Pod, []coreV1.ConfigMap, []appV1.Deployment, []coreV1.Service, error)
 	GetAllNamespaces() (*coreV1.NamespaceList, error)
 	ClusterCidr(namespace string) (cidr []string, excludeCidr []string)
+	WatchClusterCidr(namespace string, cidr []string, onChange func(cidr, excludeCidr []string))
 }
 
 // Kubernetes implements KubernetesInterface
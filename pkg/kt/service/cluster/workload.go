@@ -0,0 +1,261 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// KindDeployment kubernetes deployment
+	KindDeployment = "deployment"
+	// KindStatefulSet kubernetes stateful set
+	KindStatefulSet = "statefulset"
+	// KindDaemonSet kubernetes daemon set
+	KindDaemonSet = "daemonset"
+	// KindRollout argo rollout
+	KindRollout = "rollout"
+)
+
+var rolloutResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+
+// Workload common view of resources which manage pods
+type Workload struct {
+	Kind           string
+	Name           string
+	Namespace      string
+	Labels         map[string]string
+	Annotations    map[string]string
+	Selector       map[string]string
+	TemplateLabels map[string]string
+	Template       coreV1.PodTemplateSpec
+	Replicas       int32
+	ReadyReplicas  int32
+}
+
+// GetWorkload get deployment, stateful set, daemon set or argo rollout
+func (k *Kubernetes) GetWorkload(kind, name, namespace string) (*Workload, error) {
+	switch kind {
+	case KindDeployment:
+		app, err := k.GetDeployment(name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return fromDeployment(app), nil
+	case KindStatefulSet:
+		sts, err := k.Clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return fromStatefulSet(sts), nil
+	case KindDaemonSet:
+		ds, err := k.Clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return fromDaemonSet(ds), nil
+	case KindRollout:
+		client, err := customResourceClient(rolloutResource, namespace)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := apiContext()
+		defer cancel()
+		rollout, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return fromRollout(rollout), nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+// GetAllWorkloadsInNamespace get all deployments, stateful sets, daemon sets and argo rollouts in specified namespace
+func (k *Kubernetes) GetAllWorkloadsInNamespace(namespace string) ([]Workload, error) {
+	workloads := make([]Workload, 0)
+	apps, err := k.GetAllDeploymentInNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range apps.Items {
+		workloads = append(workloads, *fromDeployment(&apps.Items[i]))
+	}
+	options := metav1.ListOptions{TimeoutSeconds: &apiTimeout}
+	stsList, err := k.Clientset.AppsV1().StatefulSets(namespace).List(context.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stsList.Items {
+		workloads = append(workloads, *fromStatefulSet(&stsList.Items[i]))
+	}
+	dsList, err := k.Clientset.AppsV1().DaemonSets(namespace).List(context.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dsList.Items {
+		workloads = append(workloads, *fromDaemonSet(&dsList.Items[i]))
+	}
+	// argo rollouts crd may not installed
+	client, err := customResourceClient(rolloutResource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	if rollouts, err2 := client.List(ctx, options); err2 != nil {
+		log.Debug().Err(err2).Msgf("Skip looking up argo rollouts")
+	} else {
+		for i := range rollouts.Items {
+			workloads = append(workloads, *fromRollout(&rollouts.Items[i]))
+		}
+	}
+	return workloads, nil
+}
+
+// ScaleWorkload scale workload to specified replicas, daemon set is scaled to zero by patching a mismatched node selector
+func (k *Kubernetes) ScaleWorkload(kind, name, namespace string, replicas int32) error {
+	switch kind {
+	case KindDeployment:
+		return k.ScaleTo(name, namespace, &replicas)
+	case KindStatefulSet:
+		sts, err := k.Clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if sts.Spec.Replicas != nil && *sts.Spec.Replicas == replicas {
+			log.Warn().Msgf("StatefulSet %s already having %d replicas, not need to scale", name, replicas)
+			return nil
+		}
+		log.Info().Msgf("Scaling statefulset %s to %d", name, replicas)
+		sts.Spec.Replicas = &replicas
+		if _, err = k.Clientset.AppsV1().StatefulSets(namespace).Update(context.TODO(), sts, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	case KindDaemonSet:
+		// null value removes the node selector item
+		nodeSelector := "null"
+		if replicas == 0 {
+			nodeSelector = "\"true\""
+		}
+		patch := fmt.Sprintf(`{"spec":{"template":{"spec":{"nodeSelector":{"%s":%s}}}}}`, util.KtSuspend, nodeSelector)
+		log.Info().Msgf("Patching node selector of daemonset %s", name)
+		if _, err := k.Clientset.AppsV1().DaemonSets(namespace).
+			Patch(context.TODO(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	case KindRollout:
+		client, err := customResourceClient(rolloutResource, namespace)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := apiContext()
+		defer cancel()
+		patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)
+		log.Info().Msgf("Scaling rollout %s to %d", name, replicas)
+		if _, err = client.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+	log.Info().Msgf("%s %s successfully scaled to %d replicas", kind, name, replicas)
+	return nil
+}
+
+func fromDeployment(app *appV1.Deployment) *Workload {
+	return &Workload{
+		Kind:           KindDeployment,
+		Name:           app.Name,
+		Namespace:      app.Namespace,
+		Labels:         app.Labels,
+		Annotations:    app.Annotations,
+		Selector:       matchLabelsOf(app.Spec.Selector),
+		TemplateLabels: app.Spec.Template.Labels,
+		Template:       app.Spec.Template,
+		Replicas:       replicasOf(app.Spec.Replicas),
+		ReadyReplicas:  app.Status.ReadyReplicas,
+	}
+}
+
+func fromStatefulSet(sts *appV1.StatefulSet) *Workload {
+	return &Workload{
+		Kind:           KindStatefulSet,
+		Name:           sts.Name,
+		Namespace:      sts.Namespace,
+		Labels:         sts.Labels,
+		Annotations:    sts.Annotations,
+		Selector:       matchLabelsOf(sts.Spec.Selector),
+		TemplateLabels: sts.Spec.Template.Labels,
+		Template:       sts.Spec.Template,
+		Replicas:       replicasOf(sts.Spec.Replicas),
+		ReadyReplicas:  sts.Status.ReadyReplicas,
+	}
+}
+
+func fromDaemonSet(ds *appV1.DaemonSet) *Workload {
+	return &Workload{
+		Kind:           KindDaemonSet,
+		Name:           ds.Name,
+		Namespace:      ds.Namespace,
+		Labels:         ds.Labels,
+		Annotations:    ds.Annotations,
+		Selector:       matchLabelsOf(ds.Spec.Selector),
+		TemplateLabels: ds.Spec.Template.Labels,
+		Template:       ds.Spec.Template,
+		Replicas:       ds.Status.DesiredNumberScheduled,
+		ReadyReplicas:  ds.Status.NumberReady,
+	}
+}
+
+func fromRollout(rollout *unstructured.Unstructured) *Workload {
+	selector, _, _ := unstructured.NestedStringMap(rollout.Object, "spec", "selector", "matchLabels")
+	templateLabels, _, _ := unstructured.NestedStringMap(rollout.Object, "spec", "template", "metadata", "labels")
+	replicas, found, _ := unstructured.NestedInt64(rollout.Object, "spec", "replicas")
+	if !found {
+		// same as default value of argo rollout
+		replicas = 1
+	}
+	readyReplicas, _, _ := unstructured.NestedInt64(rollout.Object, "status", "readyReplicas")
+	// rollout referring to a deployment via workloadRef has no template
+	var template coreV1.PodTemplateSpec
+	if rawTemplate, found, _ := unstructured.NestedMap(rollout.Object, "spec", "template"); found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawTemplate, &template); err != nil {
+			log.Debug().Err(err).Msgf("Failed to parse pod template of rollout %s", rollout.GetName())
+		}
+	}
+	return &Workload{
+		Kind:           KindRollout,
+		Name:           rollout.GetName(),
+		Namespace:      rollout.GetNamespace(),
+		Labels:         rollout.GetLabels(),
+		Annotations:    rollout.GetAnnotations(),
+		Selector:       selector,
+		TemplateLabels: templateLabels,
+		Template:       template,
+		Replicas:       int32(replicas),
+		ReadyReplicas:  int32(readyReplicas),
+	}
+}
+
+func matchLabelsOf(selector *metav1.LabelSelector) map[string]string {
+	if selector == nil {
+		return map[string]string{}
+	}
+	return selector.MatchLabels
+}
+
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"context"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestKubernetes_ScaleWorkload(t *testing.T) {
+	replicas := int32(3)
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(
+			&appV1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       appV1.StatefulSetSpec{Replicas: &replicas},
+			},
+			&appV1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			},
+		),
+	}
+
+	require.NoError(t, k.ScaleWorkload(KindStatefulSet, "db", "default", 0))
+	sts, err := k.GetWorkload(KindStatefulSet, "db", "default")
+	require.NoError(t, err)
+	require.Equal(t, int32(0), sts.Replicas)
+
+	require.NoError(t, k.ScaleWorkload(KindDaemonSet, "agent", "default", 0))
+	ds, err := k.Clientset.AppsV1().DaemonSets("default").Get(context.TODO(), "agent", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "true", ds.Spec.Template.Spec.NodeSelector[util.KtSuspend])
+
+	require.NoError(t, k.ScaleWorkload(KindDaemonSet, "agent", "default", 2))
+	ds, err = k.Clientset.AppsV1().DaemonSets("default").Get(context.TODO(), "agent", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, exists := ds.Spec.Template.Spec.NodeSelector[util.KtSuspend]
+	require.False(t, exists)
+
+	require.Error(t, k.ScaleWorkload("job", "batch", "default", 0))
+}
@@ -0,0 +1,52 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// GetStatus query status of specified connect process
+func GetStatus(pid int) (*ConnectStatus, error) {
+	resp, err := newClient(pid).Get("http://ktctl" + pathStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query status, control api responded %d", resp.StatusCode)
+	}
+	var status ConnectStatus
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Disconnect ask specified connect process to disconnect and exit
+func Disconnect(pid int) error {
+	resp, err := newClient(pid).Post("http://ktctl"+pathDisconnect, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to disconnect, control api responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newClient(pid int) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", SocketFile(pid))
+			},
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package control
+
+import (
+	"encoding/json"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Serve expose control api of current connect process via unix socket
+func Serve(getStatus func() *ConnectStatus, disconnect func()) error {
+	socketFile := SocketFile(os.Getpid())
+	_ = os.Remove(socketFile)
+	listener, err := net.Listen("unix", socketFile)
+	if err != nil {
+		return err
+	}
+	// allow querying status without sudo
+	_ = util.FixFileOwner(socketFile)
+	go func() {
+		if err2 := http.Serve(listener, newHandler(getStatus, disconnect)); err2 != nil {
+			log.Debug().Err(err2).Msgf("Control api stopped")
+		}
+	}()
+	log.Debug().Msgf("Control api listening on %s", socketFile)
+	return nil
+}
+
+// RemoveSocket delete socket file of current connect process
+func RemoveSocket() {
+	_ = os.Remove(SocketFile(os.Getpid()))
+}
+
+func newHandler(getStatus func() *ConnectStatus, disconnect func()) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathStatus, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(getStatus())
+	})
+	mux.HandleFunc(pathDisconnect, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		log.Info().Msgf("Disconnect requested via control api")
+		w.WriteHeader(http.StatusAccepted)
+		go disconnect()
+	})
+	return mux
+}
@@ -0,0 +1,42 @@
+package control
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestControlHandler(t *testing.T) {
+	disconnected := make(chan bool, 1)
+	handler := newHandler(func() *ConnectStatus {
+		return &ConnectStatus{Pid: 123, Shadow: "kt-connect-shadow-abcde", Routes: []string{"10.0.0.0/16"}, Health: HealthHealthy}
+	}, func() {
+		disconnected <- true
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pathStatus, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status ConnectStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Equal(t, 123, status.Pid)
+	require.Equal(t, "kt-connect-shadow-abcde", status.Shadow)
+	require.Equal(t, []string{"10.0.0.0/16"}, status.Routes)
+	require.Equal(t, HealthHealthy, status.Health)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pathDisconnect, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, pathDisconnect, nil))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Errorf("disconnect not triggered")
+	}
+}
@@ -0,0 +1,59 @@
+package control
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+)
+
+const (
+	// HealthConnecting connection not established yet
+	HealthConnecting = "connecting"
+	// HealthHealthy connection to shadow pod works well
+	HealthHealthy = "healthy"
+	// HealthDegraded connection to shadow pod established, but heartbeat failed
+	HealthDegraded = "degraded"
+	// HealthReconnecting connection to shadow pod broken, and waiting for reconnect
+	HealthReconnecting = "reconnecting"
+	// HealthFailed connection to shadow pod broken, and retry budget exhausted
+	HealthFailed = "failed"
+
+	pathStatus     = "/status"
+	pathDisconnect = "/disconnect"
+)
+
+// ConnectStatus running state of a connect process, exposed via control api
+type ConnectStatus struct {
+	Pid       int    `json:"pid"`
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Mode      string `json:"mode"`
+	// Rootless services are accessible via local ports, instead of tun device and dns
+	Rootless bool   `json:"rootless,omitempty"`
+	DnsMode  string `json:"dnsMode"`
+	// DomainSuffix extra domain suffix of services, only for connect process started with --domainSuffix
+	DomainSuffix string `json:"domainSuffix,omitempty"`
+	Shadow       string `json:"shadow"`
+	ShadowIp     string `json:"shadowIp"`
+	// TunName and ProxyPort only available in tun2socks mode
+	TunName   string `json:"tunName,omitempty"`
+	ProxyPort int    `json:"proxyPort,omitempty"`
+	// HttpProxyPort only available when tun device is disabled
+	HttpProxyPort int      `json:"httpProxyPort,omitempty"`
+	Routes        []string `json:"routes"`
+	// ReconnectCount times of connection to shadow pod re-established
+	ReconnectCount int `json:"reconnectCount"`
+	// Health the worst state of all connections
+	Health string `json:"health"`
+	// Connections state of each connection to shadow pod
+	Connections map[string]string `json:"connections,omitempty"`
+	// LastError reason of last time any connection broken
+	LastError string `json:"lastError,omitempty"`
+	// Ready whether network and dns setup finished
+	Ready     bool  `json:"ready"`
+	StartTime int64 `json:"startTime"`
+}
+
+// SocketFile unix socket of control api served by specified connect process
+func SocketFile(pid int) string {
+	return fmt.Sprintf("%s/%s-%d.sock", util.KtPidDir, util.ComponentConnect, pid)
+}
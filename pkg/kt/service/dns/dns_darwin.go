@@ -29,29 +29,7 @@ func SetNameServer(dnsServer string) error {
 		return err
 	}
 	go func() {
-		var nsList []string
-		namespaces, err := cluster.Ins().GetAllNamespaces()
-		if err != nil {
-			log.Info().Msgf("Cannot list all namespaces, set dns for '%s' only", opt.Get().Global.Namespace)
-			nsList = append(nsList, opt.Get().Global.Namespace)
-		} else {
-			for _, ns := range namespaces.Items {
-				nsList = append(nsList, ns.Name)
-			}
-		}
-
-		preferredDnsInfo := strings.Split(dnsServer, ":")
-		dnsIp := preferredDnsInfo[0]
-		dnsPort := strconv.Itoa(common.StandardDnsPort)
-		if len(preferredDnsInfo) > 1 {
-			dnsPort = preferredDnsInfo[1]
-		}
-
-		createResolverFile("local", opt.Get().Connect.ClusterDomain, dnsIp, dnsPort)
-		createResolverFile("svc.local", "svc", dnsIp, dnsPort)
-		for _, ns := range nsList {
-			createResolverFile(fmt.Sprintf("%s.local", ns), ns, dnsIp, dnsPort)
-		}
+		createClusterResolverFiles(dnsServer)
 		dnsSignal <- nil
 
 		defer RestoreNameServer()
@@ -62,6 +40,13 @@ func SetNameServer(dnsServer string) error {
 	return <-dnsSignal
 }
 
+// UpdateNameServer replace dns server set before, e.g. when previous one is no longer available
+func UpdateNameServer(dnsServer string) error {
+	// existing resolver files are overwritten
+	createClusterResolverFiles(dnsServer)
+	return nil
+}
+
 // HandleExtraDomainMapping handle extra domain change
 func HandleExtraDomainMapping(extraDomains map[string]string, localDnsPort int) {
 	for _, suffix := range getAllDomainSuffixes(extraDomains) {
@@ -74,11 +59,37 @@ func HandleExtraDomainMapping(extraDomains map[string]string, localDnsPort int)
 	}
 }
 
+// SetDomainNameServer resolve domains with specified suffix via specified dns server
+func SetDomainNameServer(suffix, dnsServer string) error {
+	if err := util.CreateDirIfNotExist(resolverDir); err != nil {
+		log.Error().Err(err).Msgf("Failed to create resolver dir")
+		return err
+	}
+	dnsInfo := strings.Split(dnsServer, ":")
+	createResolverFile(fmt.Sprintf("%s.local", suffix), suffix, dnsInfo[0], dnsInfo[1])
+	return nil
+}
+
+// RestoreDomainNameServer remove the nameserver of specified domain suffix
+func RestoreDomainNameServer(suffix string) {
+	resolverFile := fmt.Sprintf("%s/%s%s.local", resolverDir, ktResolverPrefix, suffix)
+	if err := os.Remove(resolverFile); err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Msgf("Failed to remove resolver file %s", resolverFile)
+	}
+}
+
 // RestoreNameServer remove the nameservers added by ktctl
 func RestoreNameServer() {
+	// resolver files of connect processes serving other clusters should be kept
+	filesToKeep := make([]string, 0)
+	for _, session := range util.GetConnectSessions() {
+		if session.DomainSuffix != "" {
+			filesToKeep = append(filesToKeep, fmt.Sprintf("%s%s.local", ktResolverPrefix, session.DomainSuffix))
+		}
+	}
 	rd, _ := ioutil.ReadDir(resolverDir)
 	for _, f := range rd {
-		if !f.IsDir() && strings.HasPrefix(f.Name(), ktResolverPrefix) {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), ktResolverPrefix) && !util.Contains(filesToKeep, f.Name()) {
 			if err := os.Remove(fmt.Sprintf("%s/%s", resolverDir, f.Name())); err != nil {
 				log.Warn().Err(err).Msgf("Failed to remove resolver file %s", f.Name())
 			}
@@ -86,6 +97,32 @@ func RestoreNameServer() {
 	}
 }
 
+func createClusterResolverFiles(dnsServer string) {
+	var nsList []string
+	namespaces, err := cluster.Ins().GetAllNamespaces()
+	if err != nil {
+		log.Info().Msgf("Cannot list all namespaces, set dns for '%s' only", opt.Get().Global.Namespace)
+		nsList = append(nsList, opt.Get().Global.Namespace)
+	} else {
+		for _, ns := range namespaces.Items {
+			nsList = append(nsList, ns.Name)
+		}
+	}
+
+	preferredDnsInfo := strings.Split(dnsServer, ":")
+	dnsIp := preferredDnsInfo[0]
+	dnsPort := strconv.Itoa(common.StandardDnsPort)
+	if len(preferredDnsInfo) > 1 {
+		dnsPort = preferredDnsInfo[1]
+	}
+
+	createResolverFile("local", opt.Get().Connect.ClusterDomain, dnsIp, dnsPort)
+	createResolverFile("svc.local", "svc", dnsIp, dnsPort)
+	for _, ns := range nsList {
+		createResolverFile(fmt.Sprintf("%s.local", ns), ns, dnsIp, dnsPort)
+	}
+}
+
 func createResolverFile(postfix, domain, dnsIp, dnsPort string) {
 	resolverFile := fmt.Sprintf("%s/%s%s", resolverDir, ktResolverPrefix, postfix)
 	if _, err := os.Stat(resolverFile); err == nil {
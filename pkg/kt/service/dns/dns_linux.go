@@ -47,11 +47,28 @@ func SetNameServer(dnsServer string) error {
 	return <-dnsSignal
 }
 
+// SetDomainNameServer resolve domains with specified suffix via specified dns server
+func SetDomainNameServer(suffix, dnsServer string) error {
+	// dns server in resolv.conf is unique, domain is forwarded by local dns of connect process which takes it over
+	return nil
+}
+
+// RestoreDomainNameServer remove the nameserver of specified domain suffix
+func RestoreDomainNameServer(suffix string) {
+	// pass
+}
+
 // HandleExtraDomainMapping handle extra domain change
 func HandleExtraDomainMapping(extraDomains map[string]string, localDnsPort int) {
 	// pass
 }
 
+// UpdateNameServer replace dns server set before, e.g. when previous one is no longer available
+func UpdateNameServer(dnsServer string) error {
+	restoreResolvConf()
+	return setupResolvConf(dnsServer)
+}
+
 // RestoreNameServer remove the nameservers added by ktctl
 func RestoreNameServer() {
 	restoreResolvConf()
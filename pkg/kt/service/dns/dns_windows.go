@@ -3,6 +3,7 @@ package dns
 import (
 	"fmt"
 	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/tun"
 	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"os/exec"
@@ -18,19 +19,28 @@ func SetNameServer(dnsServer string) (err error) {
 		"ipv4",
 		"set",
 		"interface",
-		util.TunNameWin,
+		tun.Ins().GetName(),
 		"metric=2",
 	)); err != nil {
 		log.Error().Msgf("Failed to set tun device order")
 		return err
 	}
+	return setTunDnsServer(dnsServer)
+}
+
+// UpdateNameServer replace dns server set before, e.g. when previous one is no longer available
+func UpdateNameServer(dnsServer string) error {
+	return setTunDnsServer(dnsServer)
+}
+
+func setTunDnsServer(dnsServer string) (err error) {
 	// run command: netsh interface ip set dnsservers name=KtConnectTunnel source=static address=8.8.8.8
 	if _, _, err = util.RunAndWait(exec.Command("netsh",
 		"interface",
 		"ipv4",
 		"set",
 		"dnsservers",
-		fmt.Sprintf("name=%s", util.TunNameWin),
+		fmt.Sprintf("name=%s", tun.Ins().GetName()),
 		"source=static",
 		fmt.Sprintf("address=%s", strings.Split(dnsServer, ":")[0]),
 	)); err != nil {
@@ -40,6 +50,17 @@ func SetNameServer(dnsServer string) (err error) {
 	return nil
 }
 
+// SetDomainNameServer resolve domains with specified suffix via specified dns server
+func SetDomainNameServer(suffix, dnsServer string) error {
+	// dns server in tun device is unique, domain is forwarded by local dns of connect process which takes it over
+	return nil
+}
+
+// RestoreDomainNameServer remove the nameserver of specified domain suffix
+func RestoreDomainNameServer(suffix string) {
+	// pass
+}
+
 // HandleExtraDomainMapping handle extra domain change
 func HandleExtraDomainMapping(extraDomains map[string]string, localDnsPort int) {
 	// pass
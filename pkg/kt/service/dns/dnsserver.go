@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,6 +21,13 @@ type DnsServer struct {
 	extraDomains map[string]string
 }
 
+// sessions of connect processes serving other clusters, reloaded periodically
+var (
+	connectSessions         []util.ConnectSession
+	connectSessionsLoadedAt int64
+	connectSessionsLock     sync.Mutex
+)
+
 func SetupLocalDns(remoteDnsPort, localDnsPort int, dnsOrder []string) error {
 	var res = make(chan error)
 	go func() {
@@ -132,6 +140,17 @@ func query(req *dns.Msg, dnsAddresses []string, extraDomains map[string]string)
 		}
 	}
 
+	if sessionDnsAddr := getSessionDnsAddress(domain, loadConnectSessions()); sessionDnsAddr != "" {
+		// domain belongs to another cluster, which is connected by another connect process
+		res, err := common.NsLookup(domain, qtype, "udp", sessionDnsAddr)
+		if res != nil {
+			return res.Answer
+		} else if err != nil && !common.IsDomainNotExist(err) {
+			log.Warn().Err(err).Msgf("Failed to lookup %s (%d) in dns (%s)", domain, qtype, sessionDnsAddr)
+		}
+		return []dns.RR{}
+	}
+
 	for _, dnsAddr := range dnsAddresses {
 		dnsParts := strings.SplitN(dnsAddr, ":", 3)
 		protocol := dnsParts[0]
@@ -157,6 +176,27 @@ func query(req *dns.Msg, dnsAddresses []string, extraDomains map[string]string)
 	return []dns.RR{}
 }
 
+func loadConnectSessions() []util.ConnectSession {
+	connectSessionsLock.Lock()
+	defer connectSessionsLock.Unlock()
+	if time.Now().Unix()-connectSessionsLoadedAt > 5 {
+		connectSessions = util.GetConnectSessions()
+		connectSessionsLoadedAt = time.Now().Unix()
+	}
+	return connectSessions
+}
+
+// getSessionDnsAddress get address of dns server serving the domain suffix, return empty if domain has no such suffix
+func getSessionDnsAddress(domain string, sessions []util.ConnectSession) string {
+	for _, session := range sessions {
+		if session.DomainSuffix != "" && session.DnsAddress != "" &&
+			strings.HasSuffix(domain, fmt.Sprintf(".%s.", session.DomainSuffix)) {
+			return session.DnsAddress
+		}
+	}
+	return ""
+}
+
 func wildcardMatch(pattenDomain, targetDomain string) bool {
 	if !strings.HasSuffix(pattenDomain, ".") {
 		pattenDomain = pattenDomain + "."
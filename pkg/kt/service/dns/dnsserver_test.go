@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"reflect"
 	"testing"
 )
@@ -108,3 +109,29 @@ func Test_wildcardMatch(t *testing.T) {
 		})
 	}
 }
+
+func Test_getSessionDnsAddress(t *testing.T) {
+	sessions := []util.ConnectSession{
+		{Pid: 1, Context: "prod"},
+		{Pid: 2, Context: "staging", DomainSuffix: "staging", DnsAddress: "127.0.0.1:10054"},
+		{Pid: 3, Context: "shared", DomainSuffix: "shared.io", DnsAddress: "127.0.0.1:10055"},
+	}
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"svc.ns.staging.", "127.0.0.1:10054"},
+		{"svc.ns.svc.cluster.local.staging.", "127.0.0.1:10054"},
+		{"svc.shared.io.", "127.0.0.1:10055"},
+		{"staging.", ""},
+		{"svc.ns.svc.cluster.local.", ""},
+		{"svc.mystaging.", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := getSessionDnsAddress(tt.domain, sessions); got != tt.want {
+				t.Errorf("getSessionDnsAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -127,12 +127,11 @@ func dumpHosts(hostsMap map[string]string, linesToKeep []string) []string {
 }
 
 func mergeLines(linesBefore []string, linesAfter []string) []string {
-	lines := make([]string, 0, len(linesBefore)+len(linesAfter)+1)
+	lines := make([]string, 0, len(linesBefore)+len(linesAfter)+2)
 	lines = append(lines, linesBefore...)
-	if len(linesBefore) > 0 && len(linesAfter) > 0 {
-		lines = append(lines, "")
-	}
+	lines = append(lines, "")
 	lines = append(lines, linesAfter...)
+	lines = append(lines, "")
 	return lines
 }
 
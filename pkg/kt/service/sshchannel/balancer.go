@@ -0,0 +1,141 @@
+package sshchannel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/wzshiming/sshproxy"
+	"golang.org/x/crypto/ssh"
+)
+
+// Balancer distribute proxy connections across ssh tunnels to multiple shadow pods
+type Balancer struct {
+	backends []*backend
+	next     int
+	lock     sync.Mutex
+}
+
+// backend ssh tunnel to one shadow pod
+type backend struct {
+	name      string
+	dial      dialFunc
+	close     func() error
+	available bool
+}
+
+// NewBalancer create balancer without any backend
+func NewBalancer() *Balancer {
+	return &Balancer{}
+}
+
+// Connect establish ssh tunnel as a backend of balancer, invoke ready() once it's usable, and block until it's broken
+func (b *Balancer) Connect(name, privateKey, sshAddress string, ready func()) error {
+	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
+	if err != nil {
+		return err
+	}
+	defer dialer.Close()
+
+	cli, err := dialer.SSHClient(context.Background())
+	if err != nil {
+		return err
+	}
+	b.add(name, cli.DialContext, cli.Close)
+	defer b.remove(name)
+	ready()
+	return cli.Wait()
+}
+
+// SetAvailable mark backend as available or not, unavailable backends are only used when no backend available
+func (b *Balancer) SetAvailable(name string, available bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, bk := range b.backends {
+		if bk.name == name {
+			bk.available = available
+		}
+	}
+}
+
+// DialContext dial via backends in turn, fail over to next backend if ssh tunnel of current one is broken
+func (b *Balancer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	candidates := b.candidates()
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no shadow pod available")
+	}
+	var err error
+	for _, bk := range candidates {
+		var conn net.Conn
+		if conn, err = bk.dial(ctx, network, address); err == nil {
+			return conn, nil
+		}
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) || ctx.Err() != nil {
+			// tunnel works, but target address is not reachable
+			return nil, err
+		}
+		log.Debug().Err(err).Msgf("Tunnel via %s broken, fail over to next shadow pod", bk.name)
+		b.SetAvailable(bk.name, false)
+		_ = bk.close()
+	}
+	return nil, err
+}
+
+// DialVia dial via specified backend only, e.g. for checking health of it
+func (b *Balancer) DialVia(name, network, address string) (net.Conn, error) {
+	b.lock.Lock()
+	var dial dialFunc
+	for _, bk := range b.backends {
+		if bk.name == name {
+			dial = bk.dial
+		}
+	}
+	b.lock.Unlock()
+	if dial == nil {
+		return nil, fmt.Errorf("tunnel via %s is not established", name)
+	}
+	return dial(context.Background(), network, address)
+}
+
+// candidates available backends starting from the next one in turn, followed by unavailable backends,
+// the turn only rotates among available backends, so that each of them gets an equal share
+func (b *Balancer) candidates() []*backend {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	available := make([]*backend, 0, len(b.backends))
+	unavailable := make([]*backend, 0)
+	for _, bk := range b.backends {
+		if bk.available {
+			available = append(available, bk)
+		} else {
+			unavailable = append(unavailable, bk)
+		}
+	}
+	if len(available) > 0 {
+		start := b.next % len(available)
+		available = append(available[start:], available[:start]...)
+	}
+	b.next++
+	return append(available, unavailable...)
+}
+
+func (b *Balancer) add(name string, dial dialFunc, close func() error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.backends = append(b.backends, &backend{name: name, dial: dial, close: close, available: true})
+}
+
+func (b *Balancer) remove(name string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i, bk := range b.backends {
+		if bk.name == name {
+			b.backends = append(b.backends[:i], b.backends[i+1:]...)
+			return
+		}
+	}
+}
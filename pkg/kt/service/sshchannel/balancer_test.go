@@ -0,0 +1,91 @@
+package sshchannel
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"testing"
+)
+
+func TestBalancerRoundRobin(t *testing.T) {
+	b := NewBalancer()
+	dialed := make([]string, 0)
+	for _, name := range []string{"shadow-1", "shadow-2", "shadow-3"} {
+		b.add(name, fakeDial(name, &dialed, nil), func() error { return nil })
+	}
+	b.SetAvailable("shadow-2", false)
+	for i := 0; i < 4; i++ {
+		conn, err := b.DialContext(context.Background(), "tcp", "tomcat:8080")
+		require.NoError(t, err)
+		_ = conn.Close()
+	}
+	require.Equal(t, []string{"shadow-1", "shadow-3", "shadow-1", "shadow-3"}, dialed)
+
+	// unavailable backend is still used when no other choice
+	b.SetAvailable("shadow-1", false)
+	b.SetAvailable("shadow-3", false)
+	dialed = dialed[:0]
+	conn, err := b.DialContext(context.Background(), "tcp", "tomcat:8080")
+	require.NoError(t, err)
+	_ = conn.Close()
+	require.Len(t, dialed, 1)
+}
+
+func TestBalancerFailover(t *testing.T) {
+	b := NewBalancer()
+	dialed := make([]string, 0)
+	closed := make([]string, 0)
+	for _, name := range []string{"shadow-1", "shadow-2"} {
+		var err error
+		if name == "shadow-1" {
+			err = fmt.Errorf("EOF")
+		}
+		n := name
+		b.add(name, fakeDial(name, &dialed, err), func() error {
+			closed = append(closed, n)
+			return nil
+		})
+	}
+	conn, err := b.DialContext(context.Background(), "tcp", "tomcat:8080")
+	require.NoError(t, err)
+	_ = conn.Close()
+	require.Equal(t, []string{"shadow-1", "shadow-2"}, dialed)
+	require.Equal(t, []string{"shadow-1"}, closed)
+	require.False(t, b.backends[0].available)
+	require.True(t, b.backends[1].available)
+}
+
+func TestBalancerTargetUnreachable(t *testing.T) {
+	b := NewBalancer()
+	dialed := make([]string, 0)
+	for _, name := range []string{"shadow-1", "shadow-2"} {
+		b.add(name, fakeDial(name, &dialed, &ssh.OpenChannelError{Reason: ssh.ConnectionFailed}),
+			func() error { return nil })
+	}
+	_, err := b.DialContext(context.Background(), "tcp", "tomcat:8080")
+	require.Error(t, err)
+	// no need to fail over, since tunnel is working
+	require.Len(t, dialed, 1)
+	require.True(t, b.backends[0].available)
+
+	b.remove("shadow-1")
+	b.remove("shadow-2")
+	_, err = b.DialContext(context.Background(), "tcp", "tomcat:8080")
+	require.Error(t, err)
+	_, err = b.DialVia("shadow-1", "tcp", "tomcat:8080")
+	require.Error(t, err)
+}
+
+func fakeDial(name string, dialed *[]string, err error) dialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		*dialed = append(*dialed, name)
+		if err != nil {
+			return nil, err
+		}
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+}
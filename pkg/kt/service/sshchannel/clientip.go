@@ -0,0 +1,148 @@
+package sshchannel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+	httpMethods      = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace}
+)
+
+// writeProxyHeader prepend proxy protocol header to local connection, so that local service could get client address
+func writeProxyHeader(local net.Conn, clientIpHeader string, src, dst net.Addr) error {
+	var header []byte
+	if clientIpHeader == util.ClientIpProxyV1 {
+		header = proxyV1Header(src, dst)
+	} else {
+		header = proxyV2Header(src, dst)
+	}
+	_, err := local.Write(header)
+	return err
+}
+
+func proxyV1Header(src, dst net.Addr) []byte {
+	srcAddr, dstAddr, ok := toTcpAddrPair(src, dst)
+	if !ok {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	family := "TCP4"
+	if srcAddr.IP.To4() == nil {
+		family = "TCP6"
+	}
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, srcAddr.IP, dstAddr.IP, srcAddr.Port, dstAddr.Port))
+}
+
+func proxyV2Header(src, dst net.Addr) []byte {
+	header := bytes.NewBuffer(proxyV2Signature)
+	srcAddr, dstAddr, ok := toTcpAddrPair(src, dst)
+	if !ok {
+		// LOCAL command without address
+		header.Write([]byte{0x20, 0x00, 0x00, 0x00})
+		return header.Bytes()
+	}
+	// PROXY command, with TCP over IPv4 or IPv6
+	srcIp, dstIp, family := srcAddr.IP.To4(), dstAddr.IP.To4(), byte(0x11)
+	if srcIp == nil {
+		srcIp, dstIp, family = srcAddr.IP.To16(), dstAddr.IP.To16(), 0x21
+	}
+	header.Write([]byte{0x21, family})
+	_ = binary.Write(header, binary.BigEndian, uint16(len(srcIp)*2+4))
+	header.Write(srcIp)
+	header.Write(dstIp)
+	_ = binary.Write(header, binary.BigEndian, uint16(srcAddr.Port))
+	_ = binary.Write(header, binary.BigEndian, uint16(dstAddr.Port))
+	return header.Bytes()
+}
+
+// toTcpAddrPair convert addresses to tcp address of same ip family
+func toTcpAddrPair(src, dst net.Addr) (*net.TCPAddr, *net.TCPAddr, bool) {
+	srcAddr, err := net.ResolveTCPAddr("tcp", src.String())
+	if err != nil || srcAddr.IP == nil {
+		return nil, nil, false
+	}
+	dstAddr, err := net.ResolveTCPAddr("tcp", dst.String())
+	if err != nil || dstAddr.IP == nil {
+		dstAddr = &net.TCPAddr{IP: net.IPv4zero}
+	}
+	if (srcAddr.IP.To4() == nil) != (dstAddr.IP.To4() == nil) {
+		// e.g. remote port listened on 0.0.0.0 but accessed via ipv6
+		if srcAddr.IP.To4() == nil {
+			dstAddr = &net.TCPAddr{IP: net.IPv6unspecified, Port: dstAddr.Port}
+		} else {
+			dstAddr = &net.TCPAddr{IP: net.IPv4zero, Port: dstAddr.Port}
+		}
+	}
+	return srcAddr, dstAddr, true
+}
+
+// copyWithForwardedFor copy http requests from client to local, with client ip appended to X-Forwarded-For header
+func copyWithForwardedFor(local io.Writer, client io.Reader, clientAddr net.Addr) error {
+	clientIp := clientAddr.String()
+	if host, _, err := net.SplitHostPort(clientIp); err == nil {
+		clientIp = host
+	}
+	reader := bufio.NewReader(client)
+	if !isHttpRequest(reader) {
+		log.Debug().Msgf("Not a http request, forwarding as it is")
+		_, err := io.Copy(local, reader)
+		return err
+	}
+	for {
+		req, err := http.ReadRequest(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			req.Header.Set("X-Forwarded-For", prior+", "+clientIp)
+		} else {
+			req.Header.Set("X-Forwarded-For", clientIp)
+		}
+		if _, exists := req.Header["User-Agent"]; !exists {
+			// avoid default user agent being added
+			req.Header["User-Agent"] = []string{""}
+		}
+		if err = req.Write(local); err != nil {
+			return err
+		}
+		// content after protocol switched is no longer http request
+		if req.Method == http.MethodConnect || strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade") {
+			_, err = io.Copy(local, reader)
+			return err
+		}
+	}
+}
+
+// isHttpRequest check whether data starts with a complete http method token, without consuming it
+func isHttpRequest(reader *bufio.Reader) bool {
+	for size := 1; ; size++ {
+		data, err := reader.Peek(size)
+		if err != nil {
+			return false
+		}
+		candidate := false
+		for _, method := range httpMethods {
+			prefix := method + " "
+			if string(data) == prefix {
+				return true
+			} else if strings.HasPrefix(prefix, string(data)) {
+				candidate = true
+			}
+		}
+		if !candidate {
+			return false
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package sshchannel
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+)
+
+func Test_proxyV1Header(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 52000}
+	dst := &net.TCPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8080}
+	require.Equal(t, "PROXY TCP4 10.0.0.5 0.0.0.0 52000 8080\r\n", string(proxyV1Header(src, dst)))
+	src6 := &net.TCPAddr{IP: net.ParseIP("fd00::5"), Port: 52000}
+	require.Equal(t, "PROXY TCP6 fd00::5 :: 52000 8080\r\n", string(proxyV1Header(src6, dst)))
+	require.Equal(t, "PROXY UNKNOWN\r\n", string(proxyV1Header(&net.UnixAddr{Name: "sock"}, dst)))
+}
+
+func Test_proxyV2Header(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 52000}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.9"), Port: 8080}
+	header := proxyV2Header(src, dst)
+	require.Equal(t, proxyV2Signature, header[:12])
+	require.Equal(t, []byte{0x21, 0x11, 0x00, 0x0c, 10, 0, 0, 5, 10, 0, 0, 9, 0xcb, 0x20, 0x1f, 0x90}, header[12:])
+}
+
+func Test_copyWithForwardedFor(t *testing.T) {
+	client := strings.NewReader("GET /a HTTP/1.1\r\nHost: tomcat\r\n\r\n" +
+		"POST /b HTTP/1.1\r\nHost: tomcat\r\nX-Forwarded-For: 1.1.1.1\r\nContent-Length: 2\r\n\r\nok")
+	var local bytes.Buffer
+	err := copyWithForwardedFor(&local, client, &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 52000})
+	require.NoError(t, err)
+	require.Equal(t, "GET /a HTTP/1.1\r\nHost: tomcat\r\nX-Forwarded-For: 10.0.0.5\r\n\r\n"+
+		"POST /b HTTP/1.1\r\nHost: tomcat\r\nContent-Length: 2\r\nX-Forwarded-For: 1.1.1.1, 10.0.0.5\r\n\r\nok",
+		local.String())
+
+	local.Reset()
+	err = copyWithForwardedFor(&local, strings.NewReader("PING\r\n"), &net.TCPAddr{IP: net.ParseIP("10.0.0.5")})
+	require.NoError(t, err)
+	require.Equal(t, "PING\r\n", local.String())
+}
+
+func Test_isHttpRequest(t *testing.T) {
+	require.True(t, isHttpRequest(bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\n"))))
+	require.True(t, isHttpRequest(bufio.NewReader(strings.NewReader("OPTIONS * HTTP/1.1\r\n"))))
+	require.False(t, isHttpRequest(bufio.NewReader(strings.NewReader("G"))))
+	require.False(t, isHttpRequest(bufio.NewReader(strings.NewReader("GETX"))))
+	require.False(t, isHttpRequest(bufio.NewReader(strings.NewReader("PING\r\n"))))
+}
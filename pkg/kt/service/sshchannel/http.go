@@ -0,0 +1,143 @@
+package sshchannel
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PacPath path of pac file served by http proxy
+const PacPath = "/proxy.pac"
+
+// hopByHopHeaders headers should not be forwarded by proxy
+var hopByHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// serveHttpProxy serve http proxy with dial function, until listener closed
+func serveHttpProxy(listener net.Listener, dial dialFunc, options *ProxyOptions) {
+	server := &http.Server{Handler: newHttpProxyHandler(dial, options)}
+	if err := server.Serve(listener); err != nil {
+		log.Debug().Err(err).Msgf("Http proxy stopped")
+	}
+}
+
+// newHttpProxyHandler handle CONNECT and absolute-form requests as proxy, and serve pac file for other requests
+func newHttpProxyHandler(dial dialFunc, options *ProxyOptions) http.Handler {
+	transport := &http.Transport{
+		DialContext:         dial,
+		Proxy:               nil,
+		IdleConnTimeout:     60 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isProxyRequest := r.Method == http.MethodConnect || r.URL.IsAbs()
+		if isProxyRequest && !isProxyAuthorized(r, options) {
+			w.Header().Set("Proxy-Authenticate", "Basic realm=\"kt\"")
+			w.WriteHeader(http.StatusProxyAuthRequired)
+		} else if r.Method == http.MethodConnect {
+			handleConnect(w, r, dial)
+		} else if r.URL.IsAbs() {
+			handleForward(w, r, transport)
+		} else if r.URL.Path == PacPath && options.Pac != nil {
+			w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+			_, _ = io.WriteString(w, options.Pac(r.Host))
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// isProxyAuthorized check basic credentials in Proxy-Authorization header, always pass if no username required
+func isProxyAuthorized(r *http.Request, options *ProxyOptions) bool {
+	if options.Username == "" {
+		return true
+	}
+	auth := r.Header.Get("Proxy-Authorization")
+	if !strings.HasPrefix(auth, "Basic ") {
+		return false
+	}
+	credential, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(credential, []byte(options.Username+":"+options.Password)) == 1
+}
+
+func handleConnect(w http.ResponseWriter, r *http.Request, dial dialFunc) {
+	remote, err := dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to connect %s via http proxy", r.Host)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = remote.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		_ = remote.Close()
+		return
+	}
+	if _, err = client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = remote.Close()
+		_ = client.Close()
+		return
+	}
+	done := make(chan int, 2)
+	go func() {
+		// read via buffered reader, in case any data already buffered by http server
+		_, _ = io.Copy(remote, buf.Reader)
+		done <- 1
+	}()
+	go func() {
+		_, _ = io.Copy(client, remote)
+		done <- 1
+	}()
+	<-done
+	_ = remote.Close()
+	_ = client.Close()
+}
+
+func handleForward(w http.ResponseWriter, r *http.Request, transport http.RoundTripper) {
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	removeHopByHopHeaders(req.Header)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to request %s via http proxy", r.URL)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	removeHopByHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func removeHopByHopHeaders(header http.Header) {
+	for _, key := range strings.Split(header.Get("Connection"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			header.Del(key)
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+}
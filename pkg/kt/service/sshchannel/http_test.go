@@ -0,0 +1,107 @@
+package sshchannel
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHttpProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer backend.Close()
+	dialed := make([]string, 0)
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		var d net.Dialer
+		return d.DialContext(ctx, network, backend.Listener.Addr().String())
+	}
+	proxyServer := httptest.NewServer(newHttpProxyHandler(dial, &ProxyOptions{Pac: func(proxyAddress string) string {
+		return "PROXY " + proxyAddress
+	}}))
+	defer proxyServer.Close()
+	proxyUrl, _ := url.Parse(proxyServer.URL)
+
+	// absolute-form request
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+	resp, err := client.Get("http://tomcat.default:8080/api")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, "hello /api", string(body))
+	require.Equal(t, []string{"tomcat.default:8080"}, dialed)
+
+	// CONNECT request
+	conn, err := net.Dial("tcp", proxyUrl.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "CONNECT tomcat.default:443 HTTP/1.1\r\nHost: tomcat.default:443\r\n\r\n")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = fmt.Fprintf(conn, "GET /tunnel HTTP/1.1\r\nHost: tomcat.default\r\n\r\n")
+	require.NoError(t, err)
+	resp, err = http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	require.Equal(t, "hello /tunnel", string(body))
+	require.Equal(t, "tomcat.default:443", dialed[1])
+
+	// pac file
+	resp, err = http.Get(proxyServer.URL + PacPath)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, "PROXY "+proxyUrl.Host, string(body))
+	require.Equal(t, "application/x-ns-proxy-autoconfig", resp.Header.Get("Content-Type"))
+
+	resp, err = http.Get(proxyServer.URL + "/other")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHttpProxyAuth(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "hello")
+	}))
+	defer backend.Close()
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, backend.Listener.Addr().String())
+	}
+	proxyServer := httptest.NewServer(newHttpProxyHandler(dial, &ProxyOptions{Username: "kt", Password: "secret"}))
+	defer proxyServer.Close()
+
+	proxyUrl, _ := url.Parse(proxyServer.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+	resp, err := client.Get("http://tomcat.default:8080/")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+
+	proxyUrl.User = url.UserPassword("kt", "wrong")
+	client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+	resp, err = client.Get("http://tomcat.default:8080/")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+
+	proxyUrl.User = url.UserPassword("kt", "secret")
+	client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+	resp, err = client.Get("http://tomcat.default:8080/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, "hello", string(body))
+}
@@ -22,19 +22,39 @@ func (s SocksLogger) Println(v ...any) {
 	_, _ = util.BackgroundLogger.Write([]byte(fmt.Sprint(v...) + util.Eol))
 }
 
-// StartSocks5Proxy start socks5 proxy
-func (c *Cli) StartSocks5Proxy(privateKey, sshAddress, socks5Address string) (err error) {
+// StartSocks5Proxy start socks5 proxy, and http proxy serving pac file on the same ssh connection if http address set
+func (c *Cli) StartSocks5Proxy(privateKey, sshAddress string, options *ProxyOptions) (err error) {
 	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
 	if err != nil {
 		return err
 	}
 	defer dialer.Close()
+	return serveProxy(dialer.DialContext, options)
+}
+
+// StartBalancedSocks5Proxy same as StartSocks5Proxy, but connections are distributed to backends of the balancer
+func (c *Cli) StartBalancedSocks5Proxy(balancer *Balancer, options *ProxyOptions) error {
+	return serveProxy(balancer.DialContext, options)
+}
+
+func serveProxy(dial dialFunc, options *ProxyOptions) error {
+	if options.HttpAddress != "" {
+		listener, err := net.Listen("tcp", options.HttpAddress)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+		go serveHttpProxy(listener, dial, options)
+	}
 
 	svc := &socks5.Server{
 		Logger:    SocksLogger{},
-		ProxyDial: dialer.DialContext,
+		ProxyDial: dial,
+	}
+	if options.Username != "" {
+		svc.Authentication = socks5.UserAuth(options.Username, options.Password)
 	}
-	return svc.ListenAndServe("tcp", socks5Address)
+	return svc.ListenAndServe("tcp", options.Socks5Address)
 }
 
 // RunScript run the script on remote host.
@@ -70,8 +90,8 @@ func (c *Cli) RunScript(privateKey, sshAddress, script string) (result string, e
 	return output, nil
 }
 
-// ForwardRemoteToLocal forward remote request to local
-func (c *Cli) ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint string) error {
+// ForwardRemoteToLocal forward remote request to local, client address is carried with specified header if not empty
+func (c *Cli) ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint, clientIpHeader string) error {
 	// Handle incoming connections on reverse forwarded tunnel
 	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
 	if err != nil {
@@ -96,7 +116,7 @@ func (c *Cli) ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, local
 
 	log.Info().Msgf("Reverse tunnel %s -> %s established", remoteEndpoint, localEndpoint)
 	for {
-		if err = handleRequest(listener, localEndpoint); errors.Is(err, io.EOF) {
+		if err = handleRequest(listener, localEndpoint, clientIpHeader); errors.Is(err, io.EOF) {
 			return err
 		}
 	}
@@ -117,7 +137,7 @@ func disconnectRemotePort(privateKey, sshAddress, remoteEndpoint string, c *Cli)
 	}
 }
 
-func handleRequest(listener net.Listener, localEndpoint string) error {
+func handleRequest(listener net.Listener, localEndpoint, clientIpHeader string) error {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error().Msgf("Failed to handle request: %v", r)
@@ -142,6 +162,20 @@ func handleRequest(listener net.Listener, localEndpoint string) error {
 		return err
 	}
 
+	// Remote address of forwarded connection is the client address seen by sshd of shadow pod
+	switch clientIpHeader {
+	case util.ClientIpProxyV1, util.ClientIpProxyV2:
+		if err = writeProxyHeader(local, clientIpHeader, client.RemoteAddr(), client.LocalAddr()); err != nil {
+			_ = client.Close()
+			_ = local.Close()
+			log.Error().Err(err).Msgf("Failed to write proxy protocol header")
+			return err
+		}
+	case util.ClientIpForwardedFor:
+		go handleHttpClient(client, local)
+		return nil
+	}
+
 	// Handle request in individual coroutine, current coroutine continue to accept more requests
 	go handleClient(client, local)
 	return nil
@@ -177,6 +211,33 @@ func handleClient(client net.Conn, remote net.Conn) {
 	_ = client.Close()
 }
 
+// handleHttpClient same as handleClient, but append client address to X-Forwarded-For header of each request
+func handleHttpClient(client net.Conn, local net.Conn) {
+	done := make(chan int, 2)
+
+	localReader := util.NewInterpretableReader(local)
+	go func() {
+		defer handleBrokenTunnel(done)
+		if _, err := io.Copy(client, localReader); err != nil {
+			log.Warn().Err(err).Msgf("Error while copy remote->local")
+		}
+		done<-1
+	}()
+
+	go func() {
+		defer handleBrokenTunnel(done)
+		if err := copyWithForwardedFor(local, client, client.RemoteAddr()); err != nil {
+			log.Warn().Err(err).Msgf("Error while copy local->remote")
+		}
+		done<-1
+	}()
+
+	<-done
+	localReader.Cancel()
+	_ = local.Close()
+	_ = client.Close()
+}
+
 func handleBrokenTunnel(done chan int) {
 	if r := recover(); r != nil {
 		log.Error().Msgf("Ssh tunnel broken: %v", r)
@@ -2,11 +2,25 @@ package sshchannel
 
 // Channel network channel
 type Channel interface {
-	StartSocks5Proxy(privateKey, sshAddress, socks5Address string) error
-	ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint string) error
+	StartSocks5Proxy(privateKey, sshAddress string, options *ProxyOptions) error
+	StartBalancedSocks5Proxy(balancer *Balancer, options *ProxyOptions) error
+	ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint, clientIpHeader string) error
+	ForwardRemoteUdpToLocal(privateKey, sshAddress, remoteEndpoint string, localEndpoints map[int]string) error
 	RunScript(privateKey, sshAddress, script string) (string, error)
 }
 
+// ProxyOptions options of local proxies served via ssh connection
+type ProxyOptions struct {
+	Socks5Address string
+	// HttpAddress also serve http proxy and pac file if not empty
+	HttpAddress string
+	// Pac generate content of pac file with address of http proxy
+	Pac func(proxyAddress string) string
+	// Username and Password required by both proxies if username not empty
+	Username string
+	Password string
+}
+
 // Cli the singleton type
 type Cli struct {}
 var instance *Cli
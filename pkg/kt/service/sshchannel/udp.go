@@ -0,0 +1,102 @@
+package sshchannel
+
+import (
+	"context"
+	"errors"
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/rs/zerolog/log"
+	"github.com/wzshiming/sshproxy"
+	"io"
+	"net"
+	"time"
+)
+
+// ForwardRemoteUdpToLocal forward udp datagrams relayed by shadow pod to local, localEndpoints is keyed by remote udp port
+func (c *Cli) ForwardRemoteUdpToLocal(privateKey, sshAddress, remoteEndpoint string, localEndpoints map[int]string) error {
+	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
+	if err != nil {
+		return err
+	}
+	defer dialer.Close()
+
+	_, err = dialer.SSHClient(context.Background())
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to create ssh tunnel")
+		return err
+	}
+
+	// Relay port of shadow pod only accept connection from udp relay process inside pod
+	listener, err := dialer.Listen(context.Background(), "tcp", remoteEndpoint)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to listen remote endpoint")
+		disconnectRemotePort(privateKey, sshAddress, remoteEndpoint, c)
+		return err
+	}
+	defer listener.Close()
+
+	log.Info().Msgf("Udp reverse tunnel %s -> %v established", remoteEndpoint, localEndpoints)
+	for {
+		client, err2 := listener.Accept()
+		if err2 != nil {
+			log.Error().Err(err2).Msgf("Failed to accept remote udp relay")
+			if errors.Is(err2, io.EOF) {
+				return err2
+			}
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		go handleUdpClient(client, localEndpoints)
+	}
+}
+
+// handleUdpClient relay datagrams of a remote udp client between relay stream and local udp endpoint
+func handleUdpClient(client net.Conn, localEndpoints map[int]string) {
+	defer client.Close()
+	port, err := common.ReadRelayPort(client)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to read udp relay header")
+		return
+	}
+	localEndpoint, exists := localEndpoints[port]
+	if !exists {
+		log.Warn().Msgf("Udp port %d is not exposed", port)
+		return
+	}
+	local, err := net.Dial("udp", localEndpoint)
+	if err != nil {
+		log.Error().Err(err).Msgf("Local udp service error")
+		return
+	}
+	defer local.Close()
+
+	// Start local -> remote datagram transfer
+	go func() {
+		buf := make([]byte, common.MaxDatagramSize)
+		for {
+			n, err2 := local.Read(buf)
+			if errors.Is(err2, net.ErrClosed) {
+				return
+			} else if err2 != nil {
+				// e.g. connection refused caused by icmp port unreachable, local service may start later
+				log.Debug().Err(err2).Msgf("Failed to read from local udp endpoint %s", localEndpoint)
+				continue
+			}
+			if err2 = common.WriteDatagram(client, buf[:n]); err2 != nil {
+				log.Debug().Err(err2).Msgf("Failed to relay datagram to remote")
+				_ = local.Close()
+				return
+			}
+		}
+	}()
+
+	// Remote -> local datagram transfer, until relay session closed by shadow pod
+	for {
+		data, err2 := common.ReadDatagram(client)
+		if err2 != nil {
+			return
+		}
+		if _, err2 = local.Write(data); err2 != nil {
+			log.Debug().Err(err2).Msgf("Failed to send datagram to local udp endpoint %s", localEndpoint)
+		}
+	}
+}
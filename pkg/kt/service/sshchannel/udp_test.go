@@ -0,0 +1,39 @@
+package sshchannel
+
+import (
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestHandleUdpClient(t *testing.T) {
+	// local udp service which echoes datagram back
+	local, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer local.Close()
+	go func() {
+		buf := make([]byte, common.MaxDatagramSize)
+		for {
+			n, addr, err2 := local.ReadFromUDP(buf)
+			if err2 != nil {
+				return
+			}
+			_, _ = local.WriteToUDP(append([]byte("echo:"), buf[:n]...), addr)
+		}
+	}()
+
+	remote, client := net.Pipe()
+	defer remote.Close()
+	go handleUdpClient(client, map[int]string{53: local.LocalAddr().String()})
+
+	require.NoError(t, common.WriteRelayPort(remote, 53))
+	require.NoError(t, common.WriteDatagram(remote, []byte("ping")))
+	data, err := common.ReadDatagram(remote)
+	require.NoError(t, err)
+	require.Equal(t, "echo:ping", string(data))
+	require.NoError(t, common.WriteDatagram(remote, []byte("")))
+	data, err = common.ReadDatagram(remote)
+	require.NoError(t, err)
+	require.Equal(t, "echo:", string(data))
+}
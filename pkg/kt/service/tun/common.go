@@ -1,11 +1,94 @@
 package tun
 
 import (
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
 	"net"
 	"strconv"
 	"strings"
 )
 
+// routedRanges ip ranges currently routed to tun device, on darwin and windows the network address of each range is
+// also assigned to tun device, and could be shared by ranges of different mask
+var routedRanges []string
+
+// ExcludeRanges split ip ranges to skip excluded ranges inside them, so that excluded ranges keep using default route
+func ExcludeRanges(ipRanges []string, excludeRanges []string) []string {
+	result := ipRanges
+	for _, excluded := range excludeRanges {
+		_, excludedNet, err := net.ParseCIDR(excluded)
+		if err != nil {
+			continue
+		}
+		var remain []string
+		for _, r := range result {
+			remain = append(remain, excludeRange(r, excludedNet)...)
+		}
+		result = remain
+	}
+	return result
+}
+
+// excludeRange get ranges covering the ip range except the excluded part
+func excludeRange(ipRange string, excluded *net.IPNet) []string {
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	if err != nil || ipNet.IP.To4() == nil || excluded.IP.To4() == nil {
+		return []string{ipRange}
+	}
+	ones, _ := ipNet.Mask.Size()
+	excludedOnes, _ := excluded.Mask.Size()
+	if excludedOnes < ones || !ipNet.Contains(excluded.IP) {
+		if excludedOnes <= ones && excluded.Contains(ipNet.IP) {
+			// the whole range is excluded
+			return []string{}
+		}
+		return []string{ipRange}
+	}
+	// sibling ranges along the path from ip range down to excluded range
+	var ranges []string
+	excludedIp := excluded.IP.To4()
+	for i := ones; i < excludedOnes; i++ {
+		sibling := make(net.IP, 4)
+		copy(sibling, excludedIp)
+		sibling[i/8] ^= 1 << (7 - uint(i%8))
+		mask := net.CIDRMask(i+1, 32)
+		ranges = append(ranges, (&net.IPNet{IP: sibling.Mask(mask), Mask: mask}).String())
+	}
+	return ranges
+}
+
+// rangeAddress get the address assigned to tun device for routing the ip range
+func rangeAddress(ipRange string) string {
+	return strings.Split(ipRange, "/")[0]
+}
+
+// isAddressInUse check whether the tun address is used by any routed range
+func isAddressInUse(tunIp string) bool {
+	for _, r := range routedRanges {
+		if rangeAddress(r) == tunIp {
+			return true
+		}
+	}
+	return false
+}
+
+// getUnusedTunName use default tun device name, unless other connect process is running, e.g. connected to another cluster
+func getUnusedTunName(nameOf func(index int) string) string {
+	if len(util.GetConnectSessions()) == 0 {
+		return nameOf(0)
+	}
+	used := make(map[string]bool)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, i := range ifaces {
+			used[i.Name] = true
+		}
+	}
+	for i := 0; ; i++ {
+		if name := nameOf(i); !used[name] {
+			return name
+		}
+	}
+}
+
 func toIpAndMask(cidr string) (string, string, error) {
 	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
@@ -10,3 +10,17 @@ func TestToIpAndMask (t *testing.T) {
 	require.Equal(t, "10.95.134.192", ip)
 	require.Equal(t, "255.255.255.248", mask)
 }
+
+func TestExcludeRanges(t *testing.T) {
+	require.Equal(t, []string{"10.96.0.0/16"}, ExcludeRanges([]string{"10.96.0.0/16"}, []string{"172.16.0.0/24"}))
+	require.Equal(t, []string{"10.98.0.0/16"}, ExcludeRanges([]string{"10.96.0.0/16", "10.98.0.0/16"}, []string{"10.96.0.0/15"}))
+	require.Equal(t, []string{"10.96.128.0/17", "10.96.64.0/18", "10.96.32.0/19", "10.96.16.0/20", "10.96.8.0/21",
+		"10.96.4.0/22", "10.96.0.0/23", "10.96.3.0/24"}, ExcludeRanges([]string{"10.96.0.0/16"}, []string{"10.96.2.0/24"}))
+}
+
+func TestIsAddressInUse(t *testing.T) {
+	routedRanges = []string{"172.168.0.0/16", "10.96.0.0/16"}
+	defer func() { routedRanges = nil }()
+	require.True(t, isAddressInUse(rangeAddress("172.168.0.0/24")))
+	require.False(t, isAddressInUse(rangeAddress("172.169.0.0/24")))
+}
@@ -69,6 +69,7 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 		} else {
 			anyRouteOk = true
 		}
+		routedRanges = append(routedRanges, r)
 	}
 	if !anyRouteOk {
 		return AllRouteFailError{lastErr}
@@ -76,6 +77,75 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 	return lastErr
 }
 
+// AddRoute let more ip range route to tun device after it's set up
+func (s *Cli) AddRoute(ipRange []string) error {
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Adding route to %s", r)
+		tunIp := rangeAddress(r)
+		// address could already be assigned for another range with the same network address
+		if !isAddressInUse(tunIp) {
+			// run command: ifconfig utun6 add 172.21.0.0/16 172.21.0.0
+			if _, _, err := util.RunAndWait(exec.Command("ifconfig",
+				s.GetName(),
+				"add",
+				r,
+				tunIp,
+			)); err != nil {
+				log.Warn().Msgf("Failed to add ip addr %s to tun device", tunIp)
+				lastErr = err
+			}
+		}
+		// run command: route add -net 172.21.0.0/16 -interface utun6
+		if _, _, err := util.RunAndWait(exec.Command("route",
+			"add",
+			"-net",
+			r,
+			"-interface",
+			s.GetName(),
+		)); err != nil {
+			log.Warn().Msgf("Failed to set route %s to tun device", r)
+			lastErr = err
+		}
+		routedRanges = append(routedRanges, r)
+	}
+	return lastErr
+}
+
+// RemoveRoute stop specified ip range routing to tun device
+func (s *Cli) RemoveRoute(ipRange []string) error {
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Removing route to %s", r)
+		// run command: route delete -net 172.21.0.0/16 -interface utun6
+		if _, _, err := util.RunAndWait(exec.Command("route",
+			"delete",
+			"-net",
+			r,
+			"-interface",
+			s.GetName(),
+		)); err != nil {
+			log.Warn().Msgf("Failed to remove route %s from tun device", r)
+			lastErr = err
+		}
+		routedRanges = util.ArrayDelete(routedRanges, r)
+		// keep the address if any remaining range still uses it
+		tunIp := rangeAddress(r)
+		if isAddressInUse(tunIp) {
+			continue
+		}
+		// run command: ifconfig utun6 delete 172.21.0.0
+		if _, _, err := util.RunAndWait(exec.Command("ifconfig",
+			s.GetName(),
+			"delete",
+			tunIp,
+		)); err != nil {
+			log.Debug().Msgf("Failed to remove ip addr %s from tun device", tunIp)
+		}
+	}
+	return lastErr
+}
+
 // CheckRoute check whether all route rule setup properly
 func (s *Cli) CheckRoute(ipRange []string) []string {
 	var failedIpRange []string
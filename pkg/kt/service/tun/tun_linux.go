@@ -55,6 +55,46 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 	return lastErr
 }
 
+// AddRoute let more ip range route to tun device after it's set up
+func (s *Cli) AddRoute(ipRange []string) error {
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Adding route to %s", r)
+		// run command: ip route add 10.97.0.0/16 dev kt0
+		if _, _, err := util.RunAndWait(exec.Command("ip",
+			"route",
+			"add",
+			r,
+			"dev",
+			s.GetName(),
+		)); err != nil {
+			log.Warn().Msgf("Failed to set route %s to tun device", r)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// RemoveRoute stop specified ip range routing to tun device
+func (s *Cli) RemoveRoute(ipRange []string) error {
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Removing route to %s", r)
+		// run command: ip route del 10.97.0.0/16 dev kt0
+		if _, _, err := util.RunAndWait(exec.Command("ip",
+			"route",
+			"del",
+			r,
+			"dev",
+			s.GetName(),
+		)); err != nil {
+			log.Warn().Msgf("Failed to remove route %s from tun device", r)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 // CheckRoute check whether all route rule setup properly
 func (s *Cli) CheckRoute(ipRange []string) []string {
 	var failedIpRange []string
@@ -91,6 +131,15 @@ func (s *Cli) RestoreRoute() error {
 	return nil
 }
 
+var tunName = ""
 func (s *Cli) GetName() string {
-	return util.TunNameLinux
+	if tunName == "" {
+		tunName = getUnusedTunName(func(index int) string {
+			if index == 0 {
+				return util.TunNameLinux
+			}
+			return fmt.Sprintf("kt%d", index)
+		})
+	}
+	return tunName
 }
@@ -93,6 +93,7 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 			} else {
 				anyRouteOk = true
 			}
+			routedRanges = append(routedRanges, r)
 		}
 	}
 	if !anyRouteOk {
@@ -151,10 +152,102 @@ func (s *Cli) setIPv6Route(ipRange []string, excludeIpRange []string) (bool, err
 		} else {
 			anyRouteOk = true
 		}
+		routedRanges = append(routedRanges, r)
 	}
 	return anyRouteOk, lastErr
 }
 
+// AddRoute let more ip range route to tun device after it's set up
+func (s *Cli) AddRoute(ipRange []string) error {
+	ipVersion := "ipv4"
+	if opt.Store.Ipv6Cluster {
+		ipVersion = "ipv6"
+	}
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Adding route to %s", r)
+		tunIp := rangeAddress(r)
+		// address could already be assigned for another range with the same network address
+		if !isAddressInUse(tunIp) {
+			args := []string{"interface", ipVersion, "add", "address", s.GetName()}
+			if opt.Store.Ipv6Cluster {
+				// run command: netsh interface ipv6 add address KtConnectTunnel fd11:1112::/32
+				args = append(args, r)
+			} else {
+				// run command: netsh interface ipv4 add address KtConnectTunnel 172.21.0.1 255.255.0.0
+				_, mask, err := toIpAndMask(r)
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				args = append(args, tunIp, mask)
+			}
+			if _, _, err := util.RunAndWait(exec.Command("netsh", args...)); err != nil {
+				log.Warn().Msgf("Failed to add ip addr %s to tun device", tunIp)
+				lastErr = err
+			}
+		}
+		// run command: netsh interface ipv4 add route 172.21.0.0/16 KtConnectTunnel 172.21.0.0
+		if _, _, err := util.RunAndWait(exec.Command("netsh",
+			"interface",
+			ipVersion,
+			"add",
+			"route",
+			r,
+			s.GetName(),
+			tunIp,
+		)); err != nil {
+			log.Warn().Msgf("Failed to set route %s to tun device", r)
+			lastErr = err
+		}
+		routedRanges = append(routedRanges, r)
+	}
+	return lastErr
+}
+
+// RemoveRoute stop specified ip range routing to tun device
+func (s *Cli) RemoveRoute(ipRange []string) error {
+	ipVersion := "ipv4"
+	if opt.Store.Ipv6Cluster {
+		ipVersion = "ipv6"
+	}
+	var lastErr error
+	for _, r := range ipRange {
+		log.Info().Msgf("Removing route to %s", r)
+		tunIp := rangeAddress(r)
+		// run command: netsh interface ipv4 delete route 172.21.0.0/16 KtConnectTunnel 172.21.0.0
+		if _, _, err := util.RunAndWait(exec.Command("netsh",
+			"interface",
+			ipVersion,
+			"delete",
+			"route",
+			r,
+			s.GetName(),
+			tunIp,
+		)); err != nil {
+			log.Warn().Msgf("Failed to remove route %s from tun device", r)
+			lastErr = err
+		}
+		routedRanges = util.ArrayDelete(routedRanges, r)
+		// keep the address if any remaining range still uses it
+		if isAddressInUse(tunIp) {
+			continue
+		}
+		// run command: netsh interface ipv4 delete address KtConnectTunnel 172.21.0.0
+		if _, _, err := util.RunAndWait(exec.Command("netsh",
+			"interface",
+			ipVersion,
+			"delete",
+			"address",
+			s.GetName(),
+			tunIp,
+		)); err != nil {
+			log.Debug().Msgf("Failed to remove ip addr %s from tun device", tunIp)
+		}
+	}
+	return lastErr
+}
+
 // CheckRoute check whether all route rule setup properly
 func (s *Cli) CheckRoute(ipRange []string) []string {
 	var failedIpRange []string
@@ -225,8 +318,17 @@ func (s *Cli) RestoreRoute() error {
 	return lastErr
 }
 
+var tunName = ""
 func (s *Cli) GetName() string {
-	return util.TunNameWin
+	if tunName == "" {
+		tunName = getUnusedTunName(func(index int) string {
+			if index == 0 {
+				return util.TunNameWin
+			}
+			return fmt.Sprintf("%s%d", util.TunNameWin, index)
+		})
+	}
+	return tunName
 }
 
 func getInterfaceIndex(s *Cli) (string, []string, error) {
@@ -5,6 +5,8 @@ type Tunnel interface {
 	CheckContext() error
 	ToSocks(sockAddr string) error
 	SetRoute(ipRange []string, excludeIpRange []string) error
+	AddRoute(ipRange []string) error
+	RemoveRoute(ipRange []string) error
 	CheckRoute(ipRange []string) []string
 	RestoreRoute() error
 	GetName() string
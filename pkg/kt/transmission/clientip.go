@@ -0,0 +1,34 @@
+package transmission
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/util"
+)
+
+// ClientIpConfig way to carry client address to local service
+type ClientIpConfig struct {
+	// Header proxy protocol or http header to carry client address, empty for not carrying
+	Header string
+	// HttpPorts remote ports serving http, client address is only carried for connections to these ports
+	HttpPorts []int
+}
+
+// CheckClientIpHeader verify client ip header option value
+func CheckClientIpHeader(header string) error {
+	if header != "" && header != util.ClientIpProxyV1 && header != util.ClientIpProxyV2 &&
+		header != util.ClientIpForwardedFor {
+		return fmt.Errorf("invalid client ip header '%s', supportted are %s, %s, %s", header,
+			util.ClientIpProxyV1, util.ClientIpProxyV2, util.ClientIpForwardedFor)
+	}
+	return nil
+}
+
+// headerOf get header to carry client address for connections to specified remote port
+func (c ClientIpConfig) headerOf(remotePort int) string {
+	for _, p := range c.HttpPorts {
+		if p == remotePort {
+			return c.Header
+		}
+	}
+	return ""
+}
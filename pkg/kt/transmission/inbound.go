@@ -10,17 +10,27 @@ import (
 	"time"
 )
 
-// ForwardPodToLocal mapping pod port to local port
-func ForwardPodToLocal(exposePorts, podName, privateKey string, mirror MirrorConfig) (int, error) {
-	log.Info().Msgf("Forwarding pod %s to local via port %s", podName, exposePorts)
+// ForwardPodToLocal mapping pod port to local port, client address of http ports is carried with specified header,
+// pod name is fetched on every connecting attempt, since the pod could be recreated
+func ForwardPodToLocal(exposePorts string, podName func() string, privateKey string, clientIp ClientIpConfig,
+	mirror MirrorConfig) (int, error) {
+	return ForwardPodToLocalViaSshPort(exposePorts, podName, common.StandardSshPort, privateKey, clientIp, mirror)
+}
+
+// ForwardPodToLocalViaSshPort same as ForwardPodToLocal, but with sshd in pod listening on specified port
+func ForwardPodToLocalViaSshPort(exposePorts string, podName func() string, sshPort int, privateKey string,
+	clientIp ClientIpConfig, mirror MirrorConfig) (int, error) {
+	log.Info().Msgf("Forwarding pod %s to local via port %s", podName(), exposePorts)
 	localSshPort := util.GetRandomTcpPort()
 
-	// port forward pod 22 -> local <random port>
-	if _, err := SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
+	// port forward pod <ssh port> -> local <random port>
+	if err := NewPortForwardSupervisor(podName, sshPort, localSshPort).Start(); err != nil {
+		log.Error().Err(err).Msgf("Failed to setup port forward local:%d -> pod %s:%d",
+			localSshPort, podName(), sshPort)
 		return -1, err
 	}
 
-	err := ForwardRemotePortsViaSshTunnel(exposePorts, localSshPort, privateKey, mirror)
+	err := ForwardRemotePortsViaSshTunnel(exposePorts, localSshPort, privateKey, clientIp, mirror)
 	if err != nil {
 		return -1, err
 	}
@@ -29,61 +39,94 @@ func ForwardPodToLocal(exposePorts, podName, privateKey string, mirror MirrorCon
 }
 
 // ForwardRemotePortsViaSshTunnel forward multiple remote ports to local
-func ForwardRemotePortsViaSshTunnel(exposePorts string, localSshPort int, privateKey string, mirror MirrorConfig) error {
+func ForwardRemotePortsViaSshTunnel(exposePorts string, localSshPort int, privateKey string, clientIp ClientIpConfig,
+	mirror MirrorConfig) error {
 	// supports multi port-pairs
 	portPairs := strings.Split(exposePorts, ",")
-	res := make(chan error)
+	// each tunnel reports result of its first connecting attempt
+	res := make(chan error, len(portPairs)+1)
+	tunnelCount := 0
+	udpTargets := make(map[int]string)
 	for _, exposePort := range portPairs {
-		localPort, remotePort, err2 := util.ParsePortMapping(exposePort)
+		host, localPort, remotePort, err2 := util.ParseExposeTarget(exposePort)
 		if err2 != nil {
 			return err2
 		}
-		targetPort := remotePort
+		targetAddress := util.ExposeTargetAddress(host, localPort)
+		if protocol, _, _ := util.ParseExposeProtocol(exposePort); protocol == util.ProtocolUdp {
+			if mirror.Enabled() {
+				return fmt.Errorf("mirror is not supported for udp port %d", remotePort)
+			}
+			udpTargets[remotePort] = targetAddress
+			continue
+		}
 		if mirror.Enabled() {
-			mirror.LocalAddress = fmt.Sprintf("127.0.0.1:%d", localPort)
-			proxyPort, err := StartMirrorProxy(localPort, mirror)
+			mirror.LocalAddress = targetAddress
+			proxyPort, err := StartMirrorProxy(targetAddress, mirror)
 			if err != nil {
 				return err
 			}
-			targetPort = proxyPort
+			targetAddress = util.ExposeTargetAddress("", proxyPort)
 		}
-		forwardRemotePortViaSshTunnel(localPort, targetPort, localSshPort, privateKey, res)
+		clientIpHeader := clientIp.headerOf(remotePort)
+		if clientIpHeader != "" {
+			log.Info().Msgf("Client address to port %d is carried via %s", remotePort, clientIpHeader)
+		} else if clientIp.Header != "" {
+			log.Info().Msgf("Port %d is not a http port, client address is not carried", remotePort)
+		}
+		forwardRemotePortViaSshTunnel(targetAddress, remotePort, localSshPort, privateKey, clientIpHeader, res)
+		tunnelCount++
+	}
+	if len(udpTargets) > 0 {
+		// all udp ports share one relay port of shadow pod
+		sshAddress := fmt.Sprintf("127.0.0.1:%d", localSshPort)
+		relayEndpoint := fmt.Sprintf("127.0.0.1:%d", common.UdpRelayPort)
+		log.Debug().Msgf("Forwarding udp relay %s to %v via %s", relayEndpoint, udpTargets, sshAddress)
+		sshReverseUdpTunnel(privateKey, sshAddress, relayEndpoint, udpTargets, res)
+		tunnelCount++
 	}
-	select {
-	case err := <-res:
-		return err
-	case <-time.After(1 * time.Second):
-		go func() {
-			// consume the res channel to avoid block reverse tunnel
-			<-res
-		}()
+	for i := 0; i < tunnelCount; i++ {
+		if err := <-res; err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// ForwardRemotePortViaSshTunnel forward remote pod to local
-func forwardRemotePortViaSshTunnel(localPort, remotePort, localSshPort int, privateKey string, res chan error) {
+// ForwardRemotePortViaSshTunnel forward remote pod to local or specified target address
+func forwardRemotePortViaSshTunnel(targetAddress string, remotePort, localSshPort int, privateKey, clientIpHeader string,
+	res chan error) {
 	remoteEndpoint := fmt.Sprintf("127.0.0.1:%d", localSshPort)
 	localEndpoint := fmt.Sprintf("0.0.0.0:%d", remotePort)
-	sshAddress := fmt.Sprintf("127.0.0.1:%d", localPort)
+	sshAddress := targetAddress
 	log.Debug().Msgf("Forwarding %s to local endpoint %s via %s", remoteEndpoint, localEndpoint, sshAddress)
-	sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, res)
+	sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, clientIpHeader, res)
 }
 
-func sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress string, res chan error) {
+func sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, clientIpHeader string, res chan error) {
+	startReverseTunnel(fmt.Sprintf("Reverse tunnel %s", localEndpoint), func() error {
+		return sshchannel.Ins().ForwardRemoteToLocal(privateKey, remoteEndpoint, localEndpoint, sshAddress, clientIpHeader)
+	}, res)
+}
+
+func sshReverseUdpTunnel(privateKey, sshAddress, relayEndpoint string, udpTargets map[int]string, res chan error) {
+	startReverseTunnel("Udp reverse tunnel", func() error {
+		return sshchannel.Ins().ForwardRemoteUdpToLocal(privateKey, sshAddress, relayEndpoint, udpTargets)
+	}, res)
+}
+
+// startReverseTunnel keep tunnel alive in background, tunnel is considered established if no error occurs in 1 second
+func startReverseTunnel(name string, forward func() error, res chan error) {
+	s := NewSupervisor(name, func(ready func()) error {
+		timer := time.AfterFunc(1*time.Second, ready)
+		defer timer.Stop()
+		return forward()
+	})
 	go func() {
-		err := sshchannel.Ins().ForwardRemoteToLocal(privateKey, remoteEndpoint, localEndpoint, sshAddress)
+		err := s.Start()
 		if err != nil {
-			if res != nil {
-				log.Error().Err(err).Msgf("Failed to setup reverse tunnel")
-				res <- err
-			} else {
-				log.Debug().Err(err).Msgf("Reverse tunnel interrupted")
-			}
+			log.Error().Err(err).Msgf("%s setup failed", name)
 		}
-
-		time.Sleep(10 * time.Second)
-		log.Debug().Msgf("Reverse tunnel reconnecting ...")
-		sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, nil)
+		res <- err
 	}()
 }
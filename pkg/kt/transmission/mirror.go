@@ -150,14 +150,14 @@ func (r *mirrorRecorder) Truncated() bool {
 	return r.truncated
 }
 
-func StartMirrorProxy(localPort int, mirror MirrorConfig) (int, error) {
+func StartMirrorProxy(targetAddress string, mirror MirrorConfig) (int, error) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		return -1, err
 	}
 	addr := listener.Addr().(*net.TCPAddr)
 	proxyPort := addr.Port
-	log.Info().Msgf("Mirror proxy listening on 127.0.0.1:%d for local port %d", proxyPort, localPort)
+	log.Info().Msgf("Mirror proxy listening on 127.0.0.1:%d for %s", proxyPort, targetAddress)
 
 	rand.Seed(time.Now().UnixNano())
 	go func() {
@@ -168,15 +168,15 @@ func StartMirrorProxy(localPort int, mirror MirrorConfig) (int, error) {
 				log.Warn().Err(err).Msgf("Mirror proxy accept failed")
 				return
 			}
-			go handleMirrorConnection(conn, localPort, mirror)
+			go handleMirrorConnection(conn, targetAddress, mirror)
 		}
 	}()
 	return proxyPort, nil
 }
 
-func handleMirrorConnection(client net.Conn, localPort int, mirror MirrorConfig) {
+func handleMirrorConnection(client net.Conn, targetAddress string, mirror MirrorConfig) {
 	defer client.Close()
-	localConn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	localConn, err := net.Dial("tcp", targetAddress)
 	if err != nil {
 		log.Error().Err(err).Msgf("Mirror proxy failed to connect to local service")
 		return
@@ -15,47 +15,60 @@ import (
 	"time"
 )
 
-// SetupPortForwardToLocal mapping local port to shadow pod ssh port
+// SetupPortForwardToLocal mapping local port to shadow pod ssh port, the returned channel is closed when retry budget
+// of reconnecting exhausted
 func SetupPortForwardToLocal(podName string, remotePort, localPort int) (chan int, error) {
 	gone := make(chan int)
-	return gone, setupPortForwardToLocal(podName, remotePort, localPort, gone, true)
+	s := NewPortForwardSupervisor(func() string {
+		return podName
+	}, remotePort, localPort)
+	s.OnGiveUp = func() {
+		close(gone)
+	}
+	if err := s.Start(); err != nil {
+		log.Error().Err(err).Msgf("Failed to setup port forward local:%d -> pod %s:%d", localPort, podName, remotePort)
+		return gone, err
+	}
+	return gone, nil
 }
 
-func setupPortForwardToLocal(podName string, remotePort, localPort int, gone chan int, isInitConnect bool) error {
-	ready := make(chan struct{})
-	var ticker *time.Ticker
+// NewPortForwardSupervisor create supervisor of port forward, pod name is fetched on every connecting attempt
+func NewPortForwardSupervisor(podName func() string, remotePort, localPort int) *Supervisor {
+	return NewSupervisor(fmt.Sprintf("Port forward local:%d", localPort), func(ready func()) error {
+		return portForwardToLocal(podName(), remotePort, localPort, ready)
+	})
+}
+
+// portForwardToLocal block until port forward interrupted
+func portForwardToLocal(podName string, remotePort, localPort int, ready func()) error {
+	stop := make(chan struct{})
+	fwReady := make(chan struct{})
+	fw, err := createPortForwarder(podName, remotePort, localPort, stop, fwReady)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Invalid port forward parameter")
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		stop := make(chan struct{})
-		fw, err := createPortForwarder(podName, remotePort, localPort, stop, ready)
-		if err != nil {
-			log.Warn().Err(err).Msgf("Invalid port forward parameter")
-			return
-		}
-		// will hang here
-		err = fw.ForwardPorts()
-		if err != nil {
-			if isInitConnect {
-				log.Error().Err(err).Msgf("Failed to setup port forward local:%d -> pod %s:%d", localPort, podName, remotePort)
-			} else {
-				log.Debug().Err(err).Msgf("Port forward local:%d -> pod %s:%d interrupted", localPort, podName, remotePort)
-			}
-		}
-		if ticker != nil {
+		select {
+		case <-fwReady:
+			ticker := cluster.SetupPortForwardHeartBeat(localPort)
+			log.Info().Msgf("Port forward local:%d -> pod %s:%d established", localPort, podName, remotePort)
+			ready()
+			<-done
 			ticker.Stop()
+		case <-time.After(time.Duration(opt.Get().Global.PortForwardTimeout) * time.Second):
+			close(stop)
+		case <-done:
 		}
-		time.Sleep(time.Duration(opt.Get().Global.PortForwardTimeout) * time.Second)
-		log.Debug().Msgf("Port forward reconnecting ...")
-		_ = setupPortForwardToLocal(podName, remotePort, localPort, gone, false)
 	}()
-
-	select {
-	case <-ready:
-		ticker = cluster.SetupPortForwardHeartBeat(localPort)
-		log.Info().Msgf("Port forward local:%d -> pod %s:%d established", localPort, podName, remotePort)
-		return nil
-	case <-time.After(time.Duration(opt.Get().Global.PortForwardTimeout) * time.Second):
-		return fmt.Errorf("connect to port-forward failed")
+	// will hang here
+	if err = fw.ForwardPorts(); err != nil {
+		log.Debug().Err(err).Msgf("Port forward local:%d -> pod %s:%d interrupted", localPort, podName, remotePort)
+		return err
 	}
+	return fmt.Errorf("port forward to pod %s closed", podName)
 }
 
 // createPortForwarder fetch a port forward handler
@@ -0,0 +1,208 @@
+package transmission
+
+import (
+	"fmt"
+	opt "github.com/gitlayzer/kt-connect/pkg/kt/command/options"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/rs/zerolog/log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff exponential growing delay between reconnecting attempts
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+	// Jitter ratio of random deviation, e.g. 0.2 means delay varies within ±20%
+	Jitter float64
+}
+
+// DefaultBackoff delay from 2 seconds up to 1 minute
+func DefaultBackoff() *Backoff {
+	return &Backoff{
+		Initial: 2 * time.Second,
+		Max:     time.Minute,
+		Factor:  2,
+		Jitter:  0.2,
+	}
+}
+
+// Delay get time to wait before specified attempt, attempt starts from 0
+func (b *Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Initial) * math.Pow(b.Factor, float64(attempt))
+	if delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Supervisor keep a connection alive, re-establish it with exponential backoff once broken
+type Supervisor struct {
+	// Name of connection, used in log
+	Name string
+	// Connect establish the connection, invoke ready() when it becomes usable, and block until it's broken
+	Connect func(ready func()) error
+	// BeforeReconnect optional, invoked before every reconnecting attempt, failure is counted as a failed attempt
+	BeforeReconnect func() error
+	// OnStateChange optional, invoked whenever state of connection changed
+	OnStateChange func(state string, err error)
+	// OnGiveUp optional, invoked when retry budget exhausted
+	OnGiveUp func()
+	Backoff  *Backoff
+	// MaxRetries consecutive reconnecting attempts allowed, 0 for unlimited
+	MaxRetries int
+	state      string
+	lock       sync.Mutex
+}
+
+// NewSupervisor create supervisor with default backoff and retry budget
+func NewSupervisor(name string, connect func(ready func()) error) *Supervisor {
+	return &Supervisor{
+		Name:       name,
+		Connect:    connect,
+		Backoff:    DefaultBackoff(),
+		MaxRetries: opt.Get().Global.MaxReconnect,
+	}
+}
+
+// Start establish the connection and keep it alive in background, only returns after first connecting attempt
+func (s *Supervisor) Start() error {
+	s.setState(control.HealthConnecting, nil)
+	res := make(chan error)
+	go s.supervise(res)
+	return <-res
+}
+
+// State get current state of connection
+func (s *Supervisor) State() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state
+}
+
+// Degrade mark an established connection as not working well, e.g. heartbeat failed
+func (s *Supervisor) Degrade(err error) {
+	s.transit(control.HealthHealthy, control.HealthDegraded, err)
+}
+
+// Recover mark a degraded connection as working well again
+func (s *Supervisor) Recover() {
+	s.transit(control.HealthDegraded, control.HealthHealthy, nil)
+}
+
+func (s *Supervisor) supervise(res chan error) {
+	isInitConnect := true
+	retries := 0
+	for {
+		established, err := s.connectOnce(func() {
+			if isInitConnect {
+				res <- nil
+			}
+		})
+		if isInitConnect && !established {
+			s.setState(control.HealthFailed, err)
+			res <- err
+			return
+		}
+		isInitConnect = false
+		if established {
+			retries = 0
+		}
+		if err == nil {
+			err = fmt.Errorf("connection closed")
+		}
+		for {
+			retries++
+			if s.MaxRetries > 0 && retries > s.MaxRetries {
+				s.setState(control.HealthFailed, err)
+				if s.OnGiveUp != nil {
+					s.OnGiveUp()
+				}
+				return
+			}
+			s.setState(control.HealthReconnecting, err)
+			delay := s.Backoff.Delay(retries - 1)
+			log.Debug().Msgf("%s reconnecting in %.1f seconds (attempt %d)", s.Name, delay.Seconds(), retries)
+			time.Sleep(delay)
+			if s.BeforeReconnect == nil {
+				break
+			}
+			if err = s.BeforeReconnect(); err == nil {
+				break
+			}
+			log.Debug().Err(err).Msgf("Failed to prepare reconnecting %s", s.Name)
+		}
+	}
+}
+
+// connectOnce block until connection broken, and report whether it was ever established
+func (s *Supervisor) connectOnce(onReady func()) (bool, error) {
+	ready := make(chan struct{})
+	var once sync.Once
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Connect(func() {
+			once.Do(func() {
+				close(ready)
+			})
+		})
+	}()
+	select {
+	case <-ready:
+		s.setState(control.HealthHealthy, nil)
+		onReady()
+		return true, <-done
+	case err := <-done:
+		select {
+		case <-ready:
+			// established but broken right away
+			s.setState(control.HealthHealthy, nil)
+			onReady()
+			return true, err
+		default:
+			return false, err
+		}
+	}
+}
+
+func (s *Supervisor) setState(state string, err error) {
+	s.transit("", state, err)
+}
+
+// transit change state of connection, only if current state matches the from state when it's not empty
+func (s *Supervisor) transit(from, to string, err error) {
+	s.lock.Lock()
+	previous := s.state
+	if (from != "" && previous != from) || previous == to {
+		s.lock.Unlock()
+		return
+	}
+	s.state = to
+	s.lock.Unlock()
+	switch to {
+	case control.HealthHealthy:
+		if previous == control.HealthReconnecting {
+			log.Info().Msgf("%s re-established", s.Name)
+		} else if previous == control.HealthDegraded {
+			log.Info().Msgf("%s recovered", s.Name)
+		}
+	case control.HealthDegraded:
+		log.Warn().Err(err).Msgf("%s degraded", s.Name)
+	case control.HealthReconnecting:
+		log.Warn().Err(err).Msgf("%s interrupted", s.Name)
+	case control.HealthFailed:
+		if previous != control.HealthConnecting {
+			log.Error().Err(err).Msgf("%s failed after %d reconnecting attempts", s.Name, s.MaxRetries)
+		}
+	}
+	log.Debug().Msgf("%s state changed from '%s' to '%s'", s.Name, previous, to)
+	if s.OnStateChange != nil {
+		s.OnStateChange(to, err)
+	}
+}
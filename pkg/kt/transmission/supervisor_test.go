@@ -0,0 +1,88 @@
+package transmission
+
+import (
+	"fmt"
+	"github.com/gitlayzer/kt-connect/pkg/kt/service/control"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := &Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 2}
+	require.Equal(t, time.Second, b.Delay(0))
+	require.Equal(t, 4*time.Second, b.Delay(2))
+	require.Equal(t, 10*time.Second, b.Delay(5))
+	b.Jitter = 0.2
+	for i := 0; i < 10; i++ {
+		delay := b.Delay(1)
+		require.True(t, delay >= 1600*time.Millisecond && delay <= 2400*time.Millisecond)
+	}
+}
+
+func TestSupervisorReconnect(t *testing.T) {
+	var lock sync.Mutex
+	states := make([]string, 0)
+	attempts := 0
+	gone := make(chan struct{})
+	s := newTestSupervisor(func(ready func()) error {
+		attempts++
+		if attempts == 1 || attempts == 3 {
+			ready()
+			return fmt.Errorf("broken")
+		}
+		return fmt.Errorf("refused")
+	})
+	s.MaxRetries = 2
+	s.OnStateChange = func(state string, err error) {
+		lock.Lock()
+		states = append(states, state)
+		lock.Unlock()
+	}
+	s.OnGiveUp = func() {
+		close(gone)
+	}
+	require.NoError(t, s.Start())
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+		t.Fatal("supervisor should give up")
+	}
+	// retry counter is reset after the third attempt succeeded
+	require.Equal(t, 5, attempts)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{control.HealthConnecting, control.HealthHealthy, control.HealthReconnecting,
+		control.HealthHealthy, control.HealthReconnecting, control.HealthFailed}, states)
+	require.Equal(t, control.HealthFailed, s.State())
+}
+
+func TestSupervisorInitConnectFailed(t *testing.T) {
+	s := newTestSupervisor(func(ready func()) error {
+		return fmt.Errorf("refused")
+	})
+	require.Error(t, s.Start())
+	require.Equal(t, control.HealthFailed, s.State())
+}
+
+func TestSupervisorDegrade(t *testing.T) {
+	broken := make(chan error)
+	s := newTestSupervisor(func(ready func()) error {
+		ready()
+		return <-broken
+	})
+	s.Recover()
+	require.NoError(t, s.Start())
+	require.Equal(t, control.HealthHealthy, s.State())
+	s.Degrade(fmt.Errorf("heartbeat failed"))
+	require.Equal(t, control.HealthDegraded, s.State())
+	s.Recover()
+	require.Equal(t, control.HealthHealthy, s.State())
+}
+
+func newTestSupervisor(connect func(ready func()) error) *Supervisor {
+	s := NewSupervisor("Test connection", connect)
+	s.Backoff = &Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Factor: 2}
+	return s
+}
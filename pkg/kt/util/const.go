@@ -18,6 +18,10 @@ const (
 	ComponentPreview = "preview"
 	// ComponentForward forward command
 	ComponentForward = "forward"
+	// ComponentSync sync command
+	ComponentSync = "sync"
+	// ComponentRun run command
+	ComponentRun = "run"
 
 	// ImageKtShadow default shadow image
 	ImageKtShadow = "layzer/kt-connect-shadow"
@@ -32,10 +36,16 @@ const (
 	ExchangeModeScale = "scale"
 	// ExchangeModeSelector selector mode
 	ExchangeModeSelector = "selector"
+	// ExchangeModeEphemeral ephemeral mode
+	ExchangeModeEphemeral = "ephemeral"
 	// MeshModeAuto auto mode
 	MeshModeAuto = "auto"
 	// MeshModeManual manual mode
 	MeshModeManual = "manual"
+	// MeshModeIstio istio mode
+	MeshModeIstio = "istio"
+	// MeshModeGateway gateway api mode
+	MeshModeGateway = "gateway"
 	// DnsModeLocalDns local dns mode
 	DnsModeLocalDns = "localDNS"
 	// DnsModePodDns pod dns mode
@@ -46,6 +56,18 @@ const (
 	DnsOrderCluster = "cluster"
 	// DnsOrderUpstream proxy to upstream dns
 	DnsOrderUpstream = "upstream"
+	// ProxyAuthAuto generate random credential for local proxies
+	ProxyAuthAuto = "auto"
+	// ClientIpProxyV1 carry client ip with proxy protocol v1 header
+	ClientIpProxyV1 = "proxy-v1"
+	// ClientIpProxyV2 carry client ip with proxy protocol v2 header
+	ClientIpProxyV2 = "proxy-v2"
+	// ClientIpForwardedFor carry client ip with X-Forwarded-For http header
+	ClientIpForwardedFor = "x-forwarded-for"
+	// ProtocolTcp tcp protocol of exposed port
+	ProtocolTcp = "tcp"
+	// ProtocolUdp udp protocol of exposed port
+	ProtocolUdp = "udp"
 
 	// ControlBy label used for mark shadow pod
 	ControlBy = "control-by"
@@ -63,8 +85,14 @@ const (
 	KtRefCount = "kt-ref-count"
 	// KtLastHeartBeat annotation used for timestamp of last heart beat
 	KtLastHeartBeat = "kt-last-heart-beat"
+	// KtShadowGroup label used for spreading replicas of the same shadow to different nodes
+	KtShadowGroup = "kt-shadow-group"
 	// KtLock annotation used for avoid auto mesh conflict
 	KtLock = "kt-lock"
+	// KtOriginRule annotation used for record origin spec of istio rule or http route
+	KtOriginRule = "kt-origin-rule"
+	// KtSuspend node selector used for evicting daemon set pods during exchange
+	KtSuspend = "kt-suspend"
 
 	// PostfixRsaKey postfix of local private key name
 	PostfixRsaKey = ".key"
@@ -80,6 +108,8 @@ const (
 	DefaultNamespace = "default"
 	// KtExchangeContainer name of exchange ephemeral container
 	KtExchangeContainer = "kt-exchange"
+	// ShadowPidFile file recording pid of main process in shadow container
+	ShadowPidFile = "/var/run/kt-shadow.pid"
 	// DefaultContainer default container name
 	DefaultContainer = "standalone"
 	// StuntmanServiceSuffix suffix of stuntman service name
@@ -90,6 +120,10 @@ const (
 	ExchangePodInfix = "-kt-exchange-"
 	// MeshPodInfix mesh pod and mesh service name
 	MeshPodInfix = "-kt-mesh-"
+	// IstioRuleSuffix suffix of istio rule created by kt
+	IstioRuleSuffix = "-kt-istio"
+	// IstioRulePrefix prefix of istio route and subset name added by kt
+	IstioRulePrefix = "kt-"
 	// RectifierPodPrefix rectifier pod name
 	RectifierPodPrefix = "kt-rectifier-"
 	// RoleConnectShadow shadow role
@@ -129,4 +163,5 @@ var (
 	KtLockDir = fmt.Sprintf("%s/lock", KtHome)
 	KtProfileDir = fmt.Sprintf("%s/profile", KtHome)
 	KtConfigFile = fmt.Sprintf("%s/config", KtHome)
+	KtServicesEnvFile = fmt.Sprintf("%s/services.env", KtHome)
 )
\ No newline at end of file
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const IpAddrPattern = "[0-9]+\\.[0-9]+\\.[0-9]+\\.[0-9]+"
@@ -31,22 +32,77 @@ func GetRandomTcpPort() int {
 
 // ParsePortMapping parse <port> or <localPort>:<removePort> parameter
 func ParsePortMapping(exposePort string) (int, int, error) {
-	localPort := exposePort
-	remotePort := exposePort
-	ports := strings.SplitN(exposePort, ":", 2)
-	if len(ports) > 1 {
-		localPort = ports[0]
-		remotePort = ports[1]
+	_, lp, rp, err := ParseExposeTarget(exposePort)
+	return lp, rp, err
+}
+
+// ParseExposeProtocol split optional 'tcp/' or 'udp/' prefix of expose target, tcp is used if not specified
+func ParseExposeProtocol(exposePort string) (string, string, error) {
+	protocol, target, found := strings.Cut(exposePort, "/")
+	if !found {
+		return ProtocolTcp, exposePort, nil
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol != ProtocolTcp && protocol != ProtocolUdp {
+		return "", "", fmt.Errorf("invalid protocol '%s' of expose target '%s'", protocol, exposePort)
 	}
+	return protocol, target, nil
+}
+
+// ParseExposeTarget parse <port>, <localPort>:<remotePort>, <host>:<port> or <host>:<localPort>:<remotePort> parameter,
+// optionally prefixed with protocol, e.g. udp/<port>
+// Return empty host if traffic should be forwarded to local machine
+func ParseExposeTarget(exposePort string) (string, int, int, error) {
+	host := ""
+	_, target, err := ParseExposeProtocol(exposePort)
+	if err != nil {
+		return "", -1, -1, err
+	}
+	ports := strings.Split(target, ":")
+	if len(ports) > 3 {
+		return "", -1, -1, fmt.Errorf("invalid expose target '%s'", exposePort)
+	} else if len(ports) == 3 {
+		host = ports[0]
+		ports = ports[1:]
+	} else if _, err := strconv.Atoi(ports[0]); len(ports) == 2 && err != nil {
+		// <host>:<port> format
+		host = ports[0]
+		ports = ports[1:]
+	}
+	localPort := ports[0]
+	remotePort := ports[len(ports)-1]
 	lp, err := strconv.Atoi(localPort)
 	if err != nil {
-		return -1, -1, fmt.Errorf("local port '%s' is not a number", localPort)
+		return "", -1, -1, fmt.Errorf("local port '%s' is not a number", localPort)
 	}
 	rp, err := strconv.Atoi(remotePort)
 	if err != nil {
-		return -1, -1, fmt.Errorf("remote port '%s' is not a number", remotePort)
+		return "", -1, -1, fmt.Errorf("remote port '%s' is not a number", remotePort)
+	}
+	return host, lp, rp, nil
+}
+
+// ExposeTargetAddress get address to forward exposed traffic to
+func ExposeTargetAddress(host string, port int) string {
+	if host == "" {
+		host = "127.0.0.1"
 	}
-	return lp, rp, nil
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// FindUdpExposePorts get remote ports of exposed targets using udp protocol
+func FindUdpExposePorts(exposePorts string) []int {
+	ports := make([]int, 0)
+	for _, exposePort := range strings.Split(exposePorts, ",") {
+		protocol, _, err := ParseExposeProtocol(exposePort)
+		if err != nil || protocol != ProtocolUdp {
+			continue
+		}
+		if _, _, remotePort, err2 := ParseExposeTarget(exposePort); err2 == nil {
+			ports = append(ports, remotePort)
+		}
+	}
+	return ports
 }
 
 // FindBrokenLocalPort Check if all ports has process listening to
@@ -54,12 +110,21 @@ func ParsePortMapping(exposePort string) (int, int, error) {
 func FindBrokenLocalPort(exposePorts string) string {
 	portPairs := strings.Split(exposePorts, ",")
 	for _, exposePort := range portPairs {
-		localPort := strings.Split(exposePort, ":")[0]
-		conn, err := net.Dial("tcp", fmt.Sprintf(":%s", localPort))
+		host, localPort, _, err := ParseExposeTarget(exposePort)
+		if err != nil {
+			return exposePort
+		}
+		if protocol, _, _ := ParseExposeProtocol(exposePort); protocol == ProtocolUdp {
+			// udp is connectionless, cannot tell whether port is listened
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", ExposeTargetAddress(host, localPort), 3*time.Second)
 		if err == nil {
 			_ = conn.Close()
+		} else if host == "" {
+			return strconv.Itoa(localPort)
 		} else {
-			return localPort
+			return ExposeTargetAddress(host, localPort)
 		}
 	}
 	return ""
@@ -75,13 +140,12 @@ func FindInvalidRemotePort(exposePorts string, svcPorts map[int]string) string {
 
 	portPairs := strings.Split(exposePorts, ",")
 	for _, exposePort := range portPairs {
-		splitPorts := strings.Split(exposePort, ":")
-		remotePort := splitPorts[0]
-		if len(splitPorts) > 1 {
-			remotePort = splitPorts[1]
+		_, _, remotePort, err := ParseExposeTarget(exposePort)
+		if err != nil {
+			return exposePort
 		}
-		if !Contains(validPorts, remotePort) {
-			return remotePort
+		if !Contains(validPorts, strconv.Itoa(remotePort)) {
+			return strconv.Itoa(remotePort)
 		}
 	}
 	return ""
@@ -11,3 +11,41 @@ func TestExtractHostIp(t *testing.T) {
 	require.Equal(t, "1.2.3.4", ExtractHostIp("http://1.2.3.4:8080/a/b/c"))
 	require.Equal(t, "127.0.0.1", ExtractHostIp("http://localhost:8080/a/b/c"))
 }
+
+func TestParseExposeTarget(t *testing.T) {
+	cases := []struct {
+		expose string
+		host   string
+		local  int
+		remote int
+	}{
+		{"8080", "", 8080, 8080},
+		{"8080:80", "", 8080, 80},
+		{"host.docker.internal:8080", "host.docker.internal", 8080, 8080},
+		{"192.168.1.20:80:8080", "192.168.1.20", 80, 8080},
+		{"udp/5353", "", 5353, 5353},
+		{"udp/5353:53", "", 5353, 53},
+		{"tcp/192.168.1.20:80:8080", "192.168.1.20", 80, 8080},
+	}
+	for _, c := range cases {
+		host, local, remote, err := ParseExposeTarget(c.expose)
+		require.NoError(t, err)
+		require.Equal(t, c.host, host)
+		require.Equal(t, c.local, local)
+		require.Equal(t, c.remote, remote)
+	}
+	_, _, _, err := ParseExposeTarget("a:b:c:d")
+	require.Error(t, err)
+	_, _, _, err = ParseExposeTarget("host:abc")
+	require.Error(t, err)
+	_, _, _, err = ParseExposeTarget("sctp/80")
+	require.Error(t, err)
+	require.Equal(t, "127.0.0.1:80", ExposeTargetAddress("", 80))
+	require.Equal(t, "10.0.0.1:80", ExposeTargetAddress("10.0.0.1", 80))
+}
+
+func TestFindUdpExposePorts(t *testing.T) {
+	require.Equal(t, []int{}, FindUdpExposePorts("8080,tcp/80"))
+	require.Equal(t, []int{53, 8125}, FindUdpExposePorts("8080,udp/5353:53,UDP/8125"))
+	require.Equal(t, "", FindBrokenLocalPort("udp/5353"))
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const sessionFileSuffix = ".session"
+
+// ConnectSession information of a running connect process, shared between connect processes of different clusters
+type ConnectSession struct {
+	Pid int `json:"pid"`
+	// Context kubeconfig context connected to
+	Context string `json:"context"`
+	// DomainSuffix extra domain suffix of services in the cluster, empty for the process which takes over system dns
+	DomainSuffix string `json:"domainSuffix,omitempty"`
+	// DnsAddress udp address of local dns server serving the domain suffix
+	DnsAddress string `json:"dnsAddress,omitempty"`
+	// TunName tun device used by the process
+	TunName string `json:"tunName,omitempty"`
+	// DnsMode dns mode of the process
+	DnsMode string `json:"dnsMode,omitempty"`
+	// Routes ip ranges routed to tun device of the process
+	Routes []string `json:"routes,omitempty"`
+}
+
+// WriteConnectSession record session of current connect process
+func WriteConnectSession(session *ConnectSession) error {
+	session.Pid = os.Getpid()
+	content, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sessionFile(session.Pid), content, 0644)
+}
+
+// RemoveConnectSession remove session record of current connect process
+func RemoveConnectSession() {
+	_ = os.Remove(sessionFile(os.Getpid()))
+}
+
+// GetConnectSessions get sessions of all running connect processes, except current one
+func GetConnectSessions() []ConnectSession {
+	sessions := make([]ConnectSession, 0)
+	files, _ := os.ReadDir(KtPidDir)
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, ComponentConnect+"-") || !strings.HasSuffix(name, sessionFileSuffix) {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, ComponentConnect+"-"), sessionFileSuffix))
+		if err != nil || pid == os.Getpid() || !IsProcessExist(pid) {
+			continue
+		}
+		content, err := os.ReadFile(fmt.Sprintf("%s/%s", KtPidDir, name))
+		if err != nil {
+			continue
+		}
+		var session ConnectSession
+		if err = json.Unmarshal(content, &session); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// FindOverlappedRoute find ip range routed by other connect process which overlaps with any of specified ranges,
+// return the specified range, the overlapped range and the session routing it
+func FindOverlappedRoute(ranges []string, sessions []ConnectSession) (string, string, *ConnectSession) {
+	for i := range sessions {
+		for _, r := range ranges {
+			for _, routed := range sessions[i].Routes {
+				if IsRangeOverlapped(r, routed) {
+					return r, routed, &sessions[i]
+				}
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// IsRangeOverlapped check whether two cidr ranges share any address
+func IsRangeOverlapped(a, b string) bool {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+func sessionFile(pid int) string {
+	return fmt.Sprintf("%s/%s-%d%s", KtPidDir, ComponentConnect, pid, sessionFileSuffix)
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsRangeOverlapped(t *testing.T) {
+	require.True(t, IsRangeOverlapped("10.96.0.0/12", "10.96.0.0/12"))
+	require.True(t, IsRangeOverlapped("10.96.0.0/12", "10.100.0.0/16"))
+	require.True(t, IsRangeOverlapped("10.100.0.0/16", "10.96.0.0/12"))
+	require.False(t, IsRangeOverlapped("10.96.0.0/12", "10.112.0.0/16"))
+	require.False(t, IsRangeOverlapped("10.96.0.0/12", "invalid"))
+}
+
+func TestFindOverlappedRoute(t *testing.T) {
+	sessions := []ConnectSession{
+		{Pid: 100, Routes: []string{"172.16.0.0/16"}},
+		{Pid: 200, Routes: []string{"10.244.0.0/16", "10.96.0.0/12"}},
+	}
+	r, routed, session := FindOverlappedRoute([]string{"192.168.0.0/16", "10.100.0.0/16"}, sessions)
+	require.Equal(t, "10.100.0.0/16", r)
+	require.Equal(t, "10.96.0.0/12", routed)
+	require.Equal(t, 200, session.Pid)
+	_, _, session = FindOverlappedRoute([]string{"192.168.0.0/16"}, sessions)
+	require.Nil(t, session)
+}
@@ -21,6 +21,15 @@ var TimeDifference int64 = 0
 
 // GetDaemonRunning fetch daemon pid if exist
 func GetDaemonRunning(componentName string) int {
+	if pids := GetAllDaemonRunning(componentName); len(pids) > 0 {
+		return pids[0]
+	}
+	return -1
+}
+
+// GetAllDaemonRunning fetch pid of all running daemons of specified component
+func GetAllDaemonRunning(componentName string) []int {
+	pids := make([]int, 0)
 	files, _ := ioutil.ReadDir(KtPidDir)
 	for _, f := range files {
 		if strings.HasPrefix(f.Name(), componentName) && strings.HasSuffix(f.Name(), ".pid") {
@@ -28,11 +37,11 @@ func GetDaemonRunning(componentName string) int {
 			to := len(f.Name()) - len(".pid")
 			pid, err := strconv.Atoi(f.Name()[from:to])
 			if err == nil && IsProcessExist(pid) {
-				return pid
+				pids = append(pids, pid)
 			}
 		}
 	}
-	return -1
+	return pids
 }
 
 // IsProcessExist check whether specified process still running
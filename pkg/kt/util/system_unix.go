@@ -4,6 +4,9 @@ package util
 
 import (
 	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
 )
 
 func IsRunAsAdmin() bool {
@@ -13,3 +16,24 @@ func IsRunAsAdmin() bool {
 func GetAdminUserName() string {
 	return "root"
 }
+
+// RunAsSudoUser let command run as the user who invoked sudo, instead of root
+func RunAsSudoUser(cmd *exec.Cmd) {
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil || os.Geteuid() != 0 {
+		return
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		gid = uid
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}}
+}
+
+// DetachProcess let command keep running after current process and terminal exited
+func DetachProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+}
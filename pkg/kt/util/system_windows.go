@@ -3,6 +3,8 @@ package util
 import (
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sys/windows"
+	"os/exec"
+	"syscall"
 )
 
 // Refer to https://github.com/golang/go/issues/28804
@@ -34,3 +36,12 @@ func IsRunAsAdmin() bool {
 func GetAdminUserName() string {
 	return "administrator"
 }
+
+// RunAsSudoUser not needed in windows
+func RunAsSudoUser(cmd *exec.Cmd) {
+}
+
+// DetachProcess let command keep running after current process and terminal exited
+func DetachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS}
+}
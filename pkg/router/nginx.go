@@ -11,7 +11,11 @@ import (
 //go:embed route.conf
 var routeTemplate string
 
+//go:embed split.conf
+var splitTemplate string
+
 const pathRouteConf = "/etc/nginx/conf.d/route.conf"
+const pathSplitConf = "/etc/nginx/stream.d/split.conf"
 
 func WriteAndReloadRouteConf(ktConf *KtConf) error {
 	var err error
@@ -30,6 +34,19 @@ func WriteAndReloadRouteConf(ktConf *KtConf) error {
 	return nil
 }
 
+// WriteAndReloadSplitConf forward exposed ports to shadow service and other ports to stuntman service at tcp level
+func WriteAndReloadSplitConf(ktConf *KtConf) error {
+	err := writeSplitConf(ktConf)
+	if err != nil {
+		return err
+	}
+	err = reloadRouteConf()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func reloadRouteConf() error {
 	process, err := os.FindProcess(1)
 	if err != nil {
@@ -62,6 +79,34 @@ func writeRouteConf(ktConf *KtConf) error {
 	return nil
 }
 
+func writeSplitConf(ktConf *KtConf) error {
+	isExposed := func(port []string) bool {
+		for _, p := range ktConf.Exposed {
+			if p == port[1] {
+				return true
+			}
+		}
+		return false
+	}
+	tmpl, err := template.New("split").Funcs(template.FuncMap{"isExposed": isExposed}).Parse(splitTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to load split template: %s", err)
+	}
+
+	_ = os.Remove(pathSplitConf)
+	splitConfFile, err := os.Create(pathSplitConf)
+	if err != nil {
+		return fmt.Errorf("failed to create split configuration file: %s", err)
+	}
+	defer splitConfFile.Close()
+
+	err = tmpl.Execute(splitConfFile, ktConf)
+	if err != nil {
+		return fmt.Errorf("failed to generate split configuration: %s", err)
+	}
+	return nil
+}
+
 func removeRouteConf() error {
 	err := os.Remove(pathRouteConf)
 	if err != nil {
@@ -5,4 +5,6 @@ type KtConf struct {
 	Ports    [][]string
 	Header   string
 	Versions []string
+	Shadow   string
+	Exposed  []string
 }
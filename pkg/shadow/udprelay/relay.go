@@ -0,0 +1,114 @@
+package udprelay
+
+import (
+	"errors"
+	"github.com/gitlayzer/kt-connect/pkg/common"
+	"github.com/rs/zerolog/log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sessionIdleTimeout close relay session of a udp client after no datagram transferred for a while
+const sessionIdleTimeout = 60 * time.Second
+
+// session relay stream of a udp client
+type session struct {
+	tunnel net.Conn
+	timer  *time.Timer
+}
+
+// relay forward datagrams received on a udp port to local relay port, which is reverse tunneled to ktctl
+type relay struct {
+	conn     *net.UDPConn
+	port     int
+	sessions map[string]*session
+	lock     sync.Mutex
+}
+
+// Start listen on udp ports and relay received datagrams
+func Start(ports []int) error {
+	for _, port := range ports {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("Relaying udp port %d via port %d", port, common.UdpRelayPort)
+		r := &relay{conn: conn, port: port, sessions: make(map[string]*session)}
+		go r.serve()
+	}
+	return nil
+}
+
+func (r *relay) serve() {
+	buf := make([]byte, common.MaxDatagramSize)
+	for {
+		n, client, err := r.conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			log.Warn().Err(err).Msgf("Failed to read datagram from udp port %d", r.port)
+			continue
+		}
+		s, err := r.getSession(client)
+		if err != nil {
+			// reverse tunnel not ready, treat as packet loss
+			log.Debug().Err(err).Msgf("Dropped datagram from %s", client)
+			continue
+		}
+		s.timer.Reset(sessionIdleTimeout)
+		if err = common.WriteDatagram(s.tunnel, buf[:n]); err != nil {
+			log.Debug().Err(err).Msgf("Failed to relay datagram from %s", client)
+			_ = s.tunnel.Close()
+		}
+	}
+}
+
+func (r *relay) getSession(client *net.UDPAddr) (*session, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if s, exists := r.sessions[client.String()]; exists {
+		return s, nil
+	}
+	tunnel, err := net.Dial("tcp", net.JoinHostPort(common.Localhost, strconv.Itoa(common.UdpRelayPort)))
+	if err != nil {
+		return nil, err
+	}
+	if err = common.WriteRelayPort(tunnel, r.port); err != nil {
+		_ = tunnel.Close()
+		return nil, err
+	}
+	s := &session{
+		tunnel: tunnel,
+		timer: time.AfterFunc(sessionIdleTimeout, func() {
+			_ = tunnel.Close()
+		}),
+	}
+	r.sessions[client.String()] = s
+	log.Debug().Msgf("Relay session of %s on udp port %d started", client, r.port)
+	go r.replyToClient(client, s)
+	return s, nil
+}
+
+// replyToClient send datagrams responded by local service back to udp client
+func (r *relay) replyToClient(client *net.UDPAddr, s *session) {
+	defer func() {
+		s.timer.Stop()
+		_ = s.tunnel.Close()
+		r.lock.Lock()
+		delete(r.sessions, client.String())
+		r.lock.Unlock()
+		log.Debug().Msgf("Relay session of %s on udp port %d closed", client, r.port)
+	}()
+	for {
+		data, err := common.ReadDatagram(s.tunnel)
+		if err != nil {
+			return
+		}
+		s.timer.Reset(sessionIdleTimeout)
+		if _, err = r.conn.WriteToUDP(data, client); err != nil {
+			log.Debug().Err(err).Msgf("Failed to reply datagram to %s", client)
+		}
+	}
+}